@@ -28,17 +28,17 @@ const (
 // ClientInterceptor is a gRPC client interceptor for logging, metrics, and tracing
 type ClientInterceptor struct {
 	logger  *log.Logger
-	metrics *metrics.RequestMetrics
+	metrics *metrics.LimitedRequestMetrics
 	tracer  opentracing.Tracer
 }
 
 // NewClientInterceptor creates a new instance of gRPC server interceptor
 func NewClientInterceptor(logger *log.Logger, mf *metrics.Factory, tracer opentracing.Tracer) *ClientInterceptor {
-	metrics := &metrics.RequestMetrics{
-		ReqGauge:        mf.Gauge(clientGaugeMetricName, "gauge metric for number of active client-side grpc requests", []string{"package", "service", "method", "stream"}),
-		ReqCounter:      mf.Counter(clientCounterMetricName, "counter metric for total number of client-side grpc requests", []string{"package", "service", "method", "stream", "success"}),
-		ReqDurationHist: mf.Histogram(clientHistogramMetricName, "histogram metric for duration of client-side grpc requests in seconds", []string{"package", "service", "method", "stream", "success"}),
-		ReqDurationSumm: mf.Summary(clientSummaryMetricName, "summary metric for duration of client-side grpc requests in seconds", []string{"package", "service", "method", "stream", "success"}),
+	metrics := &metrics.LimitedRequestMetrics{
+		ReqGauge:        mf.LimitedGauge(clientGaugeMetricName, "gauge metric for number of active client-side grpc requests", []string{"package", "service", "method", "stream"}),
+		ReqCounter:      mf.LimitedCounter(clientCounterMetricName, "counter metric for total number of client-side grpc requests", []string{"package", "service", "method", "stream", "success"}),
+		ReqDurationHist: mf.LimitedHistogram(clientHistogramMetricName, "histogram metric for duration of client-side grpc requests in seconds", []string{"package", "service", "method", "stream", "success"}),
+		ReqDurationSumm: mf.LimitedSummary(clientSummaryMetricName, "summary metric for duration of client-side grpc requests in seconds", []string{"package", "service", "method", "stream", "success"}),
 	}
 
 	return &ClientInterceptor{
@@ -157,8 +157,7 @@ func (i *ClientInterceptor) UnaryInterceptor(ctx context.Context, fullMethod str
 	successText := strconv.FormatBool(success)
 	i.metrics.ReqGauge.WithLabelValues(pkg, service, method, stream).Dec()
 	i.metrics.ReqCounter.WithLabelValues(pkg, service, method, stream, successText).Inc()
-	i.metrics.ReqDurationHist.WithLabelValues(pkg, service, method, stream, successText).Observe(duration)
-	i.metrics.ReqDurationSumm.WithLabelValues(pkg, service, method, stream, successText).Observe(duration)
+	i.metrics.ObserveDuration(span, duration, pkg, service, method, stream, successText)
 
 	// Tracing
 	// https://github.com/opentracing/specification/blob/master/semantic_conventions.md
@@ -236,8 +235,7 @@ func (i *ClientInterceptor) StreamInterceptor(ctx context.Context, desc *grpc.St
 	successText := strconv.FormatBool(success)
 	i.metrics.ReqGauge.WithLabelValues(pkg, service, method, stream).Dec()
 	i.metrics.ReqCounter.WithLabelValues(pkg, service, method, stream, successText).Inc()
-	i.metrics.ReqDurationHist.WithLabelValues(pkg, service, method, stream, successText).Observe(duration)
-	i.metrics.ReqDurationSumm.WithLabelValues(pkg, service, method, stream, successText).Observe(duration)
+	i.metrics.ObserveDuration(span, duration, pkg, service, method, stream, successText)
 
 	// Tracing
 	// https://github.com/opentracing/specification/blob/master/semantic_conventions.md
@@ -3,17 +3,21 @@ package grpc
 import (
 	"context"
 	"fmt"
+	"runtime/debug"
 	"strconv"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/moorara/goto/log"
 	"github.com/moorara/goto/metrics"
+	"github.com/moorara/goto/report"
 	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/opentracing/opentracing-go/ext"
 	opentracingLog "github.com/opentracing/opentracing-go/log"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 var loggerContextKey = contextKey("logger")
@@ -35,26 +39,41 @@ const (
 	serverSummaryMetricName   = "grpc_server_request_duration_quantiles_seconds"
 )
 
+// ServerInterceptorOptions contains optional options for creating a ServerInterceptor
+type ServerInterceptorOptions struct {
+	// Reporter reports panics recovered by recovery interceptors
+	Reporter report.Reporter
+}
+
 // ServerInterceptor is a gRPC server interceptor for logging, metrics, and tracing
 type ServerInterceptor struct {
-	logger  *log.Logger
-	metrics *metrics.RequestMetrics
-	tracer  opentracing.Tracer
+	logger   *log.Logger
+	metrics  *metrics.LimitedRequestMetrics
+	tracer   opentracing.Tracer
+	reporter report.Reporter
 }
 
 // NewServerInterceptor creates a new instance of gRPC server interceptor
-func NewServerInterceptor(logger *log.Logger, mf *metrics.Factory, tracer opentracing.Tracer) *ServerInterceptor {
-	metrics := &metrics.RequestMetrics{
-		ReqGauge:        mf.Gauge(serverGaugeMetricName, "gauge metric for number of active server-side grpc requests", []string{"package", "service", "method", "stream"}),
-		ReqCounter:      mf.Counter(serverCounterMetricName, "counter metric for total number of server-side grpc requests", []string{"package", "service", "method", "stream", "success"}),
-		ReqDurationHist: mf.Histogram(serverHistogramMetricName, "histogram metric for duration of server-side grpc requests in seconds", []string{"package", "service", "method", "stream", "success"}),
-		ReqDurationSumm: mf.Summary(serverSummaryMetricName, "summary metric for duration of server-side grpc requests in seconds", []string{"package", "service", "method", "stream", "success"}),
+func NewServerInterceptor(logger *log.Logger, mf *metrics.Factory, tracer opentracing.Tracer, opts ...ServerInterceptorOptions) *ServerInterceptor {
+	var reporter report.Reporter
+	for _, o := range opts {
+		if o.Reporter != nil {
+			reporter = o.Reporter
+		}
+	}
+
+	metrics := &metrics.LimitedRequestMetrics{
+		ReqGauge:        mf.LimitedGauge(serverGaugeMetricName, "gauge metric for number of active server-side grpc requests", []string{"package", "service", "method", "stream"}),
+		ReqCounter:      mf.LimitedCounter(serverCounterMetricName, "counter metric for total number of server-side grpc requests", []string{"package", "service", "method", "stream", "success"}),
+		ReqDurationHist: mf.LimitedHistogram(serverHistogramMetricName, "histogram metric for duration of server-side grpc requests in seconds", []string{"package", "service", "method", "stream", "success"}),
+		ReqDurationSumm: mf.LimitedSummary(serverSummaryMetricName, "summary metric for duration of server-side grpc requests in seconds", []string{"package", "service", "method", "stream", "success"}),
 	}
 
 	return &ServerInterceptor{
-		logger:  logger,
-		metrics: metrics,
-		tracer:  tracer,
+		logger:   logger,
+		metrics:  metrics,
+		tracer:   tracer,
+		reporter: reporter,
 	}
 }
 
@@ -157,8 +176,7 @@ func (i *ServerInterceptor) UnaryInterceptor(ctx context.Context, req interface{
 	successText := strconv.FormatBool(success)
 	i.metrics.ReqGauge.WithLabelValues(pkg, service, method, stream).Dec()
 	i.metrics.ReqCounter.WithLabelValues(pkg, service, method, stream, successText).Inc()
-	i.metrics.ReqDurationHist.WithLabelValues(pkg, service, method, stream, successText).Observe(duration)
-	i.metrics.ReqDurationSumm.WithLabelValues(pkg, service, method, stream, successText).Observe(duration)
+	i.metrics.ObserveDuration(span, duration, pkg, service, method, stream, successText)
 
 	// Tracing
 	// https://github.com/opentracing/specification/blob/master/semantic_conventions.md
@@ -240,8 +258,7 @@ func (i *ServerInterceptor) StreamInterceptor(srv interface{}, ss grpc.ServerStr
 	successText := strconv.FormatBool(success)
 	i.metrics.ReqGauge.WithLabelValues(pkg, service, method, stream).Dec()
 	i.metrics.ReqCounter.WithLabelValues(pkg, service, method, stream, successText).Inc()
-	i.metrics.ReqDurationHist.WithLabelValues(pkg, service, method, stream, successText).Observe(duration)
-	i.metrics.ReqDurationSumm.WithLabelValues(pkg, service, method, stream, successText).Observe(duration)
+	i.metrics.ObserveDuration(span, duration, pkg, service, method, stream, successText)
 
 	// Tracing
 	// https://github.com/opentracing/specification/blob/master/semantic_conventions.md
@@ -257,3 +274,66 @@ func (i *ServerInterceptor) StreamInterceptor(srv interface{}, ss grpc.ServerStr
 
 	return err
 }
+
+// reportPanic reports and logs a recovered panic and returns an Internal error
+func (i *ServerInterceptor) reportPanic(ctx context.Context, fullMethod string, e interface{}) error {
+	err := fmt.Errorf("panic occurred: %v", e)
+
+	requestID, _ := ctx.Value(requestIDContextKey).(string)
+	if requestID == "" {
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if vals := md.Get(requestIDKey); len(vals) > 0 {
+				requestID = vals[0]
+			}
+		}
+	}
+
+	traceID, _ := metrics.TraceID(opentracing.SpanFromContext(ctx))
+
+	if i.reporter != nil {
+		i.reporter.ErrorWithMetadata(err, map[string]interface{}{
+			"method":    fullMethod,
+			"requestId": requestID,
+			"traceId":   traceID,
+		})
+	}
+
+	if i.logger != nil {
+		i.logger.Error(
+			"grpc.kind", serverKind,
+			"grpc.method", fullMethod,
+			"requestId", requestID,
+			"traceId", traceID,
+			"stack", string(debug.Stack()),
+			"message", err.Error(),
+		)
+	}
+
+	return status.Error(codes.Internal, "internal server error")
+}
+
+// RecoveryUnaryInterceptor is the gRPC UnaryServerInterceptor for recovering from panics
+// A recovered panic is reported through the reporter with the request context, logged, and returned as an Internal error.
+// It should be the innermost interceptor, so the request id and span are available and other interceptors see the error.
+func (i *ServerInterceptor) RecoveryUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (res interface{}, err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			res, err = nil, i.reportPanic(ctx, info.FullMethod, e)
+		}
+	}()
+
+	return handler(ctx, req)
+}
+
+// RecoveryStreamInterceptor is the gRPC StreamServerInterceptor for recovering from panics
+// A recovered panic is reported through the reporter with the request context, logged, and returned as an Internal error.
+// It should be the innermost interceptor, so the request id and span are available and other interceptors see the error.
+func (i *ServerInterceptor) RecoveryStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = i.reportPanic(ss.Context(), info.FullMethod, e)
+		}
+	}()
+
+	return handler(srv, ss)
+}
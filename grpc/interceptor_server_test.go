@@ -11,6 +11,7 @@ import (
 
 	"github.com/moorara/goto/log"
 	"github.com/moorara/goto/metrics"
+	"github.com/moorara/goto/report"
 	"github.com/moorara/goto/trace"
 	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/opentracing/opentracing-go/ext"
@@ -19,7 +20,9 @@ import (
 	promModel "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 func injectSpan(ctx context.Context, tracer opentracing.Tracer, span opentracing.Span) context.Context {
@@ -606,3 +609,169 @@ func TestStreamServerInterceptor(t *testing.T) {
 		})
 	}
 }
+
+func TestRecoveryUnaryInterceptor(t *testing.T) {
+	tests := []struct {
+		name              string
+		ctx               func(opentracing.Span) context.Context
+		handler           grpc.UnaryHandler
+		expectedResponse  interface{}
+		expectedCode      codes.Code
+		expectedRequestID string
+		expectedPanic     bool
+	}{
+		{
+			name: "NoPanic",
+			ctx: func(span opentracing.Span) context.Context {
+				return context.Background()
+			},
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return "response", nil
+			},
+			expectedResponse: "response",
+			expectedCode:     codes.OK,
+		},
+		{
+			name: "PanicWithContext",
+			ctx: func(span opentracing.Span) context.Context {
+				ctx := opentracing.ContextWithSpan(context.Background(), span)
+				return context.WithValue(ctx, requestIDContextKey, "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa")
+			},
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				panic("nil map")
+			},
+			expectedCode:      codes.Internal,
+			expectedRequestID: "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa",
+			expectedPanic:     true,
+		},
+		{
+			name: "PanicWithMetadata",
+			ctx: func(span opentracing.Span) context.Context {
+				md := metadata.Pairs(requestIDKey, "bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb")
+				return metadata.NewIncomingContext(context.Background(), md)
+			},
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				panic(errors.New("nil map"))
+			},
+			expectedCode:      codes.Internal,
+			expectedRequestID: "bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb",
+			expectedPanic:     true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			buff := &bytes.Buffer{}
+			recorder := report.NewRecorder()
+			tracer := mocktracer.New()
+			span := tracer.StartSpan("test")
+
+			i := &ServerInterceptor{
+				logger:   log.NewLogger(log.Options{Writer: buff}),
+				reporter: recorder,
+			}
+
+			info := &grpc.UnaryServerInfo{FullMethod: "/package.service/method"}
+			res, err := i.RecoveryUnaryInterceptor(tc.ctx(span), "request", info, tc.handler)
+
+			assert.Equal(t, tc.expectedResponse, res)
+			assert.Equal(t, tc.expectedCode, status.Code(err))
+
+			if !tc.expectedPanic {
+				assert.Empty(t, recorder.Reports())
+				assert.Equal(t, 0, buff.Len())
+				return
+			}
+
+			traceID, _ := metrics.TraceID(opentracing.SpanFromContext(tc.ctx(span)))
+
+			reports := recorder.Reports()
+			assert.Len(t, reports, 1)
+			assert.Equal(t, "panic occurred: nil map", reports[0].Error.Error())
+			assert.Equal(t, map[string]interface{}{
+				"method":    "/package.service/method",
+				"requestId": tc.expectedRequestID,
+				"traceId":   traceID,
+			}, reports[0].Metadata)
+
+			var log map[string]interface{}
+			assert.NoError(t, json.NewDecoder(buff).Decode(&log))
+			assert.Equal(t, "error", log["level"])
+			assert.Equal(t, "/package.service/method", log["grpc.method"])
+			assert.Equal(t, tc.expectedRequestID, log["requestId"])
+			assert.Equal(t, "panic occurred: nil map", log["message"])
+			assert.NotEmpty(t, log["stack"])
+		})
+	}
+}
+
+func TestRecoveryStreamInterceptor(t *testing.T) {
+	tests := []struct {
+		name          string
+		handler       grpc.StreamHandler
+		expectedCode  codes.Code
+		expectedPanic bool
+	}{
+		{
+			name: "NoPanic",
+			handler: func(srv interface{}, stream grpc.ServerStream) error {
+				return nil
+			},
+			expectedCode: codes.OK,
+		},
+		{
+			name: "NoPanicWithError",
+			handler: func(srv interface{}, stream grpc.ServerStream) error {
+				return status.Error(codes.NotFound, "not found")
+			},
+			expectedCode: codes.NotFound,
+		},
+		{
+			name: "Panic",
+			handler: func(srv interface{}, stream grpc.ServerStream) error {
+				panic("nil map")
+			},
+			expectedCode:  codes.Internal,
+			expectedPanic: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			buff := &bytes.Buffer{}
+			recorder := report.NewRecorder()
+
+			i := &ServerInterceptor{
+				logger:   log.NewLogger(log.Options{Writer: buff}),
+				reporter: recorder,
+			}
+
+			ctx := context.WithValue(context.Background(), requestIDContextKey, "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa")
+			ss := &mockServerStream{ContextOutContext: ctx}
+			info := &grpc.StreamServerInfo{FullMethod: "/package.service/method"}
+			err := i.RecoveryStreamInterceptor(nil, ss, info, tc.handler)
+
+			assert.Equal(t, tc.expectedCode, status.Code(err))
+
+			if tc.expectedPanic {
+				reports := recorder.Reports()
+				assert.Len(t, reports, 1)
+				assert.Equal(t, "panic occurred: nil map", reports[0].Error.Error())
+				assert.Equal(t, "/package.service/method", reports[0].Metadata["method"])
+				assert.Equal(t, "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa", reports[0].Metadata["requestId"])
+				assert.NotZero(t, buff.Len())
+			} else {
+				assert.Empty(t, recorder.Reports())
+			}
+		})
+	}
+}
+
+func TestNewServerInterceptorWithReporter(t *testing.T) {
+	recorder := report.NewRecorder()
+	soi := NewServerInterceptor(log.NewNopLogger(), metrics.NewFactory(metrics.FactoryOptions{}), mocktracer.New(), ServerInterceptorOptions{
+		Reporter: recorder,
+	})
+
+	assert.Equal(t, recorder, soi.reporter)
+}
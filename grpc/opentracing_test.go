@@ -4,7 +4,10 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/moorara/goto/trace"
+	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/stretchr/testify/assert"
+	jaeger "github.com/uber/jaeger-client-go"
 	"google.golang.org/grpc/metadata"
 )
 
@@ -73,3 +76,64 @@ func TestMetadataTextMap(t *testing.T) {
 		})
 	}
 }
+
+func TestMetadataTextMapPropagation(t *testing.T) {
+	tests := []struct {
+		name         string
+		propagation  []trace.Propagation
+		expectedKeys []string
+	}{
+		{
+			name:         "Jaeger",
+			propagation:  []trace.Propagation{trace.PropagationJaeger},
+			expectedKeys: []string{"uber-trace-id"},
+		},
+		{
+			name:         "W3C",
+			propagation:  []trace.Propagation{trace.PropagationW3C},
+			expectedKeys: []string{"traceparent"},
+		},
+		{
+			name:         "B3Single",
+			propagation:  []trace.Propagation{trace.PropagationB3Single},
+			expectedKeys: []string{"b3"},
+		},
+		{
+			name:         "B3Multi",
+			propagation:  []trace.Propagation{trace.PropagationB3Multi},
+			expectedKeys: []string{"x-b3-traceid", "x-b3-spanid", "x-b3-sampled"},
+		},
+		{
+			name:         "Composite",
+			propagation:  []trace.Propagation{trace.PropagationW3C, trace.PropagationB3Single, trace.PropagationJaeger},
+			expectedKeys: []string{"traceparent", "b3", "uber-trace-id"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tracer, closer, err := trace.NewTracer(trace.Options{
+				Name:         "service_name",
+				SpanReporter: trace.NewRecorder(),
+				Propagation:  tc.propagation,
+			})
+			assert.NoError(t, err)
+			defer closer.Close()
+
+			span := tracer.StartSpan("test")
+			defer span.Finish()
+
+			md := metadata.New(nil)
+			err = tracer.Inject(span.Context(), opentracing.TextMap, &metadataTextMap{md})
+			assert.NoError(t, err)
+			for _, key := range tc.expectedKeys {
+				assert.Len(t, md[key], 1)
+			}
+
+			ctx, err := tracer.Extract(opentracing.TextMap, &metadataTextMap{md})
+			assert.NoError(t, err)
+			assert.Equal(t, span.Context().(jaeger.SpanContext).TraceID(), ctx.(jaeger.SpanContext).TraceID())
+			assert.Equal(t, span.Context().(jaeger.SpanContext).SpanID(), ctx.(jaeger.SpanContext).SpanID())
+		})
+	}
+}
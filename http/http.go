@@ -4,9 +4,13 @@
 package http
 
 import (
+	"bufio"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"regexp"
+	"strings"
 )
 
 // contextKey is the type for the keys added to context
@@ -17,6 +21,28 @@ const (
 	requestIDContextKey = contextKey("RequestID")
 )
 
+var idRegex = regexp.MustCompile(`^([0-9]+|[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}|[0-9a-fA-F]{24,})$`)
+
+// RouteTemplateFunc returns the route template of a request (i.e. /users/{id})
+type RouteTemplateFunc func(*http.Request) string
+
+func rawPath(r *http.Request) string {
+	return r.URL.Path
+}
+
+// IDRouteTemplate is a RouteTemplateFunc that replaces the path segments looking like ids with {id}
+// Numbers, UUIDs, and long hex strings (i.e. object ids and hashes) are considered ids.
+func IDRouteTemplate(r *http.Request) string {
+	segments := strings.Split(r.URL.Path, "/")
+	for i, s := range segments {
+		if idRegex.MatchString(s) {
+			segments[i] = "{id}"
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
+
 // Error is an http error
 type Error struct {
 	Request    *http.Request
@@ -68,3 +94,103 @@ func (r *ResponseWriter) WriteHeader(statusCode int) {
 		r.StatusClass = fmt.Sprintf("%dxx", statusCode/100)
 	}
 }
+
+// Write overrides the default implementation of http.Write
+// Writing the body without calling WriteHeader first implies a 200 status code.
+func (r *ResponseWriter) Write(b []byte) (int, error) {
+	if r.StatusCode == 0 {
+		r.StatusCode = http.StatusOK
+		r.StatusClass = "2xx"
+	}
+
+	return r.ResponseWriter.Write(b)
+}
+
+type (
+	responseFlusher  struct{ *ResponseWriter }
+	responseHijacker struct{ *ResponseWriter }
+	responsePusher   struct{ *ResponseWriter }
+)
+
+// Flush implements http.Flusher interface
+// Flushing without calling WriteHeader first implies a 200 status code.
+func (f responseFlusher) Flush() {
+	if f.StatusCode == 0 {
+		f.StatusCode = http.StatusOK
+		f.StatusClass = "2xx"
+	}
+
+	f.ResponseWriter.ResponseWriter.(http.Flusher).Flush()
+}
+
+// Hijack implements http.Hijacker interface
+// A hijacked connection without a status code is taken as switching protocols (i.e. websockets).
+func (h responseHijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, buf, err := h.ResponseWriter.ResponseWriter.(http.Hijacker).Hijack()
+	if err == nil && h.StatusCode == 0 {
+		h.StatusCode = http.StatusSwitchingProtocols
+		h.StatusClass = "1xx"
+	}
+
+	return conn, buf, err
+}
+
+// Push implements http.Pusher interface
+func (p responsePusher) Push(target string, opts *http.PushOptions) error {
+	return p.ResponseWriter.ResponseWriter.(http.Pusher).Push(target, opts)
+}
+
+// passThrough returns the response writer implementing the same optional interfaces as the underlying http.ResponseWriter
+// The optional interfaces are http.Flusher, http.Hijacker, and http.Pusher, so streaming and websocket handlers keep working.
+func (r *ResponseWriter) passThrough() http.ResponseWriter {
+	_, isFlusher := r.ResponseWriter.(http.Flusher)
+	_, isHijacker := r.ResponseWriter.(http.Hijacker)
+	_, isPusher := r.ResponseWriter.(http.Pusher)
+
+	f, h, p := responseFlusher{r}, responseHijacker{r}, responsePusher{r}
+
+	switch {
+	case isFlusher && isHijacker && isPusher:
+		return struct {
+			*ResponseWriter
+			http.Flusher
+			http.Hijacker
+			http.Pusher
+		}{r, f, h, p}
+	case isFlusher && isHijacker:
+		return struct {
+			*ResponseWriter
+			http.Flusher
+			http.Hijacker
+		}{r, f, h}
+	case isFlusher && isPusher:
+		return struct {
+			*ResponseWriter
+			http.Flusher
+			http.Pusher
+		}{r, f, p}
+	case isHijacker && isPusher:
+		return struct {
+			*ResponseWriter
+			http.Hijacker
+			http.Pusher
+		}{r, h, p}
+	case isFlusher:
+		return struct {
+			*ResponseWriter
+			http.Flusher
+		}{r, f}
+	case isHijacker:
+		return struct {
+			*ResponseWriter
+			http.Hijacker
+		}{r, h}
+	case isPusher:
+		return struct {
+			*ResponseWriter
+			http.Pusher
+		}{r, p}
+	default:
+		return r
+	}
+}
@@ -108,3 +108,92 @@ func TestResponseWriter(t *testing.T) {
 		})
 	}
 }
+
+func TestResponseWriterWrite(t *testing.T) {
+	rec := httptest.NewRecorder()
+	rw := NewResponseWriter(rec)
+
+	n, err := rw.Write([]byte("body"))
+	assert.NoError(t, err)
+	assert.Equal(t, 4, n)
+	assert.Equal(t, http.StatusOK, rw.StatusCode)
+	assert.Equal(t, "2xx", rw.StatusClass)
+
+	rw.WriteHeader(http.StatusInternalServerError)
+	assert.Equal(t, http.StatusOK, rw.StatusCode)
+	assert.Equal(t, "2xx", rw.StatusClass)
+	assert.Equal(t, "body", rec.Body.String())
+}
+
+// pushRecorder is a response recorder implementing http.Pusher
+type pushRecorder struct {
+	*httptest.ResponseRecorder
+	targets []string
+}
+
+func (r *pushRecorder) Push(target string, opts *http.PushOptions) error {
+	r.targets = append(r.targets, target)
+	return nil
+}
+
+// plainWriter is a response writer without any optional interface
+type plainWriter struct {
+	http.ResponseWriter
+}
+
+func TestResponseWriterPassThrough(t *testing.T) {
+	t.Run("Plain", func(t *testing.T) {
+		rw := NewResponseWriter(plainWriter{httptest.NewRecorder()})
+		w := rw.passThrough()
+
+		_, isFlusher := w.(http.Flusher)
+		_, isHijacker := w.(http.Hijacker)
+		_, isPusher := w.(http.Pusher)
+		assert.False(t, isFlusher)
+		assert.False(t, isHijacker)
+		assert.False(t, isPusher)
+	})
+
+	t.Run("FlusherAndPusher", func(t *testing.T) {
+		rec := &pushRecorder{ResponseRecorder: httptest.NewRecorder()}
+		rw := NewResponseWriter(rec)
+		w := rw.passThrough()
+
+		_, isHijacker := w.(http.Hijacker)
+		assert.False(t, isHijacker)
+
+		pusher, ok := w.(http.Pusher)
+		assert.True(t, ok)
+		assert.NoError(t, pusher.Push("/style.css", nil))
+		assert.Equal(t, []string{"/style.css"}, rec.targets)
+
+		// Flushing implies a 200 status code
+		flusher, ok := w.(http.Flusher)
+		assert.True(t, ok)
+		flusher.Flush()
+		assert.True(t, rec.Flushed)
+		assert.Equal(t, http.StatusOK, rw.StatusCode)
+		assert.Equal(t, "2xx", rw.StatusClass)
+	})
+}
+
+func TestIDRouteTemplate(t *testing.T) {
+	tests := []struct {
+		path             string
+		expectedTemplate string
+	}{
+		{"/", "/"},
+		{"/v1/items", "/v1/items"},
+		{"/v1/items/1234", "/v1/items/{id}"},
+		{"/v1/users/4e1b6a3c-0f9b-4c3e-9a7d-2b8f1c6e5d4a/orders/42", "/v1/users/{id}/orders/{id}"},
+		{"/v1/objects/5d8c3b9e2f1a4c0012345678", "/v1/objects/{id}"},
+		{"/v1/items/abc", "/v1/items/abc"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.path, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tc.path, nil)
+			assert.Equal(t, tc.expectedTemplate, IDRouteTemplate(req))
+		})
+	}
+}
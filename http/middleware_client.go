@@ -28,27 +28,43 @@ type (
 	// Doer is the interface for standard http.Client Do method
 	Doer func(*http.Request) (*http.Response, error)
 
+	// ClientMiddlewareOptions contains optional options for creating a ClientMiddleware
+	ClientMiddlewareOptions struct {
+		// RouteTemplate returns the route template of a request (i.e. /users/{id}) for the url label of metrics
+		// If not set, the raw request path is used which can create a new series for every distinct path.
+		RouteTemplate RouteTemplateFunc
+	}
+
 	// ClientMiddleware is an http client middleware for logging, metrics, tracing, etc.
 	ClientMiddleware struct {
-		logger  *log.Logger
-		metrics *metrics.RequestMetrics
-		tracer  opentracing.Tracer
+		logger        *log.Logger
+		metrics       *metrics.LimitedRequestMetrics
+		tracer        opentracing.Tracer
+		routeTemplate RouteTemplateFunc
 	}
 )
 
 // NewClientMiddleware creates a new instance of http client middleware
-func NewClientMiddleware(logger *log.Logger, mf *metrics.Factory, tracer opentracing.Tracer) *ClientMiddleware {
-	metrics := &metrics.RequestMetrics{
-		ReqGauge:        mf.Gauge(clientGaugeMetricName, "gauge metric for number of active client-side http requests", []string{"method", "url"}),
-		ReqCounter:      mf.Counter(clientCounterMetricName, "counter metric for total number of client-side http requests", []string{"method", "url", "statusCode", "statusClass"}),
-		ReqDurationHist: mf.Histogram(clientHistogramMetricName, "histogram metric for duration of client-side http requests in seconds", []string{"method", "url", "statusCode", "statusClass"}),
-		ReqDurationSumm: mf.Summary(clientSummaryMetricName, "summary metric for duration of client-side http requests in seconds", []string{"method", "url", "statusCode", "statusClass"}),
+func NewClientMiddleware(logger *log.Logger, mf *metrics.Factory, tracer opentracing.Tracer, opts ...ClientMiddlewareOptions) *ClientMiddleware {
+	routeTemplate := rawPath
+	for _, o := range opts {
+		if o.RouteTemplate != nil {
+			routeTemplate = o.RouteTemplate
+		}
+	}
+
+	metrics := &metrics.LimitedRequestMetrics{
+		ReqGauge:        mf.LimitedGauge(clientGaugeMetricName, "gauge metric for number of active client-side http requests", []string{"method", "url"}),
+		ReqCounter:      mf.LimitedCounter(clientCounterMetricName, "counter metric for total number of client-side http requests", []string{"method", "url", "statusCode", "statusClass"}),
+		ReqDurationHist: mf.LimitedHistogram(clientHistogramMetricName, "histogram metric for duration of client-side http requests in seconds", []string{"method", "url", "statusCode", "statusClass"}),
+		ReqDurationSumm: mf.LimitedSummary(clientSummaryMetricName, "summary metric for duration of client-side http requests in seconds", []string{"method", "url", "statusCode", "statusClass"}),
 	}
 
 	return &ClientMiddleware{
-		logger:  logger,
-		metrics: metrics,
-		tracer:  tracer,
+		logger:        logger,
+		metrics:       metrics,
+		tracer:        tracer,
+		routeTemplate: routeTemplate,
 	}
 }
 
@@ -139,7 +155,7 @@ func (m *ClientMiddleware) Logging(next Doer) Doer {
 func (m *ClientMiddleware) Metrics(next Doer) Doer {
 	return func(r *http.Request) (*http.Response, error) {
 		method := r.Method
-		url := r.URL.Path
+		url := m.routeTemplate(r)
 
 		// Increment guage metric
 		m.metrics.ReqGauge.WithLabelValues(method, url).Inc()
@@ -164,8 +180,7 @@ func (m *ClientMiddleware) Metrics(next Doer) Doer {
 		statusText := strconv.Itoa(statusCode)
 		m.metrics.ReqGauge.WithLabelValues(method, url).Dec()
 		m.metrics.ReqCounter.WithLabelValues(method, url, statusText, statusClass).Inc()
-		m.metrics.ReqDurationHist.WithLabelValues(method, url, statusText, statusClass).Observe(duration)
-		m.metrics.ReqDurationSumm.WithLabelValues(method, url, statusText, statusClass).Observe(duration)
+		m.metrics.ObserveDuration(opentracing.SpanFromContext(r.Context()), duration, method, url, statusText, statusClass)
 
 		return res, err
 	}
@@ -16,7 +16,6 @@ import (
 	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/opentracing/opentracing-go/mocktracer"
 	"github.com/prometheus/client_golang/prometheus"
-	promModel "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -308,6 +307,7 @@ func TestClientMiddlewareLogging(t *testing.T) {
 func TestClientMiddlewareMetrics(t *testing.T) {
 	tests := []struct {
 		name                string
+		opts                ClientMiddlewareOptions
 		req                 *http.Request
 		resDelay            time.Duration
 		resError            error
@@ -371,14 +371,24 @@ func TestClientMiddlewareMetrics(t *testing.T) {
 			expectedURL:         "/v1/items/1234",
 			expectedStatusCode:  500,
 			expectedStatusClass: "5xx",
+		}, {
+			name:                "RouteTemplate",
+			opts:                ClientMiddlewareOptions{RouteTemplate: IDRouteTemplate},
+			req:                 httptest.NewRequest("GET", "/v1/items/1234", nil),
+			resDelay:            10 * time.Millisecond,
+			resError:            nil,
+			resStatusCode:       200,
+			expectedMethod:      "GET",
+			expectedURL:         "/v1/items/{id}",
+			expectedStatusCode:  200,
+			expectedStatusClass: "2xx",
 		},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			promReg := prometheus.NewRegistry()
-			metricsFactory := metrics.NewFactory(metrics.FactoryOptions{Registerer: promReg})
-			mid := NewClientMiddleware(nil, metricsFactory, nil)
+			metricsFactory := metrics.NewTestFactory()
+			mid := NewClientMiddleware(nil, metricsFactory.Factory, nil, tc.opts)
 			assert.NotNil(t, mid)
 
 			// Test http doer
@@ -404,40 +414,17 @@ func TestClientMiddlewareMetrics(t *testing.T) {
 
 			// Verify metrics
 
-			verifyLabels := func(labels []*promModel.LabelPair) {
-				for _, l := range labels {
-					switch *l.Name {
-					case "method":
-						assert.Equal(t, tc.expectedMethod, *l.Value)
-					case "url":
-						assert.Equal(t, tc.expectedURL, *l.Value)
-					case "statusCode":
-						assert.Equal(t, strconv.Itoa(tc.expectedStatusCode), *l.Value)
-					case "statusClass":
-						assert.Equal(t, tc.expectedStatusClass, *l.Value)
-					}
-				}
+			labels := prometheus.Labels{
+				"method":      tc.expectedMethod,
+				"url":         tc.expectedURL,
+				"statusCode":  strconv.Itoa(tc.expectedStatusCode),
+				"statusClass": tc.expectedStatusClass,
 			}
 
-			metricFamilies, err := promReg.Gather()
-			assert.NoError(t, err)
-
-			for _, metricFamily := range metricFamilies {
-				switch *metricFamily.Name {
-				case clientGaugeMetricName:
-					assert.Equal(t, promModel.MetricType_GAUGE, *metricFamily.Type)
-					verifyLabels(metricFamily.Metric[0].Label)
-				case clientCounterMetricName:
-					assert.Equal(t, promModel.MetricType_COUNTER, *metricFamily.Type)
-					verifyLabels(metricFamily.Metric[0].Label)
-				case clientHistogramMetricName:
-					assert.Equal(t, promModel.MetricType_HISTOGRAM, *metricFamily.Type)
-					verifyLabels(metricFamily.Metric[0].Label)
-				case clientSummaryMetricName:
-					assert.Equal(t, promModel.MetricType_SUMMARY, *metricFamily.Type)
-					verifyLabels(metricFamily.Metric[0].Label)
-				}
-			}
+			metricsFactory.AssertMetric(t, clientGaugeMetricName, prometheus.Labels{"method": tc.expectedMethod, "url": tc.expectedURL}, 0)
+			metricsFactory.AssertMetric(t, clientCounterMetricName, labels, 1)
+			metricsFactory.AssertMetric(t, clientHistogramMetricName, labels, 1)
+			metricsFactory.AssertMetric(t, clientSummaryMetricName, labels, 1)
 		})
 	}
 }
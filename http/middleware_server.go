@@ -4,12 +4,14 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"runtime/debug"
 	"strconv"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/moorara/goto/log"
 	"github.com/moorara/goto/metrics"
+	"github.com/moorara/goto/report"
 	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/opentracing/opentracing-go/ext"
 )
@@ -34,26 +36,50 @@ func LoggerForRequest(r *http.Request) (*log.Logger, bool) {
 	return logger, ok
 }
 
+// ServerMiddlewareOptions contains optional options for creating a ServerMiddleware
+type ServerMiddlewareOptions struct {
+	// RouteTemplate returns the route template of a request (i.e. /users/{id}) for the url label of metrics
+	// If not set, the raw request path is used which can create a new series for every distinct path.
+	RouteTemplate RouteTemplateFunc
+	// Reporter reports panics recovered by Recovery middleware
+	Reporter report.Reporter
+}
+
 // ServerMiddleware is an http server middleware for logging, metrics, tracing, etc.
 type ServerMiddleware struct {
-	logger  *log.Logger
-	metrics *metrics.RequestMetrics
-	tracer  opentracing.Tracer
+	logger        *log.Logger
+	metrics       *metrics.LimitedRequestMetrics
+	tracer        opentracing.Tracer
+	routeTemplate RouteTemplateFunc
+	reporter      report.Reporter
 }
 
 // NewServerMiddleware creates a new instance of http server middleware
-func NewServerMiddleware(logger *log.Logger, mf *metrics.Factory, tracer opentracing.Tracer) *ServerMiddleware {
-	metrics := &metrics.RequestMetrics{
-		ReqGauge:        mf.Gauge(serverGaugeMetricName, "gauge metric for number of active server-side http requests", []string{"method", "url"}),
-		ReqCounter:      mf.Counter(serverCounterMetricName, "counter metric for total number of server-side http requests", []string{"method", "url", "statusCode", "statusClass"}),
-		ReqDurationHist: mf.Histogram(serverHistogramMetricName, "histogram metric for duration of server-side http requests in seconds", []string{"method", "url", "statusCode", "statusClass"}),
-		ReqDurationSumm: mf.Summary(serverSummaryMetricName, "summary metric for duration of server-side http requests in seconds", []string{"method", "url", "statusCode", "statusClass"}),
+func NewServerMiddleware(logger *log.Logger, mf *metrics.Factory, tracer opentracing.Tracer, opts ...ServerMiddlewareOptions) *ServerMiddleware {
+	routeTemplate := rawPath
+	var reporter report.Reporter
+	for _, o := range opts {
+		if o.RouteTemplate != nil {
+			routeTemplate = o.RouteTemplate
+		}
+		if o.Reporter != nil {
+			reporter = o.Reporter
+		}
+	}
+
+	metrics := &metrics.LimitedRequestMetrics{
+		ReqGauge:        mf.LimitedGauge(serverGaugeMetricName, "gauge metric for number of active server-side http requests", []string{"method", "url"}),
+		ReqCounter:      mf.LimitedCounter(serverCounterMetricName, "counter metric for total number of server-side http requests", []string{"method", "url", "statusCode", "statusClass"}),
+		ReqDurationHist: mf.LimitedHistogram(serverHistogramMetricName, "histogram metric for duration of server-side http requests in seconds", []string{"method", "url", "statusCode", "statusClass"}),
+		ReqDurationSumm: mf.LimitedSummary(serverSummaryMetricName, "summary metric for duration of server-side http requests in seconds", []string{"method", "url", "statusCode", "statusClass"}),
 	}
 
 	return &ServerMiddleware{
-		logger:  logger,
-		metrics: metrics,
-		tracer:  tracer,
+		logger:        logger,
+		metrics:       metrics,
+		tracer:        tracer,
+		routeTemplate: routeTemplate,
+		reporter:      reporter,
 	}
 }
 
@@ -110,7 +136,7 @@ func (m *ServerMiddleware) Logging(next http.HandlerFunc) http.HandlerFunc {
 		// Call the next http handler
 		start := time.Now()
 		rw := NewResponseWriter(w)
-		next(rw, req)
+		next(rw.passThrough(), req)
 		statusCode := rw.StatusCode
 		statusClass := rw.StatusClass
 		duration := time.Since(start).Seconds()
@@ -140,7 +166,7 @@ func (m *ServerMiddleware) Logging(next http.HandlerFunc) http.HandlerFunc {
 func (m *ServerMiddleware) Metrics(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		method := r.Method
-		url := r.URL.Path
+		url := m.routeTemplate(r)
 
 		// Increment guage metric
 		m.metrics.ReqGauge.WithLabelValues(method, url).Inc()
@@ -148,7 +174,7 @@ func (m *ServerMiddleware) Metrics(next http.HandlerFunc) http.HandlerFunc {
 		// Call the next http handler
 		start := time.Now()
 		rw := NewResponseWriter(w)
-		next(rw, r)
+		next(rw.passThrough(), r)
 		statusCode := rw.StatusCode
 		statusClass := rw.StatusClass
 		duration := time.Since(start).Seconds()
@@ -157,8 +183,7 @@ func (m *ServerMiddleware) Metrics(next http.HandlerFunc) http.HandlerFunc {
 		statusText := strconv.Itoa(statusCode)
 		m.metrics.ReqGauge.WithLabelValues(method, url).Dec()
 		m.metrics.ReqCounter.WithLabelValues(method, url, statusText, statusClass).Inc()
-		m.metrics.ReqDurationHist.WithLabelValues(method, url, statusText, statusClass).Observe(duration)
-		m.metrics.ReqDurationSumm.WithLabelValues(method, url, statusText, statusClass).Observe(duration)
+		m.metrics.ObserveDuration(opentracing.SpanFromContext(r.Context()), duration, method, url, statusText, statusClass)
 	}
 }
 
@@ -195,7 +220,7 @@ func (m *ServerMiddleware) Tracing(next http.HandlerFunc) http.HandlerFunc {
 
 		// Call the next http handler
 		rw := NewResponseWriter(w)
-		next(rw, req)
+		next(rw.passThrough(), req)
 		statusCode := rw.StatusCode
 
 		// Tracing
@@ -209,3 +234,61 @@ func (m *ServerMiddleware) Tracing(next http.HandlerFunc) http.HandlerFunc {
 		) */
 	}
 }
+
+// Recovery recovers from panics in incoming http requests
+// A recovered panic is reported through the reporter with the request context, logged, and responded with 500.
+// It should be the innermost middleware, so the request id and span are available and other middleware see the 500 response.
+func (m *ServerMiddleware) Recovery(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rw := NewResponseWriter(w)
+
+		defer func() {
+			e := recover()
+			if e == nil {
+				return
+			}
+
+			// http.ErrAbortHandler is used for aborting a response and should not be recovered
+			if e == http.ErrAbortHandler {
+				panic(e)
+			}
+
+			err := fmt.Errorf("panic occurred: %v", e)
+
+			requestID, _ := r.Context().Value(requestIDContextKey).(string)
+			if requestID == "" {
+				requestID = r.Header.Get(requestIDHeader)
+			}
+
+			traceID, _ := metrics.TraceID(opentracing.SpanFromContext(r.Context()))
+
+			if m.reporter != nil {
+				m.reporter.HTTPErrorWithMetadata(r, err, map[string]interface{}{
+					"method":    r.Method,
+					"path":      r.URL.Path,
+					"requestId": requestID,
+					"traceId":   traceID,
+				})
+			}
+
+			if m.logger != nil {
+				m.logger.Error(
+					"http.kind", serverKind,
+					"req.method", r.Method,
+					"req.url", r.URL.Path,
+					"requestId", requestID,
+					"traceId", traceID,
+					"stack", string(debug.Stack()),
+					"message", err.Error(),
+				)
+			}
+
+			// The response cannot be changed if its header or body is already written
+			if rw.StatusCode == 0 {
+				http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+		}()
+
+		next(rw.passThrough(), r)
+	}
+}
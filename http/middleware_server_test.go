@@ -1,9 +1,11 @@
 package http
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
@@ -12,10 +14,10 @@ import (
 
 	"github.com/moorara/goto/log"
 	"github.com/moorara/goto/metrics"
+	"github.com/moorara/goto/report"
 	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/opentracing/opentracing-go/mocktracer"
 	"github.com/prometheus/client_golang/prometheus"
-	promModel "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -81,6 +83,15 @@ func TestNewServerMiddleware(t *testing.T) {
 	}
 }
 
+func TestNewServerMiddlewareTwice(t *testing.T) {
+	mf := metrics.NewFactory(metrics.FactoryOptions{Registerer: prometheus.NewRegistry()})
+
+	m1 := NewServerMiddleware(log.NewNopLogger(), mf, mocktracer.New())
+	m2 := NewServerMiddleware(log.NewNopLogger(), mf, mocktracer.New())
+
+	assert.Equal(t, m1.metrics, m2.metrics)
+}
+
 func TestServerMiddlewareRequestID(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -261,6 +272,7 @@ func TestServerMiddlewareLogging(t *testing.T) {
 func TestServerMiddlewareMetrics(t *testing.T) {
 	tests := []struct {
 		name                string
+		opts                ServerMiddlewareOptions
 		req                 *http.Request
 		resDelay            time.Duration
 		resStatusCode       int
@@ -308,14 +320,23 @@ func TestServerMiddlewareMetrics(t *testing.T) {
 			expectedURL:         "/v1/items/1234",
 			expectedStatusCode:  500,
 			expectedStatusClass: "5xx",
+		}, {
+			name:                "RouteTemplate",
+			opts:                ServerMiddlewareOptions{RouteTemplate: IDRouteTemplate},
+			req:                 httptest.NewRequest("GET", "/v1/items/1234", nil),
+			resDelay:            10 * time.Millisecond,
+			resStatusCode:       200,
+			expectedMethod:      "GET",
+			expectedURL:         "/v1/items/{id}",
+			expectedStatusCode:  200,
+			expectedStatusClass: "2xx",
 		},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			promReg := prometheus.NewRegistry()
-			metricsFactory := metrics.NewFactory(metrics.FactoryOptions{Registerer: promReg})
-			mid := NewServerMiddleware(nil, metricsFactory, nil)
+			metricsFactory := metrics.NewTestFactory()
+			mid := NewServerMiddleware(nil, metricsFactory.Factory, nil, tc.opts)
 			assert.NotNil(t, mid)
 
 			// Test http handler
@@ -333,40 +354,17 @@ func TestServerMiddlewareMetrics(t *testing.T) {
 
 			// Verify metrics
 
-			verifyLabels := func(labels []*promModel.LabelPair) {
-				for _, l := range labels {
-					switch *l.Name {
-					case "method":
-						assert.Equal(t, tc.expectedMethod, *l.Value)
-					case "url":
-						assert.Equal(t, tc.expectedURL, *l.Value)
-					case "statusCode":
-						assert.Equal(t, strconv.Itoa(tc.expectedStatusCode), *l.Value)
-					case "statusClass":
-						assert.Equal(t, tc.expectedStatusClass, *l.Value)
-					}
-				}
+			labels := prometheus.Labels{
+				"method":      tc.expectedMethod,
+				"url":         tc.expectedURL,
+				"statusCode":  strconv.Itoa(tc.expectedStatusCode),
+				"statusClass": tc.expectedStatusClass,
 			}
 
-			metricFamilies, err := promReg.Gather()
-			assert.NoError(t, err)
-
-			for _, metricFamily := range metricFamilies {
-				switch *metricFamily.Name {
-				case serverGaugeMetricName:
-					assert.Equal(t, promModel.MetricType_GAUGE, *metricFamily.Type)
-					verifyLabels(metricFamily.Metric[0].Label)
-				case serverCounterMetricName:
-					assert.Equal(t, promModel.MetricType_COUNTER, *metricFamily.Type)
-					verifyLabels(metricFamily.Metric[0].Label)
-				case serverHistogramMetricName:
-					assert.Equal(t, promModel.MetricType_HISTOGRAM, *metricFamily.Type)
-					verifyLabels(metricFamily.Metric[0].Label)
-				case serverSummaryMetricName:
-					assert.Equal(t, promModel.MetricType_SUMMARY, *metricFamily.Type)
-					verifyLabels(metricFamily.Metric[0].Label)
-				}
-			}
+			metricsFactory.AssertMetric(t, serverGaugeMetricName, prometheus.Labels{"method": tc.expectedMethod, "url": tc.expectedURL}, 0)
+			metricsFactory.AssertMetric(t, serverCounterMetricName, labels, 1)
+			metricsFactory.AssertMetric(t, serverHistogramMetricName, labels, 1)
+			metricsFactory.AssertMetric(t, serverSummaryMetricName, labels, 1)
 		})
 	}
 }
@@ -494,3 +492,172 @@ func TestServerMiddlewareTracing(t *testing.T) {
 		})
 	}
 }
+
+func TestNewServerMiddlewareWithReporter(t *testing.T) {
+	recorder := report.NewRecorder()
+	m := NewServerMiddleware(log.NewNopLogger(), metrics.NewFactory(metrics.FactoryOptions{}), mocktracer.New(), ServerMiddlewareOptions{
+		Reporter: recorder,
+	})
+
+	assert.Equal(t, recorder, m.reporter)
+}
+
+func TestServerMiddlewareRecovery(t *testing.T) {
+	tests := []struct {
+		name               string
+		handler            http.HandlerFunc
+		requestID          string
+		withSpan           bool
+		expectedStatusCode int
+		expectedBody       string
+		expectedPanic      bool
+	}{
+		{
+			name: "NoPanic",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusCreated)
+			},
+			expectedStatusCode: http.StatusCreated,
+		},
+		{
+			name: "Panic",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				panic("nil map")
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedPanic:      true,
+		},
+		{
+			name: "PanicWithRequestIDAndSpan",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				panic(errors.New("nil map"))
+			},
+			requestID:          "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa",
+			withSpan:           true,
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedPanic:      true,
+		},
+		{
+			name: "PanicAfterWriteHeader",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusAccepted)
+				panic("nil map")
+			},
+			expectedStatusCode: http.StatusAccepted,
+			expectedPanic:      true,
+		},
+		{
+			name: "PanicAfterWrite",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("partial"))
+				panic("nil map")
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       "partial",
+			expectedPanic:      true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			buff := &bytes.Buffer{}
+			recorder := report.NewRecorder()
+			mid := &ServerMiddleware{
+				logger:   log.NewLogger(log.Options{Writer: buff}),
+				reporter: recorder,
+			}
+
+			req := httptest.NewRequest("GET", "/v1/items/1234", nil)
+			if tc.requestID != "" {
+				req.Header.Set(requestIDHeader, tc.requestID)
+			}
+
+			var expectedTraceID string
+			if tc.withSpan {
+				span := mocktracer.New().StartSpan("test")
+				req = req.WithContext(opentracing.ContextWithSpan(req.Context(), span))
+				expectedTraceID, _ = metrics.TraceID(span)
+			}
+
+			rec := httptest.NewRecorder()
+			mid.Recovery(tc.handler)(rec, req)
+
+			res := rec.Result()
+			assert.Equal(t, tc.expectedStatusCode, res.StatusCode)
+			if tc.expectedBody != "" {
+				assert.Equal(t, tc.expectedBody, rec.Body.String())
+			}
+
+			if !tc.expectedPanic {
+				assert.Empty(t, recorder.Reports())
+				assert.Equal(t, 0, buff.Len())
+				return
+			}
+
+			reports := recorder.Reports()
+			assert.Len(t, reports, 1)
+			assert.Equal(t, "panic occurred: nil map", reports[0].Error.Error())
+			assert.Equal(t, req, reports[0].Request)
+			assert.Equal(t, map[string]interface{}{
+				"method":    "GET",
+				"path":      "/v1/items/1234",
+				"requestId": tc.requestID,
+				"traceId":   expectedTraceID,
+			}, reports[0].Metadata)
+
+			var log map[string]interface{}
+			assert.NoError(t, json.NewDecoder(buff).Decode(&log))
+			assert.Equal(t, "error", log["level"])
+			assert.Equal(t, "GET", log["req.method"])
+			assert.Equal(t, "/v1/items/1234", log["req.url"])
+			assert.Equal(t, "panic occurred: nil map", log["message"])
+			assert.NotEmpty(t, log["stack"])
+		})
+	}
+}
+
+func TestServerMiddlewareRecoveryAbortHandler(t *testing.T) {
+	mid := &ServerMiddleware{
+		logger: log.NewNopLogger(),
+	}
+
+	handler := mid.Recovery(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	})
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		handler(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	})
+}
+
+func TestServerMiddlewareRecoveryFlush(t *testing.T) {
+	mid := &ServerMiddleware{
+		logger: log.NewNopLogger(),
+	}
+
+	flushed := make(chan struct{})
+	release := make(chan struct{})
+
+	server := httptest.NewServer(mid.Recovery(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("first\n"))
+		w.(http.Flusher).Flush()
+		close(flushed)
+
+		// The rest of response is written after the client reads the flushed chunk
+		<-release
+		w.Write([]byte("second\n"))
+	}))
+	defer server.Close()
+
+	res, err := http.Get(server.URL)
+	assert.NoError(t, err)
+	defer res.Body.Close()
+
+	<-flushed
+	line, err := bufio.NewReader(res.Body).ReadString('\n')
+	close(release)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "first\n", line)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+}
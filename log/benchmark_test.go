@@ -0,0 +1,93 @@
+package log
+
+import (
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	kitLog "github.com/go-kit/kit/log"
+	kitLevel "github.com/go-kit/kit/log/level"
+)
+
+// newKitLogger creates a logger the way this package used to before the fast path (for comparison)
+func newKitLogger(format Format) kitLog.Logger {
+	var logger kitLog.Logger
+
+	switch format {
+	case Logfmt:
+		logger = kitLog.NewLogfmtLogger(ioutil.Discard)
+	default:
+		logger = kitLog.NewJSONLogger(ioutil.Discard)
+	}
+
+	logger = kitLog.NewSyncLogger(logger)
+	logger = kitLog.With(logger,
+		"caller", kitLog.Caller(5),
+		"timestamp", kitLog.DefaultTimestampUTC,
+		"logger", "benchmark",
+	)
+
+	return kitLevel.NewFilter(logger, kitLevel.AllowInfo())
+}
+
+func BenchmarkLogger(b *testing.B) {
+	err := errors.New("no capacity")
+
+	b.Run("Disabled", func(b *testing.B) {
+		logger := NewLogger(Options{Writer: ioutil.Discard, Name: "benchmark", Level: "info"})
+		b.ReportAllocs()
+		b.ResetTimer()
+		for n := 0; n < b.N; n++ {
+			logger.Debug("message", "operation failed", "retries", 4, "error", err)
+		}
+	})
+
+	b.Run("JSON", func(b *testing.B) {
+		logger := NewLogger(Options{Writer: ioutil.Discard, Name: "benchmark", Format: JSON})
+		b.ReportAllocs()
+		b.ResetTimer()
+		for n := 0; n < b.N; n++ {
+			logger.Info("message", "operation failed", "retries", 4, "error", err)
+		}
+	})
+
+	b.Run("Logfmt", func(b *testing.B) {
+		logger := NewLogger(Options{Writer: ioutil.Discard, Name: "benchmark", Format: Logfmt})
+		b.ReportAllocs()
+		b.ResetTimer()
+		for n := 0; n < b.N; n++ {
+			logger.Info("message", "operation failed", "retries", 4, "error", err)
+		}
+	})
+}
+
+func BenchmarkKitLogger(b *testing.B) {
+	err := errors.New("no capacity")
+
+	b.Run("Disabled", func(b *testing.B) {
+		logger := newKitLogger(JSON)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for n := 0; n < b.N; n++ {
+			kitLevel.Debug(logger).Log("message", "operation failed", "retries", 4, "error", err)
+		}
+	})
+
+	b.Run("JSON", func(b *testing.B) {
+		logger := newKitLogger(JSON)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for n := 0; n < b.N; n++ {
+			kitLevel.Info(logger).Log("message", "operation failed", "retries", 4, "error", err)
+		}
+	})
+
+	b.Run("Logfmt", func(b *testing.B) {
+		logger := newKitLogger(Logfmt)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for n := 0; n < b.N; n++ {
+			kitLevel.Info(logger).Log("message", "operation failed", "retries", 4, "error", err)
+		}
+	})
+}
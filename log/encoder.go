@@ -0,0 +1,427 @@
+package log
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	kitLog "github.com/go-kit/kit/log"
+	kitLevel "github.com/go-kit/kit/log/level"
+)
+
+const (
+	hex = "0123456789abcdef"
+
+	// Buffers larger than this will not be returned to the pool
+	maxPooledBufferSize = 64 << 10
+)
+
+var errMissingValue = kitLog.ErrMissingValue.Error()
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		buf := make([]byte, 0, 1024)
+		return &buf
+	},
+}
+
+// appendFunc appends one encoded log event to a buffer
+type appendFunc func(buf []byte, kv []interface{}) []byte
+
+// eventWriter is implemented by writers that frame every log event on their own (syslog, journald, etc.)
+type eventWriter interface {
+	writeEvent(lev Level, kv []interface{}, line []byte) error
+}
+
+// encoder implements go-kit log.Logger
+// It encodes every log event into a pooled buffer and writes it with exactly one call to Write
+type encoder struct {
+	sync.Mutex
+	writer io.Writer
+	events eventWriter
+	append appendFunc
+}
+
+func newEncoder(w io.Writer, format Format) *encoder {
+	e := &encoder{
+		writer: w,
+	}
+
+	if ew, ok := w.(eventWriter); ok {
+		e.events = ew
+	}
+
+	switch format {
+	case Logfmt:
+		e.append = appendLogfmt
+	case JSON:
+		fallthrough
+	default:
+		e.append = appendJSON
+	}
+
+	return e
+}
+
+// Log encodes a log event and writes it to the underlying writer
+func (e *encoder) Log(kv ...interface{}) error {
+	var err error
+
+	bp := bufferPool.Get().(*[]byte)
+	buf := e.append((*bp)[:0], kv)
+
+	e.Lock()
+	if e.events != nil {
+		err = e.events.writeEvent(eventLevel(kv), kv, buf)
+	} else {
+		buf = append(buf, '\n')
+		_, err = e.writer.Write(buf)
+	}
+	e.Unlock()
+
+	if cap(buf) <= maxPooledBufferSize {
+		*bp = buf
+		bufferPool.Put(bp)
+	}
+
+	return err
+}
+
+// eventLevel finds the level of a log event from its key-value pairs
+func eventLevel(kv []interface{}) Level {
+	lev, _ := findLevel(kv)
+	return lev
+}
+
+// findLevel finds the level of a log event from its key-value pairs and returns false if the event has no level
+func findLevel(kv []interface{}) (Level, bool) {
+	for i := 1; i < len(kv); i += 2 {
+		if v, ok := kv[i].(kitLevel.Value); ok {
+			switch v {
+			case kitLevel.DebugValue():
+				return DebugLevel, true
+			case kitLevel.WarnValue():
+				return WarnLevel, true
+			case kitLevel.ErrorValue():
+				return ErrorLevel, true
+			default:
+				return InfoLevel, true
+			}
+		}
+	}
+
+	return InfoLevel, false
+}
+
+func appendJSON(buf []byte, kv []interface{}) []byte {
+	buf = append(buf, '{')
+	for i := 0; i < len(kv); i += 2 {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+
+		buf = appendQuoted(buf, keyString(kv[i]))
+		buf = append(buf, ':')
+
+		if i+1 < len(kv) {
+			buf = appendJSONValue(buf, kv[i+1])
+		} else {
+			buf = appendQuoted(buf, errMissingValue)
+		}
+	}
+
+	return append(buf, '}')
+}
+
+func appendJSONValue(buf []byte, val interface{}) []byte {
+	switch v := val.(type) {
+	case nil:
+		return append(buf, "null"...)
+	case string:
+		return appendQuoted(buf, v)
+	case bool:
+		return strconv.AppendBool(buf, v)
+	case int:
+		return strconv.AppendInt(buf, int64(v), 10)
+	case int8:
+		return strconv.AppendInt(buf, int64(v), 10)
+	case int16:
+		return strconv.AppendInt(buf, int64(v), 10)
+	case int32:
+		return strconv.AppendInt(buf, int64(v), 10)
+	case int64:
+		return strconv.AppendInt(buf, v, 10)
+	case uint:
+		return strconv.AppendUint(buf, uint64(v), 10)
+	case uint8:
+		return strconv.AppendUint(buf, uint64(v), 10)
+	case uint16:
+		return strconv.AppendUint(buf, uint64(v), 10)
+	case uint32:
+		return strconv.AppendUint(buf, uint64(v), 10)
+	case uint64:
+		return strconv.AppendUint(buf, v, 10)
+	case float32:
+		return appendJSONFloat(buf, float64(v), 32)
+	case float64:
+		return appendJSONFloat(buf, v, 64)
+	case time.Time:
+		buf = append(buf, '"')
+		buf = v.AppendFormat(buf, time.RFC3339Nano)
+		return append(buf, '"')
+	case time.Duration:
+		return appendQuoted(buf, v.String())
+	case json.Marshaler, encoding.TextMarshaler:
+		return appendMarshaled(buf, v)
+	case error:
+		if s, ok := safeError(v); ok {
+			return appendQuoted(buf, s)
+		}
+		return append(buf, "null"...)
+	case fmt.Stringer:
+		if s, ok := safeString(v); ok {
+			return appendQuoted(buf, s)
+		}
+		return append(buf, "null"...)
+	default:
+		return appendMarshaled(buf, v)
+	}
+}
+
+func appendJSONFloat(buf []byte, f float64, bitSize int) []byte {
+	// JSON has no representation for NaN and infinities
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return appendQuoted(buf, strconv.FormatFloat(f, 'g', -1, bitSize))
+	}
+
+	return strconv.AppendFloat(buf, f, 'g', -1, bitSize)
+}
+
+func appendMarshaled(buf []byte, val interface{}) []byte {
+	data, err := json.Marshal(val)
+	if err != nil {
+		return appendQuoted(buf, fmt.Sprintf("%+v", val))
+	}
+
+	return append(buf, data...)
+}
+
+func appendLogfmt(buf []byte, kv []interface{}) []byte {
+	for i := 0; i < len(kv); i += 2 {
+		if i > 0 {
+			buf = append(buf, ' ')
+		}
+
+		buf = appendLogfmtKey(buf, keyString(kv[i]))
+		buf = append(buf, '=')
+
+		if i+1 < len(kv) {
+			buf = appendLogfmtValue(buf, kv[i+1])
+		} else {
+			buf = appendLogfmtString(buf, errMissingValue)
+		}
+	}
+
+	return buf
+}
+
+func appendLogfmtKey(buf []byte, key string) []byte {
+	for i, r := range key {
+		if r > ' ' && r != '=' && r != '"' && r != utf8.RuneError {
+			buf = append(buf, key[i:i+utf8.RuneLen(r)]...)
+		}
+	}
+
+	return buf
+}
+
+func appendLogfmtValue(buf []byte, val interface{}) []byte {
+	switch v := val.(type) {
+	case nil:
+		return append(buf, "null"...)
+	case string:
+		if v == "null" {
+			return append(buf, `"null"`...)
+		}
+		return appendLogfmtString(buf, v)
+	case bool:
+		return strconv.AppendBool(buf, v)
+	case int:
+		return strconv.AppendInt(buf, int64(v), 10)
+	case int8:
+		return strconv.AppendInt(buf, int64(v), 10)
+	case int16:
+		return strconv.AppendInt(buf, int64(v), 10)
+	case int32:
+		return strconv.AppendInt(buf, int64(v), 10)
+	case int64:
+		return strconv.AppendInt(buf, v, 10)
+	case uint:
+		return strconv.AppendUint(buf, uint64(v), 10)
+	case uint8:
+		return strconv.AppendUint(buf, uint64(v), 10)
+	case uint16:
+		return strconv.AppendUint(buf, uint64(v), 10)
+	case uint32:
+		return strconv.AppendUint(buf, uint64(v), 10)
+	case uint64:
+		return strconv.AppendUint(buf, v, 10)
+	case float32:
+		return strconv.AppendFloat(buf, float64(v), 'g', -1, 32)
+	case float64:
+		return strconv.AppendFloat(buf, v, 'g', -1, 64)
+	case time.Time:
+		return v.AppendFormat(buf, time.RFC3339Nano)
+	case encoding.TextMarshaler:
+		text, err := v.MarshalText()
+		if err != nil {
+			return appendLogfmtString(buf, err.Error())
+		}
+		return appendLogfmtString(buf, string(text))
+	case error:
+		if s, ok := safeError(v); ok {
+			return appendLogfmtString(buf, s)
+		}
+		return append(buf, "null"...)
+	case fmt.Stringer:
+		if s, ok := safeString(v); ok {
+			return appendLogfmtString(buf, s)
+		}
+		return append(buf, "null"...)
+	default:
+		return appendLogfmtString(buf, fmt.Sprintf("%+v", v))
+	}
+}
+
+func appendLogfmtString(buf []byte, s string) []byte {
+	for _, r := range s {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError {
+			return appendQuoted(buf, s)
+		}
+	}
+
+	return append(buf, s...)
+}
+
+// appendText appends a value as plain unquoted text
+func appendText(buf []byte, val interface{}) []byte {
+	switch v := val.(type) {
+	case string:
+		return append(buf, v...)
+	case time.Time:
+		return v.AppendFormat(buf, time.RFC3339Nano)
+	case error:
+		s, _ := safeError(v)
+		return append(buf, s...)
+	case fmt.Stringer:
+		s, _ := safeString(v)
+		return append(buf, s...)
+	default:
+		return appendJSONValue(buf, v)
+	}
+}
+
+// appendQuoted appends a quoted and escaped JSON string
+func appendQuoted(buf []byte, s string) []byte {
+	buf = append(buf, '"')
+
+	start := 0
+	for i := 0; i < len(s); {
+		if b := s[i]; b < utf8.RuneSelf {
+			if b >= 0x20 && b != '\\' && b != '"' {
+				i++
+				continue
+			}
+
+			buf = append(buf, s[start:i]...)
+			switch b {
+			case '\\', '"':
+				buf = append(buf, '\\', b)
+			case '\n':
+				buf = append(buf, '\\', 'n')
+			case '\r':
+				buf = append(buf, '\\', 'r')
+			case '\t':
+				buf = append(buf, '\\', 't')
+			default:
+				buf = append(buf, '\\', 'u', '0', '0', hex[b>>4], hex[b&0xF])
+			}
+
+			i++
+			start = i
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			buf = append(buf, s[start:i]...)
+			buf = append(buf, `\ufffd`...)
+			i += size
+			start = i
+			continue
+		}
+
+		// U+2028 and U+2029 are valid JSON but break JavaScript parsers
+		if r == '\u2028' || r == '\u2029' {
+			buf = append(buf, s[start:i]...)
+			buf = append(buf, '\\', 'u', '2', '0', '2', hex[r&0xF])
+			i += size
+			start = i
+			continue
+		}
+
+		i += size
+	}
+
+	buf = append(buf, s[start:]...)
+	return append(buf, '"')
+}
+
+func keyString(key interface{}) string {
+	switch k := key.(type) {
+	case string:
+		return k
+	case fmt.Stringer:
+		s, _ := safeString(k)
+		return s
+	default:
+		return fmt.Sprint(k)
+	}
+}
+
+// safeError calls the Error method and recovers from a panic caused by a nil pointer receiver
+func safeError(err error) (s string, ok bool) {
+	defer func() {
+		if val := recover(); val != nil {
+			if v := reflect.ValueOf(err); v.Kind() == reflect.Ptr && v.IsNil() {
+				s, ok = "", false
+			} else {
+				panic(val)
+			}
+		}
+	}()
+
+	return err.Error(), true
+}
+
+// safeString calls the String method and recovers from a panic caused by a nil pointer receiver
+func safeString(str fmt.Stringer) (s string, ok bool) {
+	defer func() {
+		if val := recover(); val != nil {
+			if v := reflect.ValueOf(str); v.Kind() == reflect.Ptr && v.IsNil() {
+				s, ok = "", false
+			} else {
+				panic(val)
+			}
+		}
+	}()
+
+	return str.String(), true
+}
@@ -0,0 +1,189 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	kitLevel "github.com/go-kit/kit/log/level"
+	"github.com/stretchr/testify/assert"
+)
+
+type stringer string
+
+func (s stringer) String() string {
+	return string(s)
+}
+
+type nilError struct {
+	message string
+}
+
+func (e *nilError) Error() string {
+	return e.message
+}
+
+func TestNewEncoder(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+	}{
+		{"JSON", JSON},
+		{"Logfmt", Logfmt},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			e := newEncoder(&bytes.Buffer{}, tc.format)
+			assert.NotNil(t, e)
+			assert.NotNil(t, e.writer)
+			assert.NotNil(t, e.append)
+		})
+	}
+}
+
+func TestEncoderJSON(t *testing.T) {
+	ts := time.Date(2019, 9, 1, 12, 30, 0, 0, time.UTC)
+	var nilErr *nilError
+
+	tests := []struct {
+		name           string
+		kv             []interface{}
+		expectedOutput string
+	}{
+		{
+			"Empty",
+			[]interface{}{},
+			"{}\n",
+		},
+		{
+			"Strings",
+			[]interface{}{"message", "hello \"world\"\n", "tab", "a\tb"},
+			`{"message":"hello \"world\"\n","tab":"a\tb"}` + "\n",
+		},
+		{
+			"Numbers",
+			[]interface{}{"int", -10, "uint", uint8(7), "float", 0.25, "nan", math.NaN()},
+			`{"int":-10,"uint":7,"float":0.25,"nan":"NaN"}` + "\n",
+		},
+		{
+			"Types",
+			[]interface{}{"bool", true, "nil", nil, "time", ts, "duration", 2 * time.Second},
+			`{"bool":true,"nil":null,"time":"2019-09-01T12:30:00Z","duration":"2s"}` + "\n",
+		},
+		{
+			"Errors",
+			[]interface{}{"error", errors.New("failed"), "nilError", nilErr, "stringer", stringer("value")},
+			`{"error":"failed","nilError":null,"stringer":"value"}` + "\n",
+		},
+		{
+			"Composites",
+			[]interface{}{"map", map[string]int{"retries": 4}, "slice", []string{"a", "b"}},
+			`{"map":{"retries":4},"slice":["a","b"]}` + "\n",
+		},
+		{
+			"NonStringKey",
+			[]interface{}{1, "one", stringer("two"), 2},
+			`{"1":"one","two":2}` + "\n",
+		},
+		{
+			"MissingValue",
+			[]interface{}{"message"},
+			`{"message":"(MISSING)"}` + "\n",
+		},
+		{
+			"InvalidUTF8",
+			[]interface{}{"message", "a\xffb\u2028"},
+			`{"message":"a\ufffdb\u2028"}` + "\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			buff := &bytes.Buffer{}
+			e := newEncoder(buff, JSON)
+
+			err := e.Log(tc.kv...)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedOutput, buff.String())
+
+			var m map[string]interface{}
+			assert.NoError(t, json.Unmarshal(buff.Bytes(), &m))
+		})
+	}
+}
+
+func TestEncoderLogfmt(t *testing.T) {
+	ts := time.Date(2019, 9, 1, 12, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		kv             []interface{}
+		expectedOutput string
+	}{
+		{
+			"Empty",
+			[]interface{}{},
+			"\n",
+		},
+		{
+			"Strings",
+			[]interface{}{"message", "hello world", "simple", "value", "empty", "", "null", "null"},
+			`message="hello world" simple=value empty= null="null"` + "\n",
+		},
+		{
+			"Numbers",
+			[]interface{}{"int", -10, "uint", uint8(7), "float", 0.25},
+			"int=-10 uint=7 float=0.25\n",
+		},
+		{
+			"Types",
+			[]interface{}{"bool", false, "nil", nil, "time", ts, "error", errors.New("not found")},
+			`bool=false nil=null time=2019-09-01T12:30:00Z error="not found"` + "\n",
+		},
+		{
+			"InvalidKey",
+			[]interface{}{"the key=", "value"},
+			"thekey=value\n",
+		},
+		{
+			"MissingValue",
+			[]interface{}{"message"},
+			"message=(MISSING)\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			buff := &bytes.Buffer{}
+			e := newEncoder(buff, Logfmt)
+
+			err := e.Log(tc.kv...)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedOutput, buff.String())
+		})
+	}
+}
+
+func TestEventLevel(t *testing.T) {
+	tests := []struct {
+		name          string
+		kv            []interface{}
+		expectedLevel Level
+	}{
+		{"NoLevel", []interface{}{"message", "hello"}, InfoLevel},
+		{"Debug", []interface{}{"level", kitLevel.DebugValue(), "message", "hello"}, DebugLevel},
+		{"Info", []interface{}{"level", kitLevel.InfoValue()}, InfoLevel},
+		{"Warn", []interface{}{"caller", "main.go:10", "level", kitLevel.WarnValue()}, WarnLevel},
+		{"Error", []interface{}{"level", kitLevel.ErrorValue()}, ErrorLevel},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedLevel, eventLevel(tc.kv))
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package log
+
+import (
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultJournaldSocket is the default path to journald native protocol socket
+const DefaultJournaldSocket = "/run/systemd/journal/socket"
+
+// JournaldOptions contains optional options for JournaldWriter
+type JournaldOptions struct {
+	Socket     string
+	Identifier string
+}
+
+// JournaldWriter is an io.Writer that writes every log event as a journald native protocol datagram
+// Every key-value pair of a log event becomes a journal field (i.e. "region" becomes REGION)
+type JournaldWriter struct {
+	sync.Mutex
+	identifier string
+	conn       *net.UnixConn
+}
+
+// NewJournaldWriter creates a new journald writer
+func NewJournaldWriter(opts JournaldOptions) (*JournaldWriter, error) {
+	if opts.Socket == "" {
+		opts.Socket = DefaultJournaldSocket
+	}
+
+	if opts.Identifier == "" {
+		opts.Identifier = filepath.Base(os.Args[0])
+	}
+
+	addr := &net.UnixAddr{
+		Name: opts.Socket,
+		Net:  "unixgram",
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &JournaldWriter{
+		identifier: opts.Identifier,
+		conn:       conn,
+	}, nil
+}
+
+func (w *JournaldWriter) writeEvent(lev Level, kv []interface{}, line []byte) error {
+	var hasMessage bool
+
+	buf := make([]byte, 0, len(line)+256)
+	buf = appendJournaldField(buf, "PRIORITY", strconv.Itoa(SyslogSeverity(lev)))
+	buf = appendJournaldField(buf, "SYSLOG_IDENTIFIER", w.identifier)
+
+	for i := 0; i+1 < len(kv); i += 2 {
+		name := journaldFieldName(keyString(kv[i]))
+		if name == "" || name == "PRIORITY" || name == "SYSLOG_IDENTIFIER" {
+			continue
+		}
+
+		if name == "MESSAGE" {
+			hasMessage = true
+		}
+
+		value := string(appendText(nil, kv[i+1]))
+		buf = appendJournaldField(buf, name, value)
+	}
+
+	if !hasMessage {
+		buf = appendJournaldField(buf, "MESSAGE", string(line))
+	}
+
+	w.Lock()
+	defer w.Unlock()
+
+	_, err := w.conn.Write(buf)
+	return err
+}
+
+// Write writes p as an informational journal entry
+func (w *JournaldWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	if n > 0 && p[n-1] == '\n' {
+		p = p[:n-1]
+	}
+
+	if err := w.writeEvent(InfoLevel, nil, p); err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// Close closes the journald writer
+func (w *JournaldWriter) Close() error {
+	return w.conn.Close()
+}
+
+// appendJournaldField appends a field in journald native protocol format
+// Values containing newlines are written with an explicit little-endian 64-bit size
+func appendJournaldField(buf []byte, name, value string) []byte {
+	buf = append(buf, name...)
+
+	if strings.IndexByte(value, '\n') == -1 {
+		buf = append(buf, '=')
+		buf = append(buf, value...)
+		return append(buf, '\n')
+	}
+
+	var size [8]byte
+	binary.LittleEndian.PutUint64(size[:], uint64(len(value)))
+
+	buf = append(buf, '\n')
+	buf = append(buf, size[:]...)
+	buf = append(buf, value...)
+	return append(buf, '\n')
+}
+
+// journaldFieldName converts a key to a valid journal field name
+// Field names consist of uppercase letters, digits, and underscores and cannot start with an underscore or a digit
+func journaldFieldName(key string) string {
+	name := make([]byte, 0, len(key))
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		switch {
+		case c >= 'a' && c <= 'z':
+			name = append(name, c-'a'+'A')
+		case c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+			name = append(name, c)
+		default:
+			name = append(name, '_')
+		}
+	}
+
+	// Trim leading characters not allowed at the beginning
+	for len(name) > 0 && (name[0] == '_' || (name[0] >= '0' && name[0] <= '9')) {
+		name = name[1:]
+	}
+
+	if len(name) > 64 {
+		name = name[:64]
+	}
+
+	return string(name)
+}
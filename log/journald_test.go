@@ -0,0 +1,136 @@
+package log
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// parseJournaldFields parses a journald native protocol datagram
+func parseJournaldFields(t *testing.T, data []byte) map[string]string {
+	fields := map[string]string{}
+
+	for len(data) > 0 {
+		i := bytes.IndexAny(data, "=\n")
+		assert.NotEqual(t, -1, i)
+
+		name := string(data[:i])
+		if data[i] == '=' {
+			data = data[i+1:]
+			j := bytes.IndexByte(data, '\n')
+			fields[name] = string(data[:j])
+			data = data[j+1:]
+		} else {
+			data = data[i+1:]
+			size := binary.LittleEndian.Uint64(data[:8])
+			data = data[8:]
+			fields[name] = string(data[:size])
+			data = data[size+1:]
+		}
+	}
+
+	return fields
+}
+
+func TestJournaldFieldName(t *testing.T) {
+	tests := []struct {
+		key          string
+		expectedName string
+	}{
+		{"message", "MESSAGE"},
+		{"req.method", "REQ_METHOD"},
+		{"_hidden", "HIDDEN"},
+		{"2xx", "XX"},
+		{"---", ""},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, tc.expectedName, journaldFieldName(tc.key))
+	}
+}
+
+func TestJournaldWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journald")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "journal.sock")
+	conn, err := net.ListenPacket("unixgram", socket)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	read := func() map[string]string {
+		buf := make([]byte, 65536)
+		n, _, err := conn.ReadFrom(buf)
+		assert.NoError(t, err)
+		return parseJournaldFields(t, buf[:n])
+	}
+
+	w, err := NewJournaldWriter(JournaldOptions{
+		Socket:     socket,
+		Identifier: "goto",
+	})
+	assert.NoError(t, err)
+	defer w.Close()
+
+	logger := NewLogger(Options{
+		Writer:      w,
+		Level:       "debug",
+		Name:        "test",
+		Environment: "dev",
+		Region:      "local",
+		Component:   "app",
+	})
+
+	tests := []struct {
+		name             string
+		log              func(kv ...interface{}) error
+		kv               []interface{}
+		expectedPriority string
+		expectedMessage  string
+	}{
+		{"Debug", logger.Debug, []interface{}{"message", "debug message"}, "7", "debug message"},
+		{"Info", logger.Info, []interface{}{"message", "info message"}, "6", "info message"},
+		{"Warn", logger.Warn, []interface{}{"message", "warn\nmessage"}, "4", "warn\nmessage"},
+		{"Error", logger.Error, []interface{}{"error", "no message"}, "3", ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.log(tc.kv...)
+			assert.NoError(t, err)
+
+			fields := read()
+			assert.Equal(t, tc.expectedPriority, fields["PRIORITY"])
+			assert.Equal(t, "goto", fields["SYSLOG_IDENTIFIER"])
+			assert.Equal(t, "test", fields["LOGGER"])
+			assert.Equal(t, "dev", fields["ENVIRONMENT"])
+			assert.Equal(t, "local", fields["REGION"])
+			assert.Equal(t, "app", fields["COMPONENT"])
+			assert.NotEmpty(t, fields["CALLER"])
+			assert.NotEmpty(t, fields["TIMESTAMP"])
+
+			if tc.expectedMessage != "" {
+				assert.Equal(t, tc.expectedMessage, fields["MESSAGE"])
+			} else {
+				assert.Contains(t, fields["MESSAGE"], `"error":"no message"`)
+			}
+		})
+	}
+
+	t.Run("Write", func(t *testing.T) {
+		n, err := w.Write([]byte("plain message\n"))
+		assert.NoError(t, err)
+		assert.Equal(t, 14, n)
+
+		fields := read()
+		assert.Equal(t, "6", fields["PRIORITY"])
+		assert.Equal(t, "plain message", fields["MESSAGE"])
+	})
+}
@@ -0,0 +1,162 @@
+package log
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ParseLevel parses a logging level from its name (debug, info, warn, error, or none)
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return DebugLevel, nil
+	case "info":
+		return InfoLevel, nil
+	case "warn":
+		return WarnLevel, nil
+	case "error":
+		return ErrorLevel, nil
+	case "none":
+		return NoneLevel, nil
+	default:
+		return InfoLevel, fmt.Errorf("invalid logging level: %s", s)
+	}
+}
+
+type levelRule struct {
+	prefix string
+	level  Level
+}
+
+// LevelTable is a table of logging levels keyed by logger name prefix
+// A prefix matches a logger with the same name and all of its descendant loggers (db matches db and db.pool).
+// The longest matching prefix wins and loggers with no matching prefix use their own level.
+// A LevelTable is safe to be changed at runtime by multiple goroutines.
+type LevelTable struct {
+	sync.Mutex
+	version uint32
+	rules   atomic.Value // []levelRule sorted by prefix length (longest first)
+}
+
+// NewLevelTable creates a new empty level table
+func NewLevelTable() *LevelTable {
+	t := &LevelTable{}
+	t.rules.Store([]levelRule{})
+	return t
+}
+
+// normalizePrefix converts db.* and db to the same prefix
+func normalizePrefix(prefix string) string {
+	prefix = strings.TrimSpace(prefix)
+	prefix = strings.TrimSuffix(prefix, "*")
+	prefix = strings.TrimSuffix(prefix, ".")
+	return prefix
+}
+
+// store replaces the rules and invalidates the cached levels of all loggers
+func (t *LevelTable) store(m map[string]Level) {
+	rules := make([]levelRule, 0, len(m))
+	for prefix, lev := range m {
+		rules = append(rules, levelRule{prefix, lev})
+	}
+
+	sort.Slice(rules, func(i, j int) bool {
+		if len(rules[i].prefix) != len(rules[j].prefix) {
+			return len(rules[i].prefix) > len(rules[j].prefix)
+		}
+		return rules[i].prefix < rules[j].prefix
+	})
+
+	t.rules.Store(rules)
+	atomic.AddUint32(&t.version, 1)
+}
+
+func (t *LevelTable) load() map[string]Level {
+	rules := t.rules.Load().([]levelRule)
+	m := make(map[string]Level, len(rules))
+	for _, r := range rules {
+		m[r.prefix] = r.level
+	}
+
+	return m
+}
+
+// Set sets the level for a logger name prefix
+func (t *LevelTable) Set(prefix string, lev Level) {
+	t.Lock()
+	defer t.Unlock()
+
+	m := t.load()
+	m[normalizePrefix(prefix)] = lev
+	t.store(m)
+}
+
+// Delete removes the level for a logger name prefix
+func (t *LevelTable) Delete(prefix string) {
+	t.Lock()
+	defer t.Unlock()
+
+	m := t.load()
+	delete(m, normalizePrefix(prefix))
+	t.store(m)
+}
+
+// Parse replaces all levels in the table with levels from a string
+// The string is a comma-separated list of prefix=level pairs (i.e. db=debug,http.*=warn)
+func (t *LevelTable) Parse(s string) error {
+	m := map[string]Level{}
+
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+
+		i := strings.LastIndex(pair, "=")
+		if i == -1 {
+			return fmt.Errorf("invalid logging level override: %s", pair)
+		}
+
+		lev, err := ParseLevel(pair[i+1:])
+		if err != nil {
+			return err
+		}
+
+		m[normalizePrefix(pair[:i])] = lev
+	}
+
+	t.Lock()
+	defer t.Unlock()
+
+	t.store(m)
+
+	return nil
+}
+
+// Lookup returns the level for a logger name from the longest matching prefix
+func (t *LevelTable) Lookup(name string) (Level, bool) {
+	for _, r := range t.rules.Load().([]levelRule) {
+		if r.prefix == "" || name == r.prefix || strings.HasPrefix(name, r.prefix) && name[len(r.prefix)] == '.' {
+			return r.level, true
+		}
+	}
+
+	return InfoLevel, false
+}
+
+// String returns the table in the same format accepted by Parse
+func (t *LevelTable) String() string {
+	rules := t.rules.Load().([]levelRule)
+	pairs := make([]string, len(rules))
+	for i, r := range rules {
+		prefix := r.prefix
+		if prefix == "" {
+			prefix = "*"
+		}
+		pairs[i] = fmt.Sprintf("%s=%s", prefix, r.level)
+	}
+
+	return strings.Join(pairs, ",")
+}
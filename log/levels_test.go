@@ -0,0 +1,136 @@
+package log
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		s             string
+		expectedLevel Level
+		expectedError string
+	}{
+		{"debug", DebugLevel, ""},
+		{"Info", InfoLevel, ""},
+		{" WARN ", WarnLevel, ""},
+		{"error", ErrorLevel, ""},
+		{"none", NoneLevel, ""},
+		{"", InfoLevel, "invalid logging level: "},
+		{"trace", InfoLevel, "invalid logging level: trace"},
+	}
+
+	for _, tc := range tests {
+		lev, err := ParseLevel(tc.s)
+
+		assert.Equal(t, tc.expectedLevel, lev)
+		if tc.expectedError == "" {
+			assert.NoError(t, err)
+		} else {
+			assert.EqualError(t, err, tc.expectedError)
+		}
+	}
+}
+
+func TestLevelTableParse(t *testing.T) {
+	tests := []struct {
+		name           string
+		s              string
+		expectedError  string
+		expectedString string
+	}{
+		{
+			name:           "Empty",
+			s:              "",
+			expectedString: "",
+		},
+		{
+			name:           "Prefixes",
+			s:              "db=debug,http=warn",
+			expectedString: "http=warn,db=debug",
+		},
+		{
+			name:           "Wildcards",
+			s:              "db.*=debug, db.pool.*=error ,*=warn",
+			expectedString: "db.pool=error,db=debug,*=warn",
+		},
+		{
+			name:          "MissingLevel",
+			s:             "db",
+			expectedError: "invalid logging level override: db",
+		},
+		{
+			name:          "InvalidLevel",
+			s:             "db=trace",
+			expectedError: "invalid logging level: trace",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			table := NewLevelTable()
+			err := table.Parse(tc.s)
+
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedString, table.String())
+			}
+		})
+	}
+}
+
+func TestLevelTableLookup(t *testing.T) {
+	table := NewLevelTable()
+	err := table.Parse("db.*=debug,db.pool=error,http=warn")
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		expectedLevel Level
+		expectedOK    bool
+	}{
+		{"db", DebugLevel, true},
+		{"db.conn", DebugLevel, true},
+		{"db.pool", ErrorLevel, true},
+		{"db.pool.idle", ErrorLevel, true},
+		{"dbx", InfoLevel, false},
+		{"http", WarnLevel, true},
+		{"grpc", InfoLevel, false},
+		{"", InfoLevel, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			lev, ok := table.Lookup(tc.name)
+
+			assert.Equal(t, tc.expectedLevel, lev)
+			assert.Equal(t, tc.expectedOK, ok)
+		})
+	}
+}
+
+func TestLevelTableSetDelete(t *testing.T) {
+	table := NewLevelTable()
+
+	table.Set("db.*", DebugLevel)
+	lev, ok := table.Lookup("db.pool")
+	assert.True(t, ok)
+	assert.Equal(t, DebugLevel, lev)
+
+	table.Set("*", ErrorLevel)
+	lev, ok = table.Lookup("http")
+	assert.True(t, ok)
+	assert.Equal(t, ErrorLevel, lev)
+
+	table.Delete("db")
+	lev, ok = table.Lookup("db.pool")
+	assert.True(t, ok)
+	assert.Equal(t, ErrorLevel, lev)
+
+	table.Delete("*")
+	_, ok = table.Lookup("db.pool")
+	assert.False(t, ok)
+}
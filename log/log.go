@@ -4,9 +4,10 @@
 package log
 
 import (
+	"fmt"
 	"io"
 	"os"
-	"strings"
+	"sync/atomic"
 
 	kitLog "github.com/go-kit/kit/log"
 	kitLevel "github.com/go-kit/kit/log/level"
@@ -25,6 +26,7 @@ type (
 		Writer      io.Writer
 		Format      Format
 		Level       string
+		Levels      string
 		Name        string
 		Environment string
 		Region      string
@@ -32,9 +34,21 @@ type (
 	}
 
 	// Logger wraps a go-kit Logger
+	// The embedded go-kit Logger discards events below the level of logger too, so it can be used directly.
 	Logger struct {
+		cache  uint32 // version of level table << 8 | cached level flags
 		Level  Level
 		Logger kitLog.Logger
+		base   kitLog.Logger
+		name   string
+		levels *LevelTable
+	}
+
+	// levelFilter is a go-kit Logger discarding events below the effective level of a Logger
+	// Events without a level are not discarded.
+	levelFilter struct {
+		logger *Logger
+		next   kitLog.Logger
 	}
 )
 
@@ -58,6 +72,30 @@ const (
 	NoneLevel
 )
 
+const (
+	cachedOverride = 0x80
+	cachedDefault  = 0x40
+	cachedLevel    = 0x0f
+)
+
+// String returns the name of logging level
+func (l Level) String() string {
+	switch l {
+	case DebugLevel:
+		return "debug"
+	case InfoLevel:
+		return "info"
+	case WarnLevel:
+		return "warn"
+	case ErrorLevel:
+		return "error"
+	case NoneLevel:
+		return "none"
+	default:
+		return fmt.Sprintf("Level(%d)", int(l))
+	}
+}
+
 var singleton = NewLogger(Options{
 	depth: 7,
 	Name:  "singleton",
@@ -67,6 +105,7 @@ var singleton = NewLogger(Options{
 func NewNopLogger() *Logger {
 	logger := kitLog.NewNopLogger()
 	return &Logger{
+		Level:  NoneLevel,
 		Logger: logger,
 	}
 }
@@ -79,7 +118,6 @@ func NewLogger(opts Options) *Logger {
 }
 
 func (l *Logger) setOptions(opts Options) {
-	var lev Level
 	var logger kitLog.Logger
 
 	if opts.depth == 0 {
@@ -90,25 +128,12 @@ func (l *Logger) setOptions(opts Options) {
 		opts.Writer = os.Stdout
 	}
 
-	switch opts.Format {
-	case Logfmt:
-		logger = kitLog.NewLogfmtLogger(opts.Writer)
-	case JSON:
-		fallthrough
-	default:
-		logger = kitLog.NewJSONLogger(opts.Writer)
-	}
-
-	logger = kitLog.NewSyncLogger(logger)
+	logger = newEncoder(opts.Writer, opts.Format)
 	logger = kitLog.With(logger,
 		"caller", kitLog.Caller(opts.depth),
 		"timestamp", kitLog.DefaultTimestampUTC,
 	)
 
-	if opts.Name != "" {
-		logger = kitLog.With(logger, "logger", opts.Name)
-	}
-
 	if opts.Environment != "" {
 		logger = kitLog.With(logger, "environment", opts.Environment)
 	}
@@ -121,57 +146,178 @@ func (l *Logger) setOptions(opts Options) {
 		logger = kitLog.With(logger, "component", opts.Component)
 	}
 
-	switch strings.ToLower(opts.Level) {
-	case "debug":
-		lev = DebugLevel
-		logger = kitLevel.NewFilter(logger, kitLevel.AllowDebug())
-	case "info":
-		lev = InfoLevel
-		logger = kitLevel.NewFilter(logger, kitLevel.AllowInfo())
-	case "warn":
-		lev = WarnLevel
-		logger = kitLevel.NewFilter(logger, kitLevel.AllowWarn())
-	case "error":
-		lev = ErrorLevel
-		logger = kitLevel.NewFilter(logger, kitLevel.AllowError())
-	case "none":
-		lev = NoneLevel
-		logger = kitLevel.NewFilter(logger, kitLevel.AllowNone())
-	default:
-		lev = InfoLevel
-		logger = kitLevel.NewFilter(logger, kitLevel.AllowInfo())
+	// Filtering by level happens in Logger methods before any allocation
+	// An invalid level falls back to info
+	lev, levErr := ParseLevel(opts.Level)
+	if opts.Level == "" {
+		levErr = nil
 	}
 
+	// Invalid level overrides are ignored
+	levels := NewLevelTable()
+	levelsErr := levels.Parse(opts.Levels)
+
+	atomic.StoreUint32(&l.cache, 0)
 	l.Level = lev
-	l.Logger = logger
+	l.name = opts.Name
+	l.levels = levels
+	l.setBase(logger)
+
+	// Invalid levels are logged regardless of level, so a configuration typo does not go unnoticed
+	if levErr != nil {
+		_ = l.base.Log(l.pairs(kitLevel.ErrorValue(), []interface{}{"message", fmt.Sprintf("%s, using %s", levErr, lev)})...)
+	}
+
+	if levelsErr != nil {
+		_ = l.base.Log(l.pairs(kitLevel.ErrorValue(), []interface{}{"message", fmt.Sprintf("%s, ignoring all overrides", levelsErr)})...)
+	}
+}
+
+// setBase sets the go-kit Logger for logging events and wraps it with a level filter for the embedded Logger
+func (l *Logger) setBase(base kitLog.Logger) {
+	l.base = base
+	l.Logger = &levelFilter{
+		logger: l,
+		next:   base,
+	}
+}
+
+// Log implements go-kit Logger interface
+func (f *levelFilter) Log(kv ...interface{}) error {
+	if lev, ok := findLevel(kv); ok && lev < f.logger.level() {
+		return nil
+	}
+
+	return f.next.Log(kv...)
 }
 
 // With returns a new logger which always logs a set of key-value pairs
 func (l *Logger) With(kv ...interface{}) *Logger {
-	return &Logger{
+	base := l.base
+	if base == nil {
+		base = l.Logger
+	}
+
+	logger := &Logger{
 		Level:  l.Level,
-		Logger: kitLog.With(l.Logger, kv...),
+		name:   l.name,
+		levels: l.levels,
 	}
+	logger.setBase(kitLog.With(base, kv...))
+
+	return logger
+}
+
+// Named returns a new child logger with a dotted name (parent.child)
+// The level of a named logger can be overridden by the level table shared with its parent
+func (l *Logger) Named(name string) *Logger {
+	if l.name != "" {
+		name = l.name + "." + name
+	}
+
+	base := l.base
+	if base == nil {
+		base = l.Logger
+	}
+
+	logger := &Logger{
+		Level:  l.Level,
+		name:   name,
+		levels: l.levels,
+	}
+	logger.setBase(base)
+
+	return logger
+}
+
+// Name returns the name of logger
+func (l *Logger) Name() string {
+	return l.name
+}
+
+// Levels returns the level table shared by this logger, its parent, and its children
+// The table can be changed at runtime for overriding levels of named loggers
+func (l *Logger) Levels() *LevelTable {
+	return l.levels
+}
+
+// level returns the effective level of logger
+// The result of looking up the level table is cached until the table changes
+func (l *Logger) level() Level {
+	if l.levels == nil {
+		return l.Level
+	}
+
+	version := atomic.LoadUint32(&l.levels.version) & 0xffffff
+	cache := atomic.LoadUint32(&l.cache)
+
+	if cache != 0 && cache>>8 == version {
+		if cache&cachedOverride != 0 {
+			return Level(cache & cachedLevel)
+		}
+		return l.Level
+	}
+
+	if lev, ok := l.levels.Lookup(l.name); ok {
+		atomic.StoreUint32(&l.cache, version<<8|cachedOverride|uint32(lev)&cachedLevel)
+		return lev
+	}
+
+	atomic.StoreUint32(&l.cache, version<<8|cachedDefault)
+	return l.Level
+}
+
+// log prepends the level and the logger name to key-value pairs and logs them
+// kv is copied, so it does not escape and a disabled level costs no allocation at call site
+func (l *Logger) log(lev kitLevel.Value, kv []interface{}) error {
+	return l.Logger.Log(l.pairs(lev, kv)...)
+}
+
+// pairs prepends the level and the logger name to key-value pairs
+func (l *Logger) pairs(lev kitLevel.Value, kv []interface{}) []interface{} {
+	pairs := make([]interface{}, 0, len(kv)+4)
+	pairs = append(pairs, kitLevel.Key(), lev)
+	if l.name != "" {
+		pairs = append(pairs, "logger", l.name)
+	}
+
+	return append(pairs, kv...)
 }
 
 // Debug logs a debug-level event
 func (l *Logger) Debug(kv ...interface{}) error {
-	return kitLevel.Debug(l.Logger).Log(kv...)
+	if l.level() > DebugLevel {
+		return nil
+	}
+
+	return l.log(kitLevel.DebugValue(), kv)
 }
 
 // Info logs an info-level event
 func (l *Logger) Info(kv ...interface{}) error {
-	return kitLevel.Info(l.Logger).Log(kv...)
+	if l.level() > InfoLevel {
+		return nil
+	}
+
+	return l.log(kitLevel.InfoValue(), kv)
 }
 
 // Warn logs a warn-level event
 func (l *Logger) Warn(kv ...interface{}) error {
-	return kitLevel.Warn(l.Logger).Log(kv...)
+	if l.level() > WarnLevel {
+		return nil
+	}
+
+	return l.log(kitLevel.WarnValue(), kv)
 }
 
 // Error logs an error-level event
 func (l *Logger) Error(kv ...interface{}) error {
-	return kitLevel.Error(l.Logger).Log(kv...)
+	if l.level() > ErrorLevel {
+		return nil
+	}
+
+	return l.log(kitLevel.ErrorValue(), kv)
 }
 
 // SetOptions set optional options for singleton logger
@@ -2,9 +2,11 @@ package log
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"testing"
 
+	kitLevel "github.com/go-kit/kit/log/level"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -117,6 +119,15 @@ func TestLoggerSetOptions(t *testing.T) {
 			},
 			NoneLevel,
 		},
+		{
+			"WithLevels",
+			Options{
+				Level:  "warn",
+				Levels: "db=debug,http=error",
+				Name:   "instance",
+			},
+			WarnLevel,
+		},
 		{
 			"CustomWriter",
 			Options{
@@ -140,6 +151,43 @@ func TestLoggerSetOptions(t *testing.T) {
 	}
 }
 
+func TestLoggerInvalidLevels(t *testing.T) {
+	tests := []struct {
+		name            string
+		opts            Options
+		expectedLevel   Level
+		expectedMessage string
+	}{
+		{
+			name:            "InvalidLevel",
+			opts:            Options{Level: "verbose"},
+			expectedLevel:   InfoLevel,
+			expectedMessage: "invalid logging level: verbose, using info",
+		},
+		{
+			name:            "InvalidLevels",
+			opts:            Options{Level: "none", Levels: "db=debug,http=verbose"},
+			expectedLevel:   NoneLevel,
+			expectedMessage: "invalid logging level: verbose, ignoring all overrides",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			buff := &bytes.Buffer{}
+			tc.opts.Writer = buff
+			logger := NewLogger(tc.opts)
+
+			assert.Equal(t, tc.expectedLevel, logger.Level)
+
+			var log map[string]interface{}
+			assert.NoError(t, json.NewDecoder(buff).Decode(&log))
+			assert.Equal(t, "error", log["level"])
+			assert.Equal(t, tc.expectedMessage, log["message"])
+		})
+	}
+}
+
 func TestLoggerWith(t *testing.T) {
 	tests := []struct {
 		mockLogger mockLogger
@@ -223,6 +271,148 @@ func TestLoggerLog(t *testing.T) {
 	}
 }
 
+func TestLoggerLevel(t *testing.T) {
+	tests := []struct {
+		name          string
+		level         Level
+		expectedCalls []bool
+	}{
+		{"DebugLevel", DebugLevel, []bool{true, true, true, true}},
+		{"InfoLevel", InfoLevel, []bool{false, true, true, true}},
+		{"WarnLevel", WarnLevel, []bool{false, false, true, true}},
+		{"ErrorLevel", ErrorLevel, []bool{false, false, false, true}},
+		{"NoneLevel", NoneLevel, []bool{false, false, false, false}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			for i, log := range []func(*Logger) error{
+				func(l *Logger) error { return l.Debug("message", "debug") },
+				func(l *Logger) error { return l.Info("message", "info") },
+				func(l *Logger) error { return l.Warn("message", "warn") },
+				func(l *Logger) error { return l.Error("message", "error") },
+			} {
+				mock := &mockLogger{}
+				logger := &Logger{Level: tc.level, Logger: mock}
+
+				err := log(logger)
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedCalls[i], mock.LogInKV != nil)
+			}
+		})
+	}
+}
+
+func TestLevelString(t *testing.T) {
+	tests := []struct {
+		level          Level
+		expectedString string
+	}{
+		{DebugLevel, "debug"},
+		{InfoLevel, "info"},
+		{WarnLevel, "warn"},
+		{ErrorLevel, "error"},
+		{NoneLevel, "none"},
+		{Level(9), "Level(9)"},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, tc.expectedString, tc.level.String())
+	}
+}
+
+func TestLoggerNamed(t *testing.T) {
+	buff := &bytes.Buffer{}
+	logger := NewLogger(Options{
+		Writer: buff,
+		Name:   "service",
+		Levels: "service.db=debug",
+	})
+
+	db := logger.Named("db")
+	pool := db.Named("pool").With("size", 4)
+	http := logger.Named("http")
+
+	assert.Equal(t, "service", logger.Name())
+	assert.Equal(t, "service.db", db.Name())
+	assert.Equal(t, "service.db.pool", pool.Name())
+	assert.Equal(t, "service.http", http.Name())
+
+	var log map[string]interface{}
+
+	// Overridden level
+	assert.NoError(t, pool.Debug("message", "pool debug"))
+	assert.NoError(t, json.NewDecoder(buff).Decode(&log))
+	assert.Equal(t, "service.db.pool", log["logger"])
+	assert.Equal(t, "debug", log["level"])
+	assert.Equal(t, float64(4), log["size"])
+
+	// Default level
+	assert.NoError(t, http.Debug("message", "http debug"))
+	assert.Empty(t, buff.String())
+
+	// Change the level table at runtime
+	assert.NoError(t, logger.Levels().Parse("service.http=debug,service.db=error"))
+
+	assert.NoError(t, pool.Warn("message", "pool warn"))
+	assert.Empty(t, buff.String())
+
+	assert.NoError(t, http.Debug("message", "http debug"))
+	assert.NoError(t, json.NewDecoder(buff).Decode(&log))
+	assert.Equal(t, "service.http", log["logger"])
+	assert.Equal(t, "http debug", log["message"])
+
+	logger.Levels().Delete("service.http")
+	assert.NoError(t, http.Debug("message", "http debug"))
+	assert.Empty(t, buff.String())
+}
+
+func TestLoggerEmbeddedLogger(t *testing.T) {
+	buff := &bytes.Buffer{}
+	logger := NewLogger(Options{
+		Writer: buff,
+		Level:  "warn",
+		Levels: "db=debug",
+	})
+
+	var log map[string]interface{}
+
+	// Events below the level are discarded
+	assert.NoError(t, kitLevel.Info(logger.Logger).Log("message", "info"))
+	assert.Empty(t, buff.String())
+
+	assert.NoError(t, kitLevel.Warn(logger.Logger).Log("message", "warn"))
+	assert.NoError(t, json.NewDecoder(buff).Decode(&log))
+	assert.Equal(t, "warn", log["message"])
+
+	// Events without a level are not discarded
+	assert.NoError(t, logger.Logger.Log("message", "no level"))
+	assert.NoError(t, json.NewDecoder(buff).Decode(&log))
+	assert.Equal(t, "no level", log["message"])
+
+	// Child loggers filter by their own levels
+	db := logger.Named("db").With("retries", 4)
+	assert.NoError(t, kitLevel.Debug(db.Logger).Log("message", "debug"))
+	assert.NoError(t, json.NewDecoder(buff).Decode(&log))
+	assert.Equal(t, "debug", log["message"])
+
+	assert.NoError(t, kitLevel.Info(logger.With("retries", 4).Logger).Log("message", "info"))
+	assert.Empty(t, buff.String())
+}
+
+func TestLoggerLevelAllocs(t *testing.T) {
+	logger := NewLogger(Options{
+		Writer: &bytes.Buffer{},
+		Levels: "db=debug",
+	}).Named("http")
+
+	allocs := testing.AllocsPerRun(100, func() {
+		logger.Debug("message", "disabled", "retries", 4)
+	})
+
+	assert.Equal(t, float64(0), allocs)
+}
+
 func TestSingletonSetOptions(t *testing.T) {
 	tests := []struct {
 		opts          Options
@@ -0,0 +1,329 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	syslogVersion   = 1
+	syslogTimestamp = "2006-01-02T15:04:05.000000Z07:00"
+	syslogNil       = "-"
+
+	// syslogSDID is the structured data id for Options fields (32473 is the enterprise number reserved for documentation)
+	syslogSDID = "log@32473"
+)
+
+// Facility is the type for syslog facility
+type Facility int
+
+const (
+	// FacilityUser is used for user-level messages (default)
+	FacilityUser Facility = iota + 1
+	// FacilityMail is used for mail system
+	FacilityMail
+	// FacilityDaemon is used for system daemons
+	FacilityDaemon
+	// FacilityAuth is used for security/authorization messages
+	FacilityAuth
+)
+
+const (
+	// FacilityLocal0 is reserved for local use
+	FacilityLocal0 Facility = iota + 16
+	// FacilityLocal1 is reserved for local use
+	FacilityLocal1
+	// FacilityLocal2 is reserved for local use
+	FacilityLocal2
+	// FacilityLocal3 is reserved for local use
+	FacilityLocal3
+	// FacilityLocal4 is reserved for local use
+	FacilityLocal4
+	// FacilityLocal5 is reserved for local use
+	FacilityLocal5
+	// FacilityLocal6 is reserved for local use
+	FacilityLocal6
+	// FacilityLocal7 is reserved for local use
+	FacilityLocal7
+)
+
+// syslogFields are the keys from Options that are mapped into syslog structured data
+var syslogFields = []string{"logger", "environment", "region", "component"}
+
+// SyslogSeverity maps a logging level to a syslog severity
+func SyslogSeverity(lev Level) int {
+	switch lev {
+	case DebugLevel:
+		return 7 // debug
+	case InfoLevel:
+		return 6 // informational
+	case WarnLevel:
+		return 4 // warning
+	case ErrorLevel:
+		return 3 // error
+	default:
+		return 5 // notice
+	}
+}
+
+// SyslogOptions contains optional options for SyslogWriter
+type SyslogOptions struct {
+	// Network can be udp, tcp, unix, or unixgram
+	// Stream networks (tcp and unix) use octet-counting framing (RFC 6587)
+	Network  string
+	Address  string
+	Facility Facility
+	AppName  string
+	Hostname string
+	Timeout  time.Duration
+}
+
+// SyslogWriter is an io.Writer that writes every log event as an RFC 5424 syslog message
+type SyslogWriter struct {
+	sync.Mutex
+	network  string
+	address  string
+	facility Facility
+	appName  string
+	hostname string
+	procID   string
+	timeout  time.Duration
+	conn     net.Conn
+}
+
+// NewSyslogWriter creates a new syslog writer and connects to the syslog server
+func NewSyslogWriter(opts SyslogOptions) (*SyslogWriter, error) {
+	switch opts.Network {
+	case "udp", "udp4", "udp6", "tcp", "tcp4", "tcp6", "unix", "unixgram":
+	case "":
+		opts.Network = "udp"
+	default:
+		return nil, fmt.Errorf("unsupported syslog network: %s", opts.Network)
+	}
+
+	if opts.Address == "" {
+		return nil, errors.New("syslog address is required")
+	}
+
+	if opts.Facility == 0 {
+		opts.Facility = FacilityUser
+	}
+
+	if opts.AppName == "" {
+		opts.AppName = filepath.Base(os.Args[0])
+	}
+
+	if opts.Hostname == "" {
+		opts.Hostname, _ = os.Hostname()
+	}
+
+	if opts.Timeout == 0 {
+		opts.Timeout = 5 * time.Second
+	}
+
+	w := &SyslogWriter{
+		network:  opts.Network,
+		address:  opts.Address,
+		facility: opts.Facility,
+		appName:  syslogHeaderField(opts.AppName, 48),
+		hostname: syslogHeaderField(opts.Hostname, 255),
+		procID:   strconv.Itoa(os.Getpid()),
+		timeout:  opts.Timeout,
+	}
+
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+func (w *SyslogWriter) connect() error {
+	conn, err := net.DialTimeout(w.network, w.address, w.timeout)
+	if err != nil {
+		return err
+	}
+
+	w.conn = conn
+	return nil
+}
+
+func (w *SyslogWriter) isStream() bool {
+	switch w.network {
+	case "tcp", "tcp4", "tcp6", "unix":
+		return true
+	default:
+		return false
+	}
+}
+
+// frame creates an RFC 5424 syslog message
+func (w *SyslogWriter) frame(lev Level, kv []interface{}, msg []byte) []byte {
+	pri := int(w.facility)*8 + SyslogSeverity(lev)
+
+	buf := make([]byte, 0, len(msg)+256)
+	buf = append(buf, '<')
+	buf = strconv.AppendInt(buf, int64(pri), 10)
+	buf = append(buf, '>')
+	buf = strconv.AppendInt(buf, syslogVersion, 10)
+	buf = append(buf, ' ')
+	buf = time.Now().UTC().AppendFormat(buf, syslogTimestamp)
+	buf = append(buf, ' ')
+	buf = append(buf, w.hostname...)
+	buf = append(buf, ' ')
+	buf = append(buf, w.appName...)
+	buf = append(buf, ' ')
+	buf = append(buf, w.procID...)
+	buf = append(buf, ' ')
+	buf = append(buf, syslogNil...) // MSGID
+	buf = append(buf, ' ')
+	buf = appendSyslogSD(buf, kv)
+	buf = append(buf, ' ')
+	buf = append(buf, msg...)
+
+	if w.isStream() {
+		framed := make([]byte, 0, len(buf)+8)
+		framed = strconv.AppendInt(framed, int64(len(buf)), 10)
+		framed = append(framed, ' ')
+		buf = append(framed, buf...)
+	}
+
+	return buf
+}
+
+func (w *SyslogWriter) writeEvent(lev Level, kv []interface{}, line []byte) error {
+	w.Lock()
+	defer w.Unlock()
+
+	msg := w.frame(lev, kv, line)
+
+	if w.conn != nil {
+		if _, err := w.conn.Write(msg); err == nil {
+			return nil
+		}
+		w.conn.Close()
+		w.conn = nil
+	}
+
+	// Reconnect once and retry
+	if err := w.connect(); err != nil {
+		return err
+	}
+
+	_, err := w.conn.Write(msg)
+	return err
+}
+
+// Write writes p as an informational syslog message without structured data
+func (w *SyslogWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	if n > 0 && p[n-1] == '\n' {
+		p = p[:n-1]
+	}
+
+	if err := w.writeEvent(InfoLevel, nil, p); err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// Close closes the connection to the syslog server
+func (w *SyslogWriter) Close() error {
+	w.Lock()
+	defer w.Unlock()
+
+	if w.conn == nil {
+		return nil
+	}
+
+	err := w.conn.Close()
+	w.conn = nil
+
+	return err
+}
+
+// appendSyslogSD appends the structured data element for Options fields
+func appendSyslogSD(buf []byte, kv []interface{}) []byte {
+	start := len(buf)
+
+	for _, field := range syslogFields {
+		val, ok := lookupKey(kv, field)
+		if !ok {
+			continue
+		}
+
+		if len(buf) == start {
+			buf = append(buf, '[')
+			buf = append(buf, syslogSDID...)
+		}
+
+		buf = append(buf, ' ')
+		buf = append(buf, field...)
+		buf = append(buf, '=', '"')
+		buf = appendSyslogParamValue(buf, val)
+		buf = append(buf, '"')
+	}
+
+	if len(buf) == start {
+		return append(buf, syslogNil...)
+	}
+
+	return append(buf, ']')
+}
+
+// lookupKey returns the last value for a key in key-value pairs
+func lookupKey(kv []interface{}, key string) (interface{}, bool) {
+	for i := len(kv) - len(kv)%2 - 2; i >= 0; i -= 2 {
+		if k, ok := kv[i].(string); ok && k == key {
+			return kv[i+1], true
+		}
+	}
+
+	return nil, false
+}
+
+// appendSyslogParamValue escapes '"', '\' and ']' in a structured data parameter value
+func appendSyslogParamValue(buf []byte, val interface{}) []byte {
+	start := len(buf)
+	buf = appendText(buf, val)
+	text := string(buf[start:])
+
+	buf = buf[:start]
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; c {
+		case '"', '\\', ']':
+			buf = append(buf, '\\', c)
+		default:
+			buf = append(buf, c)
+		}
+	}
+
+	return buf
+}
+
+// syslogHeaderField ensures a header field only contains printable US-ASCII characters
+func syslogHeaderField(s string, max int) string {
+	if s == "" {
+		return syslogNil
+	}
+
+	b := []byte(s)
+	for i, c := range b {
+		if c < 33 || c > 126 {
+			b[i] = '_'
+		}
+	}
+
+	if len(b) > max {
+		b = b[:max]
+	}
+
+	return string(b)
+}
@@ -0,0 +1,201 @@
+package log
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var syslogRegex = regexp.MustCompile(`^<(\d+)>1 (\S+) (\S+) (\S+) (\d+) - (-|\[.*?[^\\]\]) (.*)$`)
+
+// readSyslogStream reads one octet-counting framed message from a stream connection
+func readSyslogStream(t *testing.T, rd *bufio.Reader) string {
+	length, err := rd.ReadString(' ')
+	assert.NoError(t, err)
+
+	n, err := strconv.Atoi(strings.TrimSpace(length))
+	assert.NoError(t, err)
+
+	msg := make([]byte, n)
+	_, err = io.ReadFull(rd, msg)
+	assert.NoError(t, err)
+
+	return string(msg)
+}
+
+func TestSyslogSeverity(t *testing.T) {
+	tests := []struct {
+		level            Level
+		expectedSeverity int
+	}{
+		{DebugLevel, 7},
+		{InfoLevel, 6},
+		{WarnLevel, 4},
+		{ErrorLevel, 3},
+		{NoneLevel, 5},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, tc.expectedSeverity, SyslogSeverity(tc.level))
+	}
+}
+
+func TestNewSyslogWriter(t *testing.T) {
+	tests := []struct {
+		name          string
+		opts          SyslogOptions
+		expectedError string
+	}{
+		{
+			"InvalidNetwork",
+			SyslogOptions{Network: "ip", Address: "localhost:514"},
+			"unsupported syslog network: ip",
+		},
+		{
+			"NoAddress",
+			SyslogOptions{Network: "udp"},
+			"syslog address is required",
+		},
+		{
+			"Success",
+			SyslogOptions{Address: "127.0.0.1:514"},
+			"",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			w, err := NewSyslogWriter(tc.opts)
+
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				assert.Nil(t, w)
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, w)
+				assert.Equal(t, "udp", w.network)
+				assert.Equal(t, FacilityUser, w.facility)
+				assert.NotEmpty(t, w.appName)
+				assert.NotEmpty(t, w.hostname)
+				assert.NoError(t, w.Close())
+			}
+		})
+	}
+}
+
+func TestSyslogWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "syslog")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	udpConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer udpConn.Close()
+
+	unixgramConn, err := net.ListenPacket("unixgram", filepath.Join(dir, "syslog.sock"))
+	assert.NoError(t, err)
+	defer unixgramConn.Close()
+
+	tcpListener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer tcpListener.Close()
+
+	unixListener, err := net.Listen("unix", filepath.Join(dir, "syslog-stream.sock"))
+	assert.NoError(t, err)
+	defer unixListener.Close()
+
+	readPacket := func(conn net.PacketConn) func(t *testing.T) string {
+		return func(t *testing.T) string {
+			buf := make([]byte, 4096)
+			n, _, err := conn.ReadFrom(buf)
+			assert.NoError(t, err)
+			return string(buf[:n])
+		}
+	}
+
+	readStream := func(ln net.Listener) func(t *testing.T) string {
+		var rd *bufio.Reader
+		return func(t *testing.T) string {
+			if rd == nil {
+				conn, err := ln.Accept()
+				assert.NoError(t, err)
+				rd = bufio.NewReader(conn)
+			}
+			return readSyslogStream(t, rd)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		network string
+		address string
+		read    func(t *testing.T) string
+	}{
+		{"UDP", "udp", udpConn.LocalAddr().String(), readPacket(udpConn)},
+		{"Unixgram", "unixgram", unixgramConn.LocalAddr().String(), readPacket(unixgramConn)},
+		{"TCP", "tcp", tcpListener.Addr().String(), readStream(tcpListener)},
+		{"Unix", "unix", unixListener.Addr().String(), readStream(unixListener)},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			w, err := NewSyslogWriter(SyslogOptions{
+				Network:  tc.network,
+				Address:  tc.address,
+				AppName:  "goto test",
+				Hostname: "localhost",
+			})
+			assert.NoError(t, err)
+			defer w.Close()
+
+			logger := NewLogger(Options{
+				Writer:      w,
+				Name:        "test",
+				Environment: "dev",
+				Region:      "local",
+				Component:   `app "x"`,
+			})
+
+			err = logger.Warn("message", "hello world")
+			assert.NoError(t, err)
+
+			msg := tc.read(t)
+			subs := syslogRegex.FindStringSubmatch(msg)
+			assert.Len(t, subs, 8, msg)
+
+			// facility user (1) * 8 + severity warning (4)
+			assert.Equal(t, "12", subs[1])
+			assert.Equal(t, "localhost", subs[3])
+			assert.Equal(t, "goto_test", subs[4])
+			assert.Equal(t, strconv.Itoa(os.Getpid()), subs[5])
+			assert.Equal(t, `[log@32473 logger="test" environment="dev" region="local" component="app \"x\""]`, subs[6])
+
+			var log map[string]interface{}
+			err = json.Unmarshal([]byte(subs[7]), &log)
+			assert.NoError(t, err)
+			assert.Equal(t, "warn", log["level"])
+			assert.Equal(t, "hello world", log["message"])
+
+			// Write without structured data
+			_, err = w.Write([]byte("plain message\n"))
+			assert.NoError(t, err)
+
+			msg = tc.read(t)
+			subs = syslogRegex.FindStringSubmatch(msg)
+			assert.Len(t, subs, 8, msg)
+			assert.Equal(t, "14", subs[1])
+			assert.Equal(t, "-", subs[6])
+			assert.Equal(t, "plain message", subs[7])
+		})
+	}
+}
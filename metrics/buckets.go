@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// LinearBuckets creates count buckets, each width wide, where the lowest bucket has an upper bound of start
+func LinearBuckets(start, width float64, count int) []float64 {
+	return prometheus.LinearBuckets(start, width, count)
+}
+
+// ExponentialBuckets creates count buckets, where the lowest bucket has an upper bound of start
+// and each following bucket's upper bound is factor times the previous bucket's upper bound
+func ExponentialBuckets(start, factor float64, count int) []float64 {
+	return prometheus.ExponentialBuckets(start, factor, count)
+}
+
+// SLOBuckets aligns a bucket layout with SLO thresholds
+// Every threshold becomes a bucket upper bound, so the ratio of observations meeting an SLO can be calculated exactly.
+//   buckets is the base bucket layout (i.e. created by LinearBuckets or ExponentialBuckets)
+//   thresholds are the SLO latency thresholds (i.e. 0.3 for 300ms)
+func SLOBuckets(buckets []float64, thresholds ...float64) []float64 {
+	seen := map[float64]bool{}
+	result := make([]float64, 0, len(buckets)+len(thresholds))
+
+	for _, list := range [][]float64{buckets, thresholds} {
+		for _, b := range list {
+			if !seen[b] {
+				seen[b] = true
+				result = append(result, b)
+			}
+		}
+	}
+
+	sort.Float64s(result)
+
+	return result
+}
@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLinearBuckets(t *testing.T) {
+	tests := []struct {
+		start           float64
+		width           float64
+		count           int
+		expectedBuckets []float64
+	}{
+		{0.1, 0.1, 3, []float64{0.1, 0.2, 0.30000000000000004}},
+		{1, 2, 4, []float64{1, 3, 5, 7}},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, tc.expectedBuckets, LinearBuckets(tc.start, tc.width, tc.count))
+	}
+}
+
+func TestExponentialBuckets(t *testing.T) {
+	tests := []struct {
+		start           float64
+		factor          float64
+		count           int
+		expectedBuckets []float64
+	}{
+		{0.01, 10, 3, []float64{0.01, 0.1, 1}},
+		{1, 2, 4, []float64{1, 2, 4, 8}},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, tc.expectedBuckets, ExponentialBuckets(tc.start, tc.factor, tc.count))
+	}
+}
+
+func TestSLOBuckets(t *testing.T) {
+	tests := []struct {
+		name            string
+		buckets         []float64
+		thresholds      []float64
+		expectedBuckets []float64
+	}{
+		{
+			name:            "NoThreshold",
+			buckets:         []float64{0.1, 0.5, 1},
+			thresholds:      nil,
+			expectedBuckets: []float64{0.1, 0.5, 1},
+		},
+		{
+			name:            "NoBucket",
+			buckets:         nil,
+			thresholds:      []float64{1, 0.3},
+			expectedBuckets: []float64{0.3, 1},
+		},
+		{
+			name:            "Merged",
+			buckets:         []float64{0.1, 0.5, 1, 5},
+			thresholds:      []float64{0.3, 1, 2.5},
+			expectedBuckets: []float64{0.1, 0.3, 0.5, 1, 2.5, 5},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedBuckets, SLOBuckets(tc.buckets, tc.thresholds...))
+		})
+	}
+}
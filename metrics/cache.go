@@ -0,0 +1,135 @@
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	counterKind   = "counter"
+	gaugeKind     = "gauge"
+	histogramKind = "histogram"
+	summaryKind   = "summary"
+)
+
+// ConflictError is returned when a metric is already registered with a different definition
+type ConflictError struct {
+	Name   string
+	Reason string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("metric %s is already registered with %s", e.Name, e.Reason)
+}
+
+// definition is the definition of a registered metric
+type definition struct {
+	kind      string
+	help      string
+	labels    []string
+	collector prometheus.Collector
+}
+
+func (d *definition) check(name, kind, help string, labels []string) error {
+	if kind != d.kind {
+		return &ConflictError{
+			Name:   name,
+			Reason: fmt.Sprintf("a different type (%s)", d.kind),
+		}
+	}
+
+	if help != d.help {
+		return &ConflictError{
+			Name:   name,
+			Reason: fmt.Sprintf("a different help text (%q)", d.help),
+		}
+	}
+
+	if strings.Join(labels, ",") != strings.Join(d.labels, ",") {
+		return &ConflictError{
+			Name:   name,
+			Reason: fmt.Sprintf("different labels (%s)", strings.Join(d.labels, ",")),
+		}
+	}
+
+	return nil
+}
+
+// cache keeps track of metrics registered by a factory and its children keyed by name and constant labels
+type cache struct {
+	sync.Mutex
+	definitions map[string]*definition
+}
+
+func newCache() *cache {
+	return &cache{
+		definitions: map[string]*definition{},
+	}
+}
+
+// cacheKey creates a key for a metric from its name and constant labels
+func (f *Factory) cacheKey(name string) string {
+	pairs := make([]string, 0, len(f.constLabels))
+	for k, v := range f.constLabels {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", k, v))
+	}
+	sort.Strings(pairs)
+
+	return fmt.Sprintf("%s{%s}", name, strings.Join(pairs, ","))
+}
+
+// register registers a new metric or returns the already registered one with the same definition
+func (f *Factory) register(kind, name, help string, labels []string, newCollector func() prometheus.Collector) (prometheus.Collector, error) {
+	f.cache.Lock()
+	defer f.cache.Unlock()
+
+	key := f.cacheKey(name)
+	if d, ok := f.cache.definitions[key]; ok {
+		if err := d.check(name, kind, help, labels); err != nil {
+			return nil, err
+		}
+		return d.collector, nil
+	}
+
+	collector := newCollector()
+
+	// Make sure the metric definition is valid on its own before registering it
+	if err := prometheus.NewRegistry().Register(collector); err != nil {
+		return nil, err
+	}
+
+	if err := f.registerer.Register(collector); err != nil {
+		// The metric may have been registered by another factory or directly with the registerer
+		are, ok := err.(prometheus.AlreadyRegisteredError)
+		if !ok {
+			return nil, &ConflictError{
+				Name:   name,
+				Reason: fmt.Sprintf("a different definition: %s", err),
+			}
+		}
+
+		collector = are.ExistingCollector
+	}
+
+	f.cache.definitions[key] = &definition{
+		kind:      kind,
+		help:      help,
+		labels:    append([]string{}, labels...),
+		collector: collector,
+	}
+
+	return collector, nil
+}
+
+// mustRegisterOnce registers a collector and ignores the error if it is already registered
+func mustRegisterOnce(registerer prometheus.Registerer, collector prometheus.Collector) {
+	if err := registerer.Register(collector); err != nil {
+		if _, ok := err.(prometheus.AlreadyRegisteredError); !ok {
+			panic(err)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConflictError(t *testing.T) {
+	err := &ConflictError{Name: "requests_total", Reason: "a different type (gauge)"}
+	assert.EqualError(t, err, "metric requests_total is already registered with a different type (gauge)")
+}
+
+func TestFactoryIdempotent(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	mf := NewFactory(FactoryOptions{Registerer: registry})
+	labels := []string{"method", "url"}
+
+	c1, err := mf.RegisterCounter("requests_total", "total requests", labels)
+	assert.NoError(t, err)
+	c2, err := mf.RegisterCounter("requests_total", "total requests", labels)
+	assert.NoError(t, err)
+	assert.True(t, c1 == c2)
+
+	g1 := mf.Gauge("requests", "active requests", labels)
+	g2 := mf.Gauge("requests", "active requests", labels)
+	assert.True(t, g1 == g2)
+
+	h1 := mf.Histogram("request_duration_seconds", "request durations", labels)
+	h2 := mf.Histogram("request_duration_seconds", "request durations", labels)
+	assert.True(t, h1 == h2)
+
+	s1 := mf.Summary("request_duration_quantiles_seconds", "request durations", labels)
+	s2 := mf.Summary("request_duration_quantiles_seconds", "request durations", labels)
+	assert.True(t, s1 == s2)
+
+	// Another factory sharing the same registerer
+	other := NewFactory(FactoryOptions{Registerer: registry})
+	c3, err := other.RegisterCounter("requests_total", "total requests", labels)
+	assert.NoError(t, err)
+	assert.True(t, c1 == c3)
+}
+
+func TestFactoryConflict(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	mf := NewFactory(FactoryOptions{Registerer: registry})
+	mf.Counter("requests_total", "total requests", []string{"method", "url"})
+
+	tests := []struct {
+		name          string
+		register      func(f *Factory) error
+		expectedError string
+	}{
+		{
+			name: "DifferentHelp",
+			register: func(f *Factory) error {
+				_, err := f.RegisterCounter("requests_total", "number of requests", []string{"method", "url"})
+				return err
+			},
+			expectedError: `metric requests_total is already registered with a different help text ("total requests")`,
+		},
+		{
+			name: "DifferentLabels",
+			register: func(f *Factory) error {
+				_, err := f.RegisterCounter("requests_total", "total requests", []string{"url", "method"})
+				return err
+			},
+			expectedError: "metric requests_total is already registered with different labels (method,url)",
+		},
+		{
+			name: "DifferentType",
+			register: func(f *Factory) error {
+				_, err := f.RegisterGauge("requests_total", "total requests", []string{"method", "url"})
+				return err
+			},
+			expectedError: "metric requests_total is already registered with a different type (counter)",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.register(mf)
+			assert.EqualError(t, err, tc.expectedError)
+		})
+	}
+
+	t.Run("AnotherFactory", func(t *testing.T) {
+		other := NewFactory(FactoryOptions{Registerer: registry})
+		_, err := other.RegisterCounter("requests_total", "number of requests", []string{"method", "url"})
+		assert.Error(t, err)
+		assert.IsType(t, &ConflictError{}, err)
+
+		_, err = other.RegisterGauge("requests_total", "total requests", []string{"method", "url"})
+		assert.EqualError(t, err, "metric requests_total is already registered with a different type")
+	})
+
+	t.Run("Panic", func(t *testing.T) {
+		assert.Panics(t, func() {
+			mf.Counter("requests_total", "total requests", []string{"method"})
+		})
+	})
+}
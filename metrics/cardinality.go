@@ -0,0 +1,329 @@
+package metrics
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// OverflowLabelValue is the label value for series exceeding the series limit of a metric
+const OverflowLabelValue = "__overflow__"
+
+type (
+	// seriesLimiter limits the number of series of a metric vector
+	// The first label value combinations up to the limit are used as they are.
+	// All other combinations are replaced with one combination of __overflow__ label values, so they share one series.
+	// A nil limiter does not limit anything.
+	seriesLimiter struct {
+		sync.RWMutex
+		name      string
+		labels    []string
+		maxSeries int
+		series    map[string]bool
+		overflow  []string
+		overflows *CounterVec
+	}
+
+	// CounterVec is a prometheus.CounterVec limiting the number of its series to the series limit of factory
+	// Curried vectors are not limited.
+	CounterVec struct {
+		*prometheus.CounterVec
+		limiter *seriesLimiter
+	}
+
+	// GaugeVec is a prometheus.GaugeVec limiting the number of its series to the series limit of factory
+	// Curried vectors are not limited.
+	GaugeVec struct {
+		*prometheus.GaugeVec
+		limiter *seriesLimiter
+	}
+
+	// HistogramVec is a prometheus.HistogramVec limiting the number of its series to the series limit of factory
+	// Curried vectors are not limited.
+	HistogramVec struct {
+		*prometheus.HistogramVec
+		limiter *seriesLimiter
+	}
+
+	// SummaryVec is a prometheus.SummaryVec limiting the number of its series to the series limit of factory
+	// Curried vectors are not limited.
+	SummaryVec struct {
+		*prometheus.SummaryVec
+		limiter *seriesLimiter
+	}
+)
+
+// newLimiter creates a series limiter for a metric or nil if the metric is not limited
+func (f *Factory) newLimiter(name string, labels []string) *seriesLimiter {
+	if f.maxSeries <= 0 || len(labels) == 0 {
+		return nil
+	}
+
+	overflow := make([]string, len(labels))
+	for i := range overflow {
+		overflow[i] = OverflowLabelValue
+	}
+
+	return &seriesLimiter{
+		name:      name,
+		labels:    append([]string{}, labels...),
+		maxSeries: f.maxSeries,
+		series:    map[string]bool{},
+		overflow:  overflow,
+		overflows: f.overflows,
+	}
+}
+
+// labelValues returns the label values to use for a series
+// Label values of new series exceeding the limit are replaced with __overflow__ label values.
+// Invalid label values are returned as they are, so the metric vector can reject them.
+func (l *seriesLimiter) labelValues(lvs []string) []string {
+	if l == nil || len(lvs) != len(l.labels) {
+		return lvs
+	}
+
+	key := strings.Join(lvs, "\xff")
+
+	l.RLock()
+	ok := l.series[key]
+	l.RUnlock()
+
+	if ok {
+		return lvs
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	if l.series[key] {
+		return lvs
+	}
+
+	if len(l.series) < l.maxSeries {
+		l.series[key] = true
+		return lvs
+	}
+
+	l.overflows.WithLabelValues(l.name).Inc()
+
+	return l.overflow
+}
+
+// labelMap is the same as labelValues for labels given as a map
+func (l *seriesLimiter) labelMap(labels prometheus.Labels) prometheus.Labels {
+	lvs, ok := l.ordered(labels)
+	if !ok {
+		return labels
+	}
+
+	lvs = l.labelValues(lvs)
+
+	limited := make(prometheus.Labels, len(lvs))
+	for i, name := range l.labels {
+		limited[name] = lvs[i]
+	}
+
+	return limited
+}
+
+// ordered returns the values of labels in the order of label names
+func (l *seriesLimiter) ordered(labels prometheus.Labels) ([]string, bool) {
+	if l == nil || len(labels) != len(l.labels) {
+		return nil, false
+	}
+
+	lvs := make([]string, len(l.labels))
+	for i, name := range l.labels {
+		v, ok := labels[name]
+		if !ok {
+			return nil, false
+		}
+		lvs[i] = v
+	}
+
+	return lvs, true
+}
+
+// forget frees the slot of a deleted series
+func (l *seriesLimiter) forget(lvs []string) {
+	if l == nil {
+		return
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	delete(l.series, strings.Join(lvs, "\xff"))
+}
+
+// forgetMap is the same as forget for labels given as a map
+func (l *seriesLimiter) forgetMap(labels prometheus.Labels) {
+	if lvs, ok := l.ordered(labels); ok {
+		l.forget(lvs)
+	}
+}
+
+// reset frees the slots of all series
+func (l *seriesLimiter) reset() {
+	if l == nil {
+		return
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	l.series = map[string]bool{}
+}
+
+// WithLabelValues returns the counter for the label values or the __overflow__ counter if the series limit is reached
+func (v *CounterVec) WithLabelValues(lvs ...string) prometheus.Counter {
+	return v.CounterVec.WithLabelValues(v.limiter.labelValues(lvs)...)
+}
+
+// With returns the counter for the labels or the __overflow__ counter if the series limit is reached
+func (v *CounterVec) With(labels prometheus.Labels) prometheus.Counter {
+	return v.CounterVec.With(v.limiter.labelMap(labels))
+}
+
+// GetMetricWithLabelValues returns the counter for the label values or the __overflow__ counter if the series limit is reached
+func (v *CounterVec) GetMetricWithLabelValues(lvs ...string) (prometheus.Counter, error) {
+	return v.CounterVec.GetMetricWithLabelValues(v.limiter.labelValues(lvs)...)
+}
+
+// GetMetricWith returns the counter for the labels or the __overflow__ counter if the series limit is reached
+func (v *CounterVec) GetMetricWith(labels prometheus.Labels) (prometheus.Counter, error) {
+	return v.CounterVec.GetMetricWith(v.limiter.labelMap(labels))
+}
+
+// DeleteLabelValues deletes the counter for the label values
+func (v *CounterVec) DeleteLabelValues(lvs ...string) bool {
+	v.limiter.forget(lvs)
+	return v.CounterVec.DeleteLabelValues(lvs...)
+}
+
+// Delete deletes the counter for the labels
+func (v *CounterVec) Delete(labels prometheus.Labels) bool {
+	v.limiter.forgetMap(labels)
+	return v.CounterVec.Delete(labels)
+}
+
+// Reset deletes all counters
+func (v *CounterVec) Reset() {
+	v.limiter.reset()
+	v.CounterVec.Reset()
+}
+
+// WithLabelValues returns the gauge for the label values or the __overflow__ gauge if the series limit is reached
+func (v *GaugeVec) WithLabelValues(lvs ...string) prometheus.Gauge {
+	return v.GaugeVec.WithLabelValues(v.limiter.labelValues(lvs)...)
+}
+
+// With returns the gauge for the labels or the __overflow__ gauge if the series limit is reached
+func (v *GaugeVec) With(labels prometheus.Labels) prometheus.Gauge {
+	return v.GaugeVec.With(v.limiter.labelMap(labels))
+}
+
+// GetMetricWithLabelValues returns the gauge for the label values or the __overflow__ gauge if the series limit is reached
+func (v *GaugeVec) GetMetricWithLabelValues(lvs ...string) (prometheus.Gauge, error) {
+	return v.GaugeVec.GetMetricWithLabelValues(v.limiter.labelValues(lvs)...)
+}
+
+// GetMetricWith returns the gauge for the labels or the __overflow__ gauge if the series limit is reached
+func (v *GaugeVec) GetMetricWith(labels prometheus.Labels) (prometheus.Gauge, error) {
+	return v.GaugeVec.GetMetricWith(v.limiter.labelMap(labels))
+}
+
+// DeleteLabelValues deletes the gauge for the label values
+func (v *GaugeVec) DeleteLabelValues(lvs ...string) bool {
+	v.limiter.forget(lvs)
+	return v.GaugeVec.DeleteLabelValues(lvs...)
+}
+
+// Delete deletes the gauge for the labels
+func (v *GaugeVec) Delete(labels prometheus.Labels) bool {
+	v.limiter.forgetMap(labels)
+	return v.GaugeVec.Delete(labels)
+}
+
+// Reset deletes all gauges
+func (v *GaugeVec) Reset() {
+	v.limiter.reset()
+	v.GaugeVec.Reset()
+}
+
+// WithLabelValues returns the histogram for the label values or the __overflow__ histogram if the series limit is reached
+func (v *HistogramVec) WithLabelValues(lvs ...string) prometheus.Observer {
+	return v.HistogramVec.WithLabelValues(v.limiter.labelValues(lvs)...)
+}
+
+// With returns the histogram for the labels or the __overflow__ histogram if the series limit is reached
+func (v *HistogramVec) With(labels prometheus.Labels) prometheus.Observer {
+	return v.HistogramVec.With(v.limiter.labelMap(labels))
+}
+
+// GetMetricWithLabelValues returns the histogram for the label values or the __overflow__ histogram if the series limit is reached
+func (v *HistogramVec) GetMetricWithLabelValues(lvs ...string) (prometheus.Observer, error) {
+	return v.HistogramVec.GetMetricWithLabelValues(v.limiter.labelValues(lvs)...)
+}
+
+// GetMetricWith returns the histogram for the labels or the __overflow__ histogram if the series limit is reached
+func (v *HistogramVec) GetMetricWith(labels prometheus.Labels) (prometheus.Observer, error) {
+	return v.HistogramVec.GetMetricWith(v.limiter.labelMap(labels))
+}
+
+// DeleteLabelValues deletes the histogram for the label values
+func (v *HistogramVec) DeleteLabelValues(lvs ...string) bool {
+	v.limiter.forget(lvs)
+	return v.HistogramVec.DeleteLabelValues(lvs...)
+}
+
+// Delete deletes the histogram for the labels
+func (v *HistogramVec) Delete(labels prometheus.Labels) bool {
+	v.limiter.forgetMap(labels)
+	return v.HistogramVec.Delete(labels)
+}
+
+// Reset deletes all histograms
+func (v *HistogramVec) Reset() {
+	v.limiter.reset()
+	v.HistogramVec.Reset()
+}
+
+// WithLabelValues returns the summary for the label values or the __overflow__ summary if the series limit is reached
+func (v *SummaryVec) WithLabelValues(lvs ...string) prometheus.Observer {
+	return v.SummaryVec.WithLabelValues(v.limiter.labelValues(lvs)...)
+}
+
+// With returns the summary for the labels or the __overflow__ summary if the series limit is reached
+func (v *SummaryVec) With(labels prometheus.Labels) prometheus.Observer {
+	return v.SummaryVec.With(v.limiter.labelMap(labels))
+}
+
+// GetMetricWithLabelValues returns the summary for the label values or the __overflow__ summary if the series limit is reached
+func (v *SummaryVec) GetMetricWithLabelValues(lvs ...string) (prometheus.Observer, error) {
+	return v.SummaryVec.GetMetricWithLabelValues(v.limiter.labelValues(lvs)...)
+}
+
+// GetMetricWith returns the summary for the labels or the __overflow__ summary if the series limit is reached
+func (v *SummaryVec) GetMetricWith(labels prometheus.Labels) (prometheus.Observer, error) {
+	return v.SummaryVec.GetMetricWith(v.limiter.labelMap(labels))
+}
+
+// DeleteLabelValues deletes the summary for the label values
+func (v *SummaryVec) DeleteLabelValues(lvs ...string) bool {
+	v.limiter.forget(lvs)
+	return v.SummaryVec.DeleteLabelValues(lvs...)
+}
+
+// Delete deletes the summary for the labels
+func (v *SummaryVec) Delete(labels prometheus.Labels) bool {
+	v.limiter.forgetMap(labels)
+	return v.SummaryVec.Delete(labels)
+}
+
+// Reset deletes all summaries
+func (v *SummaryVec) Reset() {
+	v.limiter.reset()
+	v.SummaryVec.Reset()
+}
@@ -0,0 +1,160 @@
+package metrics
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+// gatherSeries gathers all series of a metric keyed by the value of a label
+func gatherSeries(t *testing.T, registry *prometheus.Registry, name, label string) map[string]*dto.Metric {
+	metricFamilies, err := registry.Gather()
+	assert.NoError(t, err)
+
+	series := map[string]*dto.Metric{}
+	for _, metricFamily := range metricFamilies {
+		if metricFamily.GetName() != name {
+			continue
+		}
+
+		for _, metric := range metricFamily.Metric {
+			for _, l := range metric.Label {
+				if l.GetName() == label {
+					series[l.GetValue()] = metric
+				}
+			}
+		}
+	}
+
+	return series
+}
+
+func TestFactoryMaxSeries(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	mf := NewFactory(FactoryOptions{
+		Registerer: registry,
+		MaxSeries:  2,
+	})
+
+	counter := mf.LimitedCounter("requests_total", "total requests", []string{"url"})
+	gauge := mf.LimitedGauge("requests", "active requests", []string{"url"})
+	histogram := mf.LimitedHistogram("request_duration_seconds", "request durations", []string{"url"}, HistogramOptions{Buckets: []float64{0.1, 1}})
+	summary := mf.LimitedSummary("request_duration_quantiles_seconds", "request durations", []string{"url"})
+
+	for i, url := range []string{"/users/1", "/users/2", "/users/3", "/users/4"} {
+		counter.WithLabelValues(url).Add(float64(i + 1))
+		gauge.WithLabelValues(url).Set(float64(i + 1))
+		histogram.WithLabelValues(url).Observe(float64(i) / 2)
+		summary.WithLabelValues(url).Observe(float64(i + 1))
+	}
+
+	t.Run("Counter", func(t *testing.T) {
+		series := gatherSeries(t, registry, "requests_total", "url")
+		assert.Len(t, series, 3)
+		assert.Equal(t, 1.0, series["/users/1"].GetCounter().GetValue())
+		assert.Equal(t, 2.0, series["/users/2"].GetCounter().GetValue())
+		assert.Equal(t, 7.0, series[OverflowLabelValue].GetCounter().GetValue())
+	})
+
+	t.Run("Gauge", func(t *testing.T) {
+		series := gatherSeries(t, registry, "requests", "url")
+		assert.Len(t, series, 3)
+		// Both series beyond the limit set the same __overflow__ gauge
+		assert.Equal(t, 4.0, series[OverflowLabelValue].GetGauge().GetValue())
+	})
+
+	t.Run("Histogram", func(t *testing.T) {
+		series := gatherSeries(t, registry, "request_duration_seconds", "url")
+		assert.Len(t, series, 3)
+
+		h := series[OverflowLabelValue].GetHistogram()
+		assert.Equal(t, uint64(2), h.GetSampleCount())
+		assert.Equal(t, 2.5, h.GetSampleSum())
+		assert.Equal(t, uint64(0), h.Bucket[0].GetCumulativeCount())
+		assert.Equal(t, uint64(1), h.Bucket[1].GetCumulativeCount())
+	})
+
+	t.Run("Summary", func(t *testing.T) {
+		series := gatherSeries(t, registry, "request_duration_quantiles_seconds", "url")
+		assert.Len(t, series, 3)
+
+		s := series[OverflowLabelValue].GetSummary()
+		assert.Equal(t, uint64(2), s.GetSampleCount())
+		assert.Equal(t, 7.0, s.GetSampleSum())
+		assert.NotEmpty(t, s.Quantile)
+	})
+
+	t.Run("Overflows", func(t *testing.T) {
+		series := gatherSeries(t, registry, "metric_overflow_total", "metric")
+		assert.Equal(t, 2.0, series["requests_total"].GetCounter().GetValue())
+		assert.Equal(t, 2.0, series["requests"].GetCounter().GetValue())
+		assert.Equal(t, 2.0, series["request_duration_seconds"].GetCounter().GetValue())
+		assert.Equal(t, 2.0, series["request_duration_quantiles_seconds"].GetCounter().GetValue())
+	})
+
+	t.Run("Stable", func(t *testing.T) {
+		counter.WithLabelValues("/users/4").Inc()
+		counter.WithLabelValues("/users/5").Inc()
+
+		series := gatherSeries(t, registry, "requests_total", "url")
+		assert.Len(t, series, 3)
+		assert.Equal(t, 9.0, series[OverflowLabelValue].GetCounter().GetValue())
+
+		overflows := gatherSeries(t, registry, "metric_overflow_total", "metric")
+		assert.Equal(t, 4.0, overflows["requests_total"].GetCounter().GetValue())
+	})
+
+	t.Run("AnotherFactory", func(t *testing.T) {
+		other := NewFactory(FactoryOptions{Registerer: registry})
+		c := other.LimitedCounter("requests_total", "total requests", []string{"url"})
+		assert.True(t, c == counter)
+
+		// The vectors returned by Counter are the same vectors without the series limit
+		assert.True(t, mf.Counter("requests_total", "total requests", []string{"url"}) == counter.CounterVec)
+	})
+}
+
+func TestFactoryMaxSeriesMemory(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	mf := NewFactory(FactoryOptions{
+		Registerer: registry,
+		MaxSeries:  2,
+	})
+
+	counter := mf.LimitedCounter("requests_total", "total requests", []string{"url"})
+	gauge := mf.LimitedGauge("requests", "active requests", []string{"url"})
+
+	for i := 0; i < 1000; i++ {
+		url := fmt.Sprintf("/users/%d", i)
+		counter.WithLabelValues(url).Inc()
+		gauge.With(prometheus.Labels{"url": url}).Inc()
+	}
+
+	// Only the series up to the limit and the __overflow__ series are kept in memory
+	assert.Equal(t, 3, testutil.CollectAndCount(counter.CounterVec))
+	assert.Equal(t, 3, testutil.CollectAndCount(gauge.GaugeVec))
+	assert.Len(t, counter.limiter.series, 2)
+	assert.Len(t, gauge.limiter.series, 2)
+
+	series := gatherSeries(t, registry, "requests_total", "url")
+	assert.Equal(t, 998.0, series[OverflowLabelValue].GetCounter().GetValue())
+
+	// Deleting a series frees its slot
+	assert.True(t, counter.DeleteLabelValues("/users/0"))
+	counter.WithLabelValues("/users/1000").Inc()
+	series = gatherSeries(t, registry, "requests_total", "url")
+	assert.Equal(t, 1.0, series["/users/1000"].GetCounter().GetValue())
+
+	// Resetting frees all slots
+	gauge.Reset()
+	gauge.WithLabelValues("/users/1001").Inc()
+	assert.Equal(t, 1, testutil.CollectAndCount(gauge.GaugeVec))
+
+	// Invalid label values are rejected by the vector
+	_, err := counter.GetMetricWithLabelValues("a", "b")
+	assert.Error(t, err)
+}
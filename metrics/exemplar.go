@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"strings"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/prometheus/client_golang/prometheus"
+	jaeger "github.com/uber/jaeger-client-go"
+)
+
+// TraceIDLabel is the name of exemplar label for trace ids
+const TraceIDLabel = "trace_id"
+
+// jaegerSpanContext is implemented by span contexts exposing Jaeger trace ids (i.e. jaeger.SpanContext)
+type jaegerSpanContext interface {
+	TraceID() jaeger.TraceID
+}
+
+// TraceID returns the trace id of a span
+// Since OpenTracing does not expose trace ids, the trace id is read from span contexts exposing Jaeger trace ids.
+// For other tracers, the span context is injected using the tracer of span
+// and the trace id is read from the known propagation formats (Jaeger, W3C Trace Context, and B3 single and multiple headers).
+func TraceID(span opentracing.Span) (string, bool) {
+	if span == nil {
+		return "", false
+	}
+
+	if ctx, ok := span.Context().(jaegerSpanContext); ok {
+		if traceID := ctx.TraceID(); traceID.IsValid() {
+			return traceID.String(), true
+		}
+		return "", false
+	}
+
+	carrier := opentracing.TextMapCarrier{}
+	if err := span.Tracer().Inject(span.Context(), opentracing.TextMap, carrier); err != nil {
+		return "", false
+	}
+
+	headers := make(map[string]string, len(carrier))
+	for key, val := range carrier {
+		headers[strings.ToLower(key)] = val
+	}
+
+	// Formats are checked in a fixed order, so the same trace id is returned if a span context is injected in multiple formats
+	if val, ok := headers["uber-trace-id"]; ok {
+		// {trace-id}:{span-id}:{parent-span-id}:{flags}
+		if traceID := strings.Split(val, ":")[0]; traceID != "" {
+			return traceID, true
+		}
+	}
+
+	if val, ok := headers["traceparent"]; ok {
+		// {version}-{trace-id}-{parent-id}-{trace-flags}
+		if parts := strings.Split(val, "-"); len(parts) == 4 {
+			return parts[1], true
+		}
+	}
+
+	if val, ok := headers["b3"]; ok {
+		// {trace-id}-{span-id}-{sampled}-{parent-span-id}
+		if parts := strings.Split(val, "-"); len(parts) >= 2 {
+			return parts[0], true
+		}
+	}
+
+	if val, ok := headers["x-b3-traceid"]; ok && val != "" {
+		return val, true
+	}
+
+	return "", false
+}
+
+// ObserveWithTraceID observes a value and attaches the trace id of span as an exemplar
+// If span is nil or the observer does not support exemplars, the value is observed without an exemplar.
+func ObserveWithTraceID(observer prometheus.Observer, value float64, span opentracing.Span) {
+	if eo, ok := observer.(prometheus.ExemplarObserver); ok {
+		if traceID, ok := TraceID(span); ok {
+			eo.ObserveWithExemplar(value, prometheus.Labels{TraceIDLabel: traceID})
+			return
+		}
+	}
+
+	observer.Observe(value)
+}
@@ -0,0 +1,176 @@
+package metrics
+
+import (
+	"io"
+	"testing"
+
+	"github.com/moorara/goto/trace"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	jaeger "github.com/uber/jaeger-client-go"
+)
+
+// headersInjector injects fixed headers for a span context of mock tracer
+type headersInjector map[string]string
+
+func (i headersInjector) Inject(ctx mocktracer.MockSpanContext, carrier interface{}) error {
+	w, ok := carrier.(opentracing.TextMapWriter)
+	if !ok {
+		return opentracing.ErrInvalidCarrier
+	}
+
+	for k, v := range i {
+		w.Set(k, v)
+	}
+
+	return nil
+}
+
+func newMockSpan(headers map[string]string) opentracing.Span {
+	tracer := mocktracer.New()
+	if headers != nil {
+		tracer.RegisterInjector(opentracing.TextMap, headersInjector(headers))
+	}
+
+	return tracer.StartSpan("test")
+}
+
+func newJaegerSpan() (opentracing.Span, io.Closer) {
+	tracer, closer := jaeger.NewTracer("test", jaeger.NewConstSampler(true), jaeger.NewNullReporter())
+	return tracer.StartSpan("test"), closer
+}
+
+func TestTraceID(t *testing.T) {
+	jaegerSpan, closer := newJaegerSpan()
+	defer closer.Close()
+
+	compositeTracer, compositeCloser, _ := trace.NewTracer(trace.Options{
+		Name:         "test",
+		SpanReporter: jaeger.NewNullReporter(),
+		Propagation:  []trace.Propagation{trace.PropagationW3C, trace.PropagationB3Multi, trace.PropagationJaeger},
+	})
+	defer compositeCloser.Close()
+	compositeSpan := compositeTracer.StartSpan("test")
+
+	tests := []struct {
+		name            string
+		span            opentracing.Span
+		expectedOK      bool
+		expectedTraceID string
+	}{
+		{
+			name:       "NoSpan",
+			span:       nil,
+			expectedOK: false,
+		},
+		{
+			name:       "NoopTracer",
+			span:       opentracing.NoopTracer{}.StartSpan("test"),
+			expectedOK: false,
+		},
+		{
+			name:       "UnknownFormat",
+			span:       newMockSpan(nil),
+			expectedOK: false,
+		},
+		{
+			name:            "JaegerTracer",
+			span:            jaegerSpan,
+			expectedOK:      true,
+			expectedTraceID: jaegerSpan.Context().(jaeger.SpanContext).TraceID().String(),
+		},
+		{
+			name:            "CompositeTracer",
+			span:            compositeSpan,
+			expectedOK:      true,
+			expectedTraceID: compositeSpan.Context().(jaeger.SpanContext).TraceID().String(),
+		},
+		{
+			name:            "JaegerFormat",
+			span:            newMockSpan(map[string]string{"Uber-Trace-Id": "6bdbc3a5c4f0e8ad:6bdbc3a5c4f0e8ad:0:1"}),
+			expectedOK:      true,
+			expectedTraceID: "6bdbc3a5c4f0e8ad",
+		},
+		{
+			name:            "W3CFormat",
+			span:            newMockSpan(map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}),
+			expectedOK:      true,
+			expectedTraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+		},
+		{
+			name:            "B3SingleFormat",
+			span:            newMockSpan(map[string]string{"b3": "80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-1"}),
+			expectedOK:      true,
+			expectedTraceID: "80f198ee56343ba864fe8b2a57d3eff7",
+		},
+		{
+			name:            "B3MultiFormat",
+			span:            newMockSpan(map[string]string{"X-B3-TraceId": "64fe8b2a57d3eff7", "X-B3-SpanId": "e457b5a2e4d86bd1"}),
+			expectedOK:      true,
+			expectedTraceID: "64fe8b2a57d3eff7",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			traceID, ok := TraceID(tc.span)
+
+			assert.Equal(t, tc.expectedOK, ok)
+			if tc.expectedOK {
+				assert.Equal(t, tc.expectedTraceID, traceID)
+			}
+		})
+	}
+}
+
+func TestObserveWithTraceID(t *testing.T) {
+	span, closer := newJaegerSpan()
+	defer closer.Close()
+	traceID, _ := TraceID(span)
+
+	tests := []struct {
+		name             string
+		span             opentracing.Span
+		expectedExemplar bool
+	}{
+		{
+			name:             "WithoutSpan",
+			span:             nil,
+			expectedExemplar: false,
+		},
+		{
+			name:             "WithSpan",
+			span:             span,
+			expectedExemplar: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			histogram := prometheus.NewHistogram(prometheus.HistogramOpts{
+				Name:    "request_duration_seconds",
+				Help:    "request durations",
+				Buckets: []float64{0.1, 1},
+			})
+
+			ObserveWithTraceID(histogram, 0.5, tc.span)
+
+			metric := new(dto.Metric)
+			assert.NoError(t, histogram.Write(metric))
+			assert.Equal(t, uint64(1), metric.Histogram.GetSampleCount())
+
+			exemplar := metric.Histogram.Bucket[1].Exemplar
+			if tc.expectedExemplar {
+				assert.NotNil(t, exemplar)
+				assert.Equal(t, 0.5, exemplar.GetValue())
+				assert.Equal(t, TraceIDLabel, exemplar.Label[0].GetName())
+				assert.Equal(t, traceID, exemplar.Label[0].GetValue())
+			} else {
+				assert.Nil(t, exemplar)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+)
+
+// Handler creates an http handler for exposing the metrics registered with a factory
+// The metrics are served in Prometheus text format or OpenMetrics format if requested by the client.
+// Responses are compressed with gzip if accepted by the client.
+// If the registerer of factory is not a prometheus.Gatherer (i.e. *prometheus.Registry), the default gatherer is used.
+func Handler(f *Factory) http.Handler {
+	gatherer := f.gatherer()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		families, err := gatherer.Gather()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("error gathering metrics: %s", err), http.StatusInternalServerError)
+			return
+		}
+
+		buf := new(bytes.Buffer)
+		var contentType string
+
+		if acceptsOpenMetrics(r.Header) {
+			contentType = openMetricsContentType
+			err = writeOpenMetrics(buf, families)
+		} else {
+			contentType = string(expfmt.FmtText)
+			for _, family := range families {
+				if _, err = expfmt.MetricFamilyToText(buf, family); err != nil {
+					break
+				}
+			}
+		}
+
+		if err != nil {
+			http.Error(w, fmt.Sprintf("error encoding metrics: %s", err), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		var out io.Writer = w
+		if acceptsGzip(r.Header) {
+			w.Header().Set("Content-Encoding", "gzip")
+			gz := gzip.NewWriter(w)
+			defer gz.Close()
+			out = gz
+		}
+
+		_, _ = buf.WriteTo(out)
+	})
+}
+
+// gatherer returns the gatherer for the metrics registered with factory
+func (f *Factory) gatherer() prometheus.Gatherer {
+	if g, ok := f.registerer.(prometheus.Gatherer); ok {
+		return g
+	}
+
+	return prometheus.DefaultGatherer
+}
+
+func acceptsOpenMetrics(header http.Header) bool {
+	for _, part := range strings.Split(header.Get("Accept"), ",") {
+		mediaType := strings.TrimSpace(strings.Split(part, ";")[0])
+		if mediaType == "application/openmetrics-text" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func acceptsGzip(header http.Header) bool {
+	for _, part := range strings.Split(header.Get("Accept-Encoding"), ",") {
+		encoding := strings.TrimSpace(strings.Split(part, ";")[0])
+		if encoding == "gzip" {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	mf := NewFactory(FactoryOptions{
+		Prefix:     "test",
+		Registerer: registry,
+	})
+
+	mf.Counter("requests_total", "total requests", []string{"method"}).WithLabelValues("GET").Inc()
+
+	tests := []struct {
+		name                string
+		accept              string
+		acceptEncoding      string
+		expectedContentType string
+		expectedGzip        bool
+		expectedContains    []string
+	}{
+		{
+			name:                "Text",
+			accept:              "",
+			expectedContentType: "text/plain; version=0.0.4; charset=utf-8",
+			expectedContains: []string{
+				"# TYPE test_requests_total counter\n",
+				"test_requests_total{method=\"GET\"} 1\n",
+			},
+		},
+		{
+			name:                "OpenMetrics",
+			accept:              "application/openmetrics-text; version=0.0.1,text/plain;version=0.0.4;q=0.5",
+			expectedContentType: "application/openmetrics-text; version=0.0.1; charset=utf-8",
+			expectedContains: []string{
+				"# TYPE test_requests counter\n",
+				"test_requests_total{method=\"GET\"} 1\n",
+				"# EOF\n",
+			},
+		},
+		{
+			name:                "Gzip",
+			accept:              "text/plain",
+			acceptEncoding:      "deflate, gzip;q=1.0",
+			expectedContentType: "text/plain; version=0.0.4; charset=utf-8",
+			expectedGzip:        true,
+			expectedContains: []string{
+				"test_requests_total{method=\"GET\"} 1\n",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/metrics", nil)
+			req.Header.Set("Accept", tc.accept)
+			req.Header.Set("Accept-Encoding", tc.acceptEncoding)
+			rec := httptest.NewRecorder()
+
+			Handler(mf).ServeHTTP(rec, req)
+
+			res := rec.Result()
+			assert.Equal(t, http.StatusOK, res.StatusCode)
+			assert.Equal(t, tc.expectedContentType, res.Header.Get("Content-Type"))
+
+			body := res.Body
+			if tc.expectedGzip {
+				assert.Equal(t, "gzip", res.Header.Get("Content-Encoding"))
+				gz, err := gzip.NewReader(res.Body)
+				assert.NoError(t, err)
+				body = gz
+			} else {
+				assert.Empty(t, res.Header.Get("Content-Encoding"))
+			}
+
+			data, err := ioutil.ReadAll(body)
+			assert.NoError(t, err)
+
+			for _, s := range tc.expectedContains {
+				assert.Contains(t, string(data), s)
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	defaultCheckTimeout = 5 * time.Second
+
+	// StatusOK is the status of a passing health check
+	StatusOK = "ok"
+	// StatusFail is the status of a failing health check
+	StatusFail = "fail"
+)
+
+type (
+	// CheckFunc is a function for checking the health of a component
+	// It should return as soon as the context is done.
+	CheckFunc func(ctx context.Context) error
+
+	// CheckOptions contains optional options for a health check
+	CheckOptions struct {
+		// Timeout is the maximum duration of running the check (default 5s)
+		Timeout time.Duration
+		// Readiness makes the check only part of readiness and not liveness
+		Readiness bool
+	}
+
+	// CheckResult is the result of running a health check
+	CheckResult struct {
+		Status   string `json:"status"`
+		Error    string `json:"error,omitempty"`
+		Duration string `json:"duration"`
+	}
+
+	// HealthReport is the result of running a set of health checks
+	HealthReport struct {
+		Status string                 `json:"status"`
+		Checks map[string]CheckResult `json:"checks"`
+	}
+
+	check struct {
+		name string
+		fn   CheckFunc
+		opts CheckOptions
+	}
+
+	// Health runs named health checks registered by components
+	// Liveness reports the result of all checks that are not readiness-only.
+	// Readiness reports the result of all checks.
+	Health struct {
+		sync.Mutex
+		checks map[string]*check
+		status *prometheus.GaugeVec
+	}
+)
+
+// NewHealth creates a new Health
+// The result of every check is exposed as a health_check_status gauge (1 for passing and 0 for failing).
+func NewHealth(mf *Factory) *Health {
+	return &Health{
+		checks: map[string]*check{},
+		status: mf.Gauge("health_check_status", "status of health checks (1 for ok and 0 for fail)", []string{"check"}),
+	}
+}
+
+// AddCheck registers a named health check
+// A check with the same name replaces the existing check.
+func (h *Health) AddCheck(name string, fn CheckFunc, opts CheckOptions) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultCheckTimeout
+	}
+
+	h.Lock()
+	defer h.Unlock()
+
+	h.checks[name] = &check{
+		name: name,
+		fn:   fn,
+		opts: opts,
+	}
+}
+
+// RemoveCheck unregisters a named health check
+func (h *Health) RemoveCheck(name string) {
+	h.Lock()
+	defer h.Unlock()
+
+	delete(h.checks, name)
+	h.status.DeleteLabelValues(name)
+}
+
+// Liveness runs all checks that are not readiness-only
+func (h *Health) Liveness(ctx context.Context) HealthReport {
+	return h.run(ctx, false)
+}
+
+// Readiness runs all checks
+func (h *Health) Readiness(ctx context.Context) HealthReport {
+	return h.run(ctx, true)
+}
+
+func (h *Health) run(ctx context.Context, readiness bool) HealthReport {
+	h.Lock()
+	checks := make([]*check, 0, len(h.checks))
+	for _, c := range h.checks {
+		if readiness || !c.opts.Readiness {
+			checks = append(checks, c)
+		}
+	}
+	h.Unlock()
+
+	sort.Slice(checks, func(i, j int) bool {
+		return checks[i].name < checks[j].name
+	})
+
+	results := make([]CheckResult, len(checks))
+
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c *check) {
+			defer wg.Done()
+			results[i] = h.runCheck(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	report := HealthReport{
+		Status: StatusOK,
+		Checks: make(map[string]CheckResult, len(checks)),
+	}
+
+	for i, c := range checks {
+		report.Checks[c.name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+
+	return report
+}
+
+func (h *Health) runCheck(parent context.Context, c *check) CheckResult {
+	ctx, cancel := context.WithTimeout(parent, c.opts.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		done <- c.fn(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	if err != nil && parent.Err() == nil && ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("check timed out after %s", c.opts.Timeout)
+	}
+
+	result := CheckResult{
+		Status:   StatusOK,
+		Duration: time.Since(start).String(),
+	}
+
+	status := 1.0
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+		status = 0
+	}
+
+	// A check interrupted by the caller (i.e. a canceled request) says nothing about the health of component
+	if parent.Err() != nil {
+		return result
+	}
+
+	h.Lock()
+	defer h.Unlock()
+
+	// The check may have been removed or replaced while running
+	if h.checks[c.name] == c {
+		h.status.WithLabelValues(c.name).Set(status)
+	}
+
+	return result
+}
+
+// LivenessHandler creates an http handler for liveness checks (i.e. /healthz)
+func (h *Health) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeHealthReport(w, h.Liveness(r.Context()))
+	})
+}
+
+// ReadinessHandler creates an http handler for readiness checks (i.e. /readyz)
+func (h *Health) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeHealthReport(w, h.Readiness(r.Context()))
+	})
+}
+
+func writeHealthReport(w http.ResponseWriter, report HealthReport) {
+	statusCode := http.StatusOK
+	if report.Status != StatusOK {
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,181 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealth(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	mf := NewFactory(FactoryOptions{Registerer: registry})
+
+	h := NewHealth(mf)
+	h.AddCheck("ok", func(ctx context.Context) error {
+		return nil
+	}, CheckOptions{})
+	h.AddCheck("database", func(ctx context.Context) error {
+		return errors.New("connection refused")
+	}, CheckOptions{Readiness: true})
+	h.AddCheck("cache", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, CheckOptions{Timeout: 10 * time.Millisecond, Readiness: true})
+	h.AddCheck("stuck", func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}, CheckOptions{Timeout: 10 * time.Millisecond, Readiness: true})
+	h.AddCheck("panic", func(ctx context.Context) error {
+		panic("oops")
+	}, CheckOptions{Readiness: true})
+
+	tests := []struct {
+		name               string
+		handler            http.Handler
+		expectedStatusCode int
+		expectedReport     HealthReport
+	}{
+		{
+			name:               "Liveness",
+			handler:            h.LivenessHandler(),
+			expectedStatusCode: 200,
+			expectedReport: HealthReport{
+				Status: StatusOK,
+				Checks: map[string]CheckResult{
+					"ok": {Status: StatusOK},
+				},
+			},
+		},
+		{
+			name:               "Readiness",
+			handler:            h.ReadinessHandler(),
+			expectedStatusCode: 503,
+			expectedReport: HealthReport{
+				Status: StatusFail,
+				Checks: map[string]CheckResult{
+					"ok":       {Status: StatusOK},
+					"database": {Status: StatusFail, Error: "connection refused"},
+					"cache":    {Status: StatusFail, Error: "check timed out after 10ms"},
+					"stuck":    {Status: StatusFail, Error: "check timed out after 10ms"},
+					"panic":    {Status: StatusFail, Error: "panic: oops"},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			rec := httptest.NewRecorder()
+
+			tc.handler.ServeHTTP(rec, req)
+
+			res := rec.Result()
+			assert.Equal(t, tc.expectedStatusCode, res.StatusCode)
+			assert.Equal(t, "application/json; charset=utf-8", res.Header.Get("Content-Type"))
+
+			var report HealthReport
+			err := json.NewDecoder(res.Body).Decode(&report)
+			assert.NoError(t, err)
+
+			assert.Equal(t, tc.expectedReport.Status, report.Status)
+			assert.Len(t, report.Checks, len(tc.expectedReport.Checks))
+			for name, expected := range tc.expectedReport.Checks {
+				result := report.Checks[name]
+				assert.Equal(t, expected.Status, result.Status, name)
+				assert.Equal(t, expected.Error, result.Error, name)
+				assert.NotEmpty(t, result.Duration, name)
+			}
+		})
+	}
+
+	t.Run("Gauge", func(t *testing.T) {
+		metricFamilies, err := registry.Gather()
+		assert.NoError(t, err)
+
+		statuses := map[string]float64{}
+		for _, metricFamily := range metricFamilies {
+			if *metricFamily.Name == "health_check_status" {
+				for _, metric := range metricFamily.Metric {
+					statuses[*metric.Label[0].Value] = *metric.Gauge.Value
+				}
+			}
+		}
+
+		assert.Equal(t, map[string]float64{
+			"ok":       1,
+			"database": 0,
+			"cache":    0,
+			"stuck":    0,
+			"panic":    0,
+		}, statuses)
+	})
+
+	t.Run("RemoveCheck", func(t *testing.T) {
+		for _, name := range []string{"database", "cache", "stuck", "panic"} {
+			h.RemoveCheck(name)
+		}
+
+		report := h.Readiness(context.Background())
+		assert.Equal(t, StatusOK, report.Status)
+		assert.Len(t, report.Checks, 1)
+	})
+}
+
+func TestHealthCanceledContext(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	mf := NewFactory(FactoryOptions{Registerer: registry})
+
+	h := NewHealth(mf)
+	h.AddCheck("database", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, CheckOptions{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	report := h.Readiness(ctx)
+	assert.Equal(t, StatusFail, report.Status)
+	assert.Equal(t, "context canceled", report.Checks["database"].Error)
+
+	// The failure is not recorded since the check was interrupted by the caller
+	assert.Equal(t, 0, testutil.CollectAndCount(h.status))
+}
+
+func TestHealthRemoveCheckWhileRunning(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	mf := NewFactory(FactoryOptions{Registerer: registry})
+
+	h := NewHealth(mf)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	h.AddCheck("database", func(ctx context.Context) error {
+		close(started)
+		<-release
+		return nil
+	}, CheckOptions{})
+
+	done := make(chan HealthReport)
+	go func() {
+		done <- h.Readiness(context.Background())
+	}()
+
+	<-started
+	h.RemoveCheck("database")
+	close(release)
+
+	report := <-done
+	assert.Equal(t, StatusOK, report.Status)
+
+	// The result of a removed check is not recorded
+	assert.Equal(t, 0, testutil.CollectAndCount(h.status))
+}
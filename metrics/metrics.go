@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"strings"
 
+	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -27,14 +28,37 @@ type (
 		Buckets    []float64
 		Quantiles  map[float64]float64
 		Registerer prometheus.Registerer
+		// RuntimeMetrics enables scheduler latency, GC pause, heap size class, goroutine state, and file descriptor metrics
+		RuntimeMetrics bool
+		// MaxSeries is the maximum number of label value combinations exposed for each limited metric (default unlimited)
+		// Label values of new series exceeding the limit are replaced with __overflow__, so they share one series.
+		MaxSeries int
 	}
 
 	// Factory is used for creating new metrics with consistent settings
+	// Creating a metric more than once with the same definition returns the already registered metric
 	Factory struct {
-		prefix     string
-		buckets    []float64
-		quantiles  map[float64]float64
-		registerer prometheus.Registerer
+		prefix      string
+		buckets     []float64
+		quantiles   map[float64]float64
+		registerer  prometheus.Registerer
+		cache       *cache
+		subsystem   string
+		constLabels prometheus.Labels
+		maxSeries   int
+		overflows   *CounterVec
+	}
+
+	// HistogramOptions contains optional options for creating a histogram metric
+	// Zero values fall back to the factory defaults
+	HistogramOptions struct {
+		Buckets []float64
+	}
+
+	// SummaryOptions contains optional options for creating a summary metric
+	// Zero values fall back to the factory defaults
+	SummaryOptions struct {
+		Quantiles map[float64]float64
 	}
 
 	// OpMetrics includes metrics for internal operations
@@ -43,6 +67,13 @@ type (
 		OpLatencySumm *prometheus.SummaryVec
 	}
 
+	// OperationMetrics includes the RED metrics for internal operations (i.e. database calls, queue consumers, cron jobs, etc.)
+	OperationMetrics struct {
+		OpGauge       *GaugeVec
+		OpCounter     *CounterVec
+		OpLatencyHist *HistogramVec
+	}
+
 	// RequestMetrics includes metrics for service requests
 	RequestMetrics struct {
 		ReqCounter      *prometheus.CounterVec
@@ -50,6 +81,14 @@ type (
 		ReqDurationHist *prometheus.HistogramVec
 		ReqDurationSumm *prometheus.SummaryVec
 	}
+
+	// LimitedRequestMetrics includes metrics for service requests limited to the series limit of factory
+	LimitedRequestMetrics struct {
+		ReqCounter      *CounterVec
+		ReqGauge        *GaugeVec
+		ReqDurationHist *HistogramVec
+		ReqDurationSumm *SummaryVec
+	}
 )
 
 // NewFactory creates a new instance of Factory
@@ -67,20 +106,67 @@ func NewFactory(opts FactoryOptions) *Factory {
 	}
 
 	// GoCollector and ProcessCollector are registered with default Prometheus registry by default
+	// They may have already been registered by another factory using the same registerer
 	if opts.Registerer != prometheus.DefaultRegisterer {
-		opts.Registerer.MustRegister(prometheus.NewGoCollector())
-		opts.Registerer.MustRegister(prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}))
+		mustRegisterOnce(opts.Registerer, prometheus.NewGoCollector())
+		mustRegisterOnce(opts.Registerer, prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}))
 	}
 
-	return &Factory{
+	f := &Factory{
 		prefix:     opts.Prefix,
 		buckets:    opts.Buckets,
 		quantiles:  opts.Quantiles,
 		registerer: opts.Registerer,
+		cache:      newCache(),
+	}
+
+	if opts.RuntimeMetrics {
+		mustRegisterOnce(f.registerer, newRuntimeCollector(f))
 	}
+
+	if opts.MaxSeries > 0 {
+		f.overflows = f.LimitedCounter("metric_overflow_total", "total number of lookups of new series redirected to __overflow__ series per metric", []string{"metric"})
+		f.maxSeries = opts.MaxSeries
+	}
+
+	return f
+}
+
+// With creates a child factory that adds constant labels to every metric it creates
+// The child factory shares the registerer, prefix, and subsystem with its parent
+func (f *Factory) With(constLabels prometheus.Labels) *Factory {
+	labels := prometheus.Labels{}
+	for k, v := range f.constLabels {
+		labels[k] = v
+	}
+	for k, v := range constLabels {
+		labels[k] = v
+	}
+
+	child := *f
+	child.constLabels = labels
+
+	return &child
+}
+
+// Sub creates a child factory that adds a subsystem to the names of all metrics it creates (prefix_subsystem_name)
+// The child factory shares the registerer, prefix, and constant labels with its parent
+func (f *Factory) Sub(subsystem string) *Factory {
+	if f.subsystem != "" {
+		subsystem = fmt.Sprintf("%s_%s", f.subsystem, subsystem)
+	}
+
+	child := *f
+	child.subsystem = subsystem
+
+	return &child
 }
 
 func (f *Factory) getMetricName(name string) string {
+	if f.subsystem != "" {
+		name = fmt.Sprintf("%s_%s", f.subsystem, name)
+	}
+
 	if f.prefix != "" {
 		name = fmt.Sprintf("%s_%s", f.prefix, name)
 	}
@@ -91,56 +177,284 @@ func (f *Factory) getMetricName(name string) string {
 	return name
 }
 
-// Counter creates a new counter metrics
+// Counter creates a new counter metrics or returns the existing one with the same definition
+// It panics if a metric with the same name is already registered with a different definition
 func (f *Factory) Counter(name, description string, labels []string) *prometheus.CounterVec {
-	opts := prometheus.CounterOpts{
-		Name: f.getMetricName(name),
-		Help: description,
+	counter, err := f.RegisterCounter(name, description, labels)
+	if err != nil {
+		panic(err)
 	}
 
-	counter := prometheus.NewCounterVec(opts, labels)
-	f.registerer.MustRegister(counter)
+	return counter
+}
+
+// RegisterCounter creates a new counter metrics or returns the existing one with the same definition
+func (f *Factory) RegisterCounter(name, description string, labels []string) (*prometheus.CounterVec, error) {
+	counter, err := f.registerLimitedCounter(name, description, labels)
+	if err != nil {
+		return nil, err
+	}
+
+	return counter.CounterVec, nil
+}
+
+// LimitedCounter is the same as Counter but the returned counter is limited to the series limit of factory
+// It panics if a metric with the same name is already registered with a different definition
+func (f *Factory) LimitedCounter(name, description string, labels []string) *CounterVec {
+	counter, err := f.registerLimitedCounter(name, description, labels)
+	if err != nil {
+		panic(err)
+	}
 
 	return counter
 }
 
-// Gauge creates a new gauge metrics
+// registerLimitedCounter creates a new counter metrics limited to the series limit of factory or returns the existing one with the same definition
+func (f *Factory) registerLimitedCounter(name, description string, labels []string) (*CounterVec, error) {
+	opts := prometheus.CounterOpts{
+		Name:        f.getMetricName(name),
+		Help:        description,
+		ConstLabels: f.constLabels,
+	}
+
+	c, err := f.register(counterKind, opts.Name, opts.Help, labels, func() prometheus.Collector {
+		return &CounterVec{
+			CounterVec: prometheus.NewCounterVec(opts, labels),
+			limiter:    f.newLimiter(opts.Name, labels),
+		}
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch counter := c.(type) {
+	case *CounterVec:
+		return counter, nil
+	case *prometheus.CounterVec:
+		// Registered directly with the registerer
+		return &CounterVec{CounterVec: counter}, nil
+	default:
+		return nil, &ConflictError{Name: opts.Name, Reason: "a different type"}
+	}
+}
+
+// Gauge creates a new gauge metrics or returns the existing one with the same definition
+// It panics if a metric with the same name is already registered with a different definition
 func (f *Factory) Gauge(name, description string, labels []string) *prometheus.GaugeVec {
-	opts := prometheus.GaugeOpts{
-		Name: f.getMetricName(name),
-		Help: description,
+	gauge, err := f.RegisterGauge(name, description, labels)
+	if err != nil {
+		panic(err)
 	}
 
-	gauge := prometheus.NewGaugeVec(opts, labels)
-	f.registerer.MustRegister(gauge)
+	return gauge
+}
+
+// RegisterGauge creates a new gauge metrics or returns the existing one with the same definition
+func (f *Factory) RegisterGauge(name, description string, labels []string) (*prometheus.GaugeVec, error) {
+	gauge, err := f.registerLimitedGauge(name, description, labels)
+	if err != nil {
+		return nil, err
+	}
+
+	return gauge.GaugeVec, nil
+}
+
+// LimitedGauge is the same as Gauge but the returned gauge is limited to the series limit of factory
+// It panics if a metric with the same name is already registered with a different definition
+func (f *Factory) LimitedGauge(name, description string, labels []string) *GaugeVec {
+	gauge, err := f.registerLimitedGauge(name, description, labels)
+	if err != nil {
+		panic(err)
+	}
 
 	return gauge
 }
 
-// Histogram creates a new histogram metrics
-func (f *Factory) Histogram(name, description string, labels []string) *prometheus.HistogramVec {
-	opts := prometheus.HistogramOpts{
-		Name:    f.getMetricName(name),
-		Help:    description,
-		Buckets: defaultBuckets,
+// registerLimitedGauge creates a new gauge metrics limited to the series limit of factory or returns the existing one with the same definition
+func (f *Factory) registerLimitedGauge(name, description string, labels []string) (*GaugeVec, error) {
+	opts := prometheus.GaugeOpts{
+		Name:        f.getMetricName(name),
+		Help:        description,
+		ConstLabels: f.constLabels,
+	}
+
+	c, err := f.register(gaugeKind, opts.Name, opts.Help, labels, func() prometheus.Collector {
+		return &GaugeVec{
+			GaugeVec: prometheus.NewGaugeVec(opts, labels),
+			limiter:  f.newLimiter(opts.Name, labels),
+		}
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch gauge := c.(type) {
+	case *GaugeVec:
+		return gauge, nil
+	case *prometheus.GaugeVec:
+		// Registered directly with the registerer
+		return &GaugeVec{GaugeVec: gauge}, nil
+	default:
+		return nil, &ConflictError{Name: opts.Name, Reason: "a different type"}
 	}
+}
 
-	histogram := prometheus.NewHistogramVec(opts, labels)
-	f.registerer.MustRegister(histogram)
+// Histogram creates a new histogram metrics or returns the existing one with the same definition
+// The buckets of factory are used unless overridden by options
+// It panics if a metric with the same name is already registered with a different definition
+func (f *Factory) Histogram(name, description string, labels []string, options ...HistogramOptions) *prometheus.HistogramVec {
+	histogram, err := f.RegisterHistogram(name, description, labels, options...)
+	if err != nil {
+		panic(err)
+	}
 
 	return histogram
 }
 
-// Summary creates a new summary metrics
-func (f *Factory) Summary(name, description string, labels []string) *prometheus.SummaryVec {
-	opts := prometheus.SummaryOpts{
-		Name:       f.getMetricName(name),
-		Help:       description,
-		Objectives: defaultQuantiles,
+// RegisterHistogram creates a new histogram metrics or returns the existing one with the same definition
+// The buckets of factory are used unless overridden by options
+func (f *Factory) RegisterHistogram(name, description string, labels []string, options ...HistogramOptions) (*prometheus.HistogramVec, error) {
+	histogram, err := f.registerLimitedHistogram(name, description, labels, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	return histogram.HistogramVec, nil
+}
+
+// LimitedHistogram is the same as Histogram but the returned histogram is limited to the series limit of factory
+// It panics if a metric with the same name is already registered with a different definition
+func (f *Factory) LimitedHistogram(name, description string, labels []string, options ...HistogramOptions) *HistogramVec {
+	histogram, err := f.registerLimitedHistogram(name, description, labels, options...)
+	if err != nil {
+		panic(err)
 	}
 
-	summary := prometheus.NewSummaryVec(opts, labels)
-	f.registerer.MustRegister(summary)
+	return histogram
+}
+
+// registerLimitedHistogram creates a new histogram metrics limited to the series limit of factory or returns the existing one with the same definition
+func (f *Factory) registerLimitedHistogram(name, description string, labels []string, options ...HistogramOptions) (*HistogramVec, error) {
+	buckets := f.buckets
+	for _, o := range options {
+		if len(o.Buckets) > 0 {
+			buckets = o.Buckets
+		}
+	}
+
+	opts := prometheus.HistogramOpts{
+		Name:        f.getMetricName(name),
+		Help:        description,
+		ConstLabels: f.constLabels,
+		Buckets:     buckets,
+	}
+
+	c, err := f.register(histogramKind, opts.Name, opts.Help, labels, func() prometheus.Collector {
+		return &HistogramVec{
+			HistogramVec: prometheus.NewHistogramVec(opts, labels),
+			limiter:      f.newLimiter(opts.Name, labels),
+		}
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch histogram := c.(type) {
+	case *HistogramVec:
+		return histogram, nil
+	case *prometheus.HistogramVec:
+		// Registered directly with the registerer
+		return &HistogramVec{HistogramVec: histogram}, nil
+	default:
+		return nil, &ConflictError{Name: opts.Name, Reason: "a different type"}
+	}
+}
+
+// Summary creates a new summary metrics or returns the existing one with the same definition
+// The quantiles of factory are used unless overridden by options
+// It panics if a metric with the same name is already registered with a different definition
+func (f *Factory) Summary(name, description string, labels []string, options ...SummaryOptions) *prometheus.SummaryVec {
+	summary, err := f.RegisterSummary(name, description, labels, options...)
+	if err != nil {
+		panic(err)
+	}
 
 	return summary
 }
+
+// RegisterSummary creates a new summary metrics or returns the existing one with the same definition
+// The quantiles of factory are used unless overridden by options
+func (f *Factory) RegisterSummary(name, description string, labels []string, options ...SummaryOptions) (*prometheus.SummaryVec, error) {
+	summary, err := f.registerLimitedSummary(name, description, labels, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	return summary.SummaryVec, nil
+}
+
+// LimitedSummary is the same as Summary but the returned summary is limited to the series limit of factory
+// It panics if a metric with the same name is already registered with a different definition
+func (f *Factory) LimitedSummary(name, description string, labels []string, options ...SummaryOptions) *SummaryVec {
+	summary, err := f.registerLimitedSummary(name, description, labels, options...)
+	if err != nil {
+		panic(err)
+	}
+
+	return summary
+}
+
+// registerLimitedSummary creates a new summary metrics limited to the series limit of factory or returns the existing one with the same definition
+func (f *Factory) registerLimitedSummary(name, description string, labels []string, options ...SummaryOptions) (*SummaryVec, error) {
+	quantiles := f.quantiles
+	for _, o := range options {
+		if len(o.Quantiles) > 0 {
+			quantiles = o.Quantiles
+		}
+	}
+
+	opts := prometheus.SummaryOpts{
+		Name:        f.getMetricName(name),
+		Help:        description,
+		ConstLabels: f.constLabels,
+		Objectives:  quantiles,
+	}
+
+	c, err := f.register(summaryKind, opts.Name, opts.Help, labels, func() prometheus.Collector {
+		return &SummaryVec{
+			SummaryVec: prometheus.NewSummaryVec(opts, labels),
+			limiter:    f.newLimiter(opts.Name, labels),
+		}
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch summary := c.(type) {
+	case *SummaryVec:
+		return summary, nil
+	case *prometheus.SummaryVec:
+		// Registered directly with the registerer
+		return &SummaryVec{SummaryVec: summary}, nil
+	default:
+		return nil, &ConflictError{Name: opts.Name, Reason: "a different type"}
+	}
+}
+
+// ObserveDuration observes a request duration with the histogram and summary metrics
+// The trace id of span is attached to the histogram observation as an exemplar if span is not nil.
+func (m *RequestMetrics) ObserveDuration(span opentracing.Span, duration float64, labelValues ...string) {
+	ObserveWithTraceID(m.ReqDurationHist.WithLabelValues(labelValues...), duration, span)
+	m.ReqDurationSumm.WithLabelValues(labelValues...).Observe(duration)
+}
+
+// ObserveDuration observes a request duration with the histogram and summary metrics
+// The trace id of span is attached to the histogram observation as an exemplar if span is not nil.
+func (m *LimitedRequestMetrics) ObserveDuration(span opentracing.Span, duration float64, labelValues ...string) {
+	ObserveWithTraceID(m.ReqDurationHist.WithLabelValues(labelValues...), duration, span)
+	m.ReqDurationSumm.WithLabelValues(labelValues...).Observe(duration)
+}
@@ -1,6 +1,7 @@
 package metrics
 
 import (
+	"net/http/httptest"
 	"testing"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -315,3 +316,258 @@ func TestSummary(t *testing.T) {
 		})
 	}
 }
+
+func TestHistogramBuckets(t *testing.T) {
+	tests := []struct {
+		name            string
+		opts            FactoryOptions
+		options         []HistogramOptions
+		expectedBuckets []float64
+	}{
+		{
+			name:            "Defaults",
+			opts:            FactoryOptions{},
+			options:         nil,
+			expectedBuckets: defaultBuckets,
+		},
+		{
+			name: "FactoryBuckets",
+			opts: FactoryOptions{
+				Buckets: []float64{0.1, 1, 10},
+			},
+			options:         nil,
+			expectedBuckets: []float64{0.1, 1, 10},
+		},
+		{
+			name: "HistogramBuckets",
+			opts: FactoryOptions{
+				Buckets: []float64{0.1, 1, 10},
+			},
+			options: []HistogramOptions{
+				{Buckets: []float64{0.25, 0.5}},
+			},
+			expectedBuckets: []float64{0.25, 0.5},
+		},
+		{
+			name: "EmptyHistogramBuckets",
+			opts: FactoryOptions{
+				Buckets: []float64{0.1, 1, 10},
+			},
+			options: []HistogramOptions{
+				{},
+			},
+			expectedBuckets: []float64{0.1, 1, 10},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.opts.Registerer = prometheus.NewRegistry()
+			mf := NewFactory(tc.opts)
+			histogram := mf.Histogram("histogram_metric_name", "metric description", []string{"region"}, tc.options...)
+
+			reg := prometheus.NewRegistry()
+			reg.MustRegister(histogram)
+			histogram.WithLabelValues("us-east-1").Observe(0.1234)
+
+			metricFamilies, err := reg.Gather()
+			assert.NoError(t, err)
+			assert.Len(t, metricFamilies, 1)
+
+			buckets := []float64{}
+			for _, b := range metricFamilies[0].Metric[0].Histogram.Bucket {
+				buckets = append(buckets, *b.UpperBound)
+			}
+			assert.Equal(t, tc.expectedBuckets, buckets)
+		})
+	}
+}
+
+func TestSummaryQuantiles(t *testing.T) {
+	tests := []struct {
+		name              string
+		opts              FactoryOptions
+		options           []SummaryOptions
+		expectedQuantiles []float64
+	}{
+		{
+			name:              "Defaults",
+			opts:              FactoryOptions{},
+			options:           nil,
+			expectedQuantiles: []float64{0.1, 0.5, 0.95, 0.99},
+		},
+		{
+			name: "FactoryQuantiles",
+			opts: FactoryOptions{
+				Quantiles: map[float64]float64{0.5: 0.05, 0.9: 0.01},
+			},
+			options:           nil,
+			expectedQuantiles: []float64{0.5, 0.9},
+		},
+		{
+			name: "SummaryQuantiles",
+			opts: FactoryOptions{
+				Quantiles: map[float64]float64{0.5: 0.05, 0.9: 0.01},
+			},
+			options: []SummaryOptions{
+				{Quantiles: map[float64]float64{0.99: 0.001}},
+			},
+			expectedQuantiles: []float64{0.99},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.opts.Registerer = prometheus.NewRegistry()
+			mf := NewFactory(tc.opts)
+			summary := mf.Summary("summary_metric_name", "metric description", []string{"region"}, tc.options...)
+
+			reg := prometheus.NewRegistry()
+			reg.MustRegister(summary)
+			summary.WithLabelValues("us-east-1").Observe(0.1234)
+
+			metricFamilies, err := reg.Gather()
+			assert.NoError(t, err)
+			assert.Len(t, metricFamilies, 1)
+
+			quantiles := []float64{}
+			for _, q := range metricFamilies[0].Metric[0].Summary.Quantile {
+				quantiles = append(quantiles, *q.Quantile)
+			}
+			assert.Equal(t, tc.expectedQuantiles, quantiles)
+		})
+	}
+}
+
+func TestFactoryWith(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	mf := NewFactory(FactoryOptions{
+		Prefix:     "service",
+		Registerer: registry,
+	})
+
+	child := mf.With(prometheus.Labels{"service": "auth", "region": "us-east-1"})
+	grandchild := child.With(prometheus.Labels{"region": "ca-central-1"})
+
+	assert.Equal(t, prometheus.Labels{"service": "auth", "region": "us-east-1"}, child.constLabels)
+	assert.Equal(t, prometheus.Labels{"service": "auth", "region": "ca-central-1"}, grandchild.constLabels)
+	assert.Nil(t, mf.constLabels)
+
+	// Same metric name with different constant label values
+	child.Counter("requests_total", "total requests", []string{"method"}).WithLabelValues("GET").Inc()
+	grandchild.Counter("requests_total", "total requests", []string{"method"}).WithLabelValues("GET").Add(2)
+
+	// Same metric name and constant labels
+	c1 := child.Gauge("requests", "active requests", []string{"method"})
+	c2 := mf.With(prometheus.Labels{"region": "us-east-1", "service": "auth"}).Gauge("requests", "active requests", []string{"method"})
+	assert.True(t, c1 == c2)
+
+	// Same metric name with different constant label names
+	_, err := child.With(prometheus.Labels{"version": "0.1.0"}).RegisterCounter("requests_total", "total requests", []string{"method"})
+	assert.Error(t, err)
+
+	metricFamilies, err := registry.Gather()
+	assert.NoError(t, err)
+
+	for _, metricFamily := range metricFamilies {
+		if *metricFamily.Name != "service_requests_total" {
+			continue
+		}
+
+		assert.Len(t, metricFamily.Metric, 2)
+		for _, metric := range metricFamily.Metric {
+			labels := map[string]string{}
+			for _, l := range metric.Label {
+				labels[*l.Name] = *l.Value
+			}
+
+			switch *metric.Counter.Value {
+			case 1:
+				assert.Equal(t, map[string]string{"method": "GET", "service": "auth", "region": "us-east-1"}, labels)
+			case 2:
+				assert.Equal(t, map[string]string{"method": "GET", "service": "auth", "region": "ca-central-1"}, labels)
+			}
+		}
+	}
+}
+
+func TestFactorySub(t *testing.T) {
+	tests := []struct {
+		name         string
+		prefix       string
+		subsystems   []string
+		metricName   string
+		expectedName string
+	}{
+		{
+			name:         "NoPrefix",
+			prefix:       "",
+			subsystems:   []string{"db"},
+			metricName:   "queries_total",
+			expectedName: "db_queries_total",
+		},
+		{
+			name:         "WithPrefix",
+			prefix:       "service",
+			subsystems:   []string{"db"},
+			metricName:   "queries_total",
+			expectedName: "service_db_queries_total",
+		},
+		{
+			name:         "Nested",
+			prefix:       "service",
+			subsystems:   []string{"db", "connection-pool"},
+			metricName:   "connections",
+			expectedName: "service_db_connection_pool_connections",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mf := NewFactory(FactoryOptions{
+				Prefix:     tc.prefix,
+				Registerer: prometheus.NewRegistry(),
+			})
+
+			for _, sub := range tc.subsystems {
+				mf = mf.Sub(sub)
+			}
+
+			counter := mf.Counter(tc.metricName, "metric description", nil)
+
+			reg := prometheus.NewRegistry()
+			reg.MustRegister(counter)
+			counter.WithLabelValues().Inc()
+
+			metricFamilies, err := reg.Gather()
+			assert.NoError(t, err)
+			assert.Len(t, metricFamilies, 1)
+			assert.Equal(t, tc.expectedName, *metricFamilies[0].Name)
+		})
+	}
+}
+
+func TestRequestMetricsObserveDuration(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	mf := NewFactory(FactoryOptions{Registerer: registry})
+
+	m := &RequestMetrics{
+		ReqDurationHist: mf.Histogram("request_duration_seconds", "request durations", []string{"method"}),
+		ReqDurationSumm: mf.Summary("request_duration_quantiles_seconds", "request durations", []string{"method"}),
+	}
+
+	span, closer := newJaegerSpan()
+	defer closer.Close()
+	m.ObserveDuration(span, 0.2, "GET")
+	m.ObserveDuration(nil, 0.3, "GET")
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	req.Header.Set("Accept", "application/openmetrics-text")
+	rec := httptest.NewRecorder()
+	Handler(mf).ServeHTTP(rec, req)
+
+	traceID, _ := TraceID(span)
+	body := rec.Body.String()
+	assert.Contains(t, body, `request_duration_seconds_bucket{method="GET",le="0.5"} 2 # {trace_id="`+traceID+`"} 0.2 `)
+	assert.Contains(t, body, `request_duration_quantiles_seconds_count{method="GET"} 2`)
+}
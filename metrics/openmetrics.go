@@ -0,0 +1,170 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+const openMetricsContentType = "application/openmetrics-text; version=0.0.1; charset=utf-8"
+
+var openMetricsEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+// writeOpenMetrics writes metric families in the OpenMetrics text format
+func writeOpenMetrics(w io.Writer, families []*dto.MetricFamily) error {
+	bw := bufio.NewWriter(w)
+
+	for _, family := range families {
+		writeOpenMetricsFamily(bw, family)
+	}
+
+	_, _ = bw.WriteString("# EOF\n")
+
+	return bw.Flush()
+}
+
+func writeOpenMetricsFamily(w *bufio.Writer, family *dto.MetricFamily) {
+	name := family.GetName()
+
+	var typ string
+	switch family.GetType() {
+	case dto.MetricType_COUNTER:
+		// The name of a counter family does not include the _total suffix
+		typ, name = "counter", strings.TrimSuffix(name, "_total")
+	case dto.MetricType_GAUGE:
+		typ = "gauge"
+	case dto.MetricType_HISTOGRAM:
+		typ = "histogram"
+	case dto.MetricType_SUMMARY:
+		typ = "summary"
+	default:
+		typ = "unknown"
+	}
+
+	_, _ = w.WriteString("# TYPE " + name + " " + typ + "\n")
+	if family.Help != nil {
+		_, _ = w.WriteString("# HELP " + name + " " + openMetricsEscaper.Replace(family.GetHelp()) + "\n")
+	}
+
+	for _, m := range family.Metric {
+		switch family.GetType() {
+		case dto.MetricType_COUNTER:
+			writeOpenMetricsSample(w, name+"_total", m, "", 0, m.GetCounter().GetValue(), m.GetCounter().GetExemplar())
+
+		case dto.MetricType_GAUGE:
+			writeOpenMetricsSample(w, name, m, "", 0, m.GetGauge().GetValue(), nil)
+
+		case dto.MetricType_HISTOGRAM:
+			h := m.GetHistogram()
+			hasInf := false
+			for _, b := range h.Bucket {
+				writeOpenMetricsSample(w, name+"_bucket", m, "le", b.GetUpperBound(), float64(b.GetCumulativeCount()), b.GetExemplar())
+				hasInf = hasInf || math.IsInf(b.GetUpperBound(), +1)
+			}
+			if !hasInf {
+				writeOpenMetricsSample(w, name+"_bucket", m, "le", math.Inf(+1), float64(h.GetSampleCount()), nil)
+			}
+			writeOpenMetricsSample(w, name+"_count", m, "", 0, float64(h.GetSampleCount()), nil)
+			writeOpenMetricsSample(w, name+"_sum", m, "", 0, h.GetSampleSum(), nil)
+
+		case dto.MetricType_SUMMARY:
+			s := m.GetSummary()
+			for _, q := range s.Quantile {
+				writeOpenMetricsSample(w, name, m, "quantile", q.GetQuantile(), q.GetValue(), nil)
+			}
+			writeOpenMetricsSample(w, name+"_count", m, "", 0, float64(s.GetSampleCount()), nil)
+			writeOpenMetricsSample(w, name+"_sum", m, "", 0, s.GetSampleSum(), nil)
+
+		default:
+			writeOpenMetricsSample(w, name, m, "", 0, m.GetUntyped().GetValue(), nil)
+		}
+	}
+}
+
+// writeOpenMetricsSample writes one sample line with an optional extra label (le or quantile) and an optional exemplar
+func writeOpenMetricsSample(w *bufio.Writer, name string, m *dto.Metric, extraName string, extraValue float64, value float64, exemplar *dto.Exemplar) {
+	_, _ = w.WriteString(name)
+
+	labels := make([]*dto.LabelPair, len(m.Label))
+	copy(labels, m.Label)
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].GetName() < labels[j].GetName()
+	})
+
+	if len(labels) > 0 || extraName != "" {
+		_ = w.WriteByte('{')
+		for i, l := range labels {
+			if i > 0 {
+				_ = w.WriteByte(',')
+			}
+			_, _ = w.WriteString(l.GetName() + `="` + openMetricsEscaper.Replace(l.GetValue()) + `"`)
+		}
+		if extraName != "" {
+			if len(labels) > 0 {
+				_ = w.WriteByte(',')
+			}
+			_, _ = w.WriteString(extraName + `="` + formatOpenMetricsLabelFloat(extraValue) + `"`)
+		}
+		_ = w.WriteByte('}')
+	}
+
+	_ = w.WriteByte(' ')
+	_, _ = w.WriteString(formatOpenMetricsFloat(value))
+
+	if m.TimestampMs != nil {
+		_ = w.WriteByte(' ')
+		_, _ = w.WriteString(strconv.FormatFloat(float64(m.GetTimestampMs())/1000, 'f', -1, 64))
+	}
+
+	if exemplar != nil {
+		writeOpenMetricsExemplar(w, exemplar)
+	}
+
+	_ = w.WriteByte('\n')
+}
+
+// writeOpenMetricsExemplar writes an exemplar (i.e. # {trace_id="abc"} 0.42 1500000000.123)
+func writeOpenMetricsExemplar(w *bufio.Writer, e *dto.Exemplar) {
+	_, _ = w.WriteString(" # {")
+	for i, l := range e.Label {
+		if i > 0 {
+			_ = w.WriteByte(',')
+		}
+		_, _ = w.WriteString(l.GetName() + `="` + openMetricsEscaper.Replace(l.GetValue()) + `"`)
+	}
+	_, _ = w.WriteString("} ")
+	_, _ = w.WriteString(formatOpenMetricsFloat(e.GetValue()))
+
+	if ts := e.GetTimestamp(); ts != nil {
+		_ = w.WriteByte(' ')
+		_, _ = w.WriteString(strconv.FormatFloat(float64(ts.Seconds)+float64(ts.Nanos)/1e9, 'f', -1, 64))
+	}
+}
+
+func formatOpenMetricsFloat(f float64) string {
+	switch {
+	case math.IsInf(f, +1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+}
+
+// formatOpenMetricsLabelFloat formats le and quantile label values in the canonical form (1 is written as 1.0)
+func formatOpenMetricsLabelFloat(f float64) string {
+	s := formatOpenMetricsFloat(f)
+	if !math.IsInf(f, 0) && !math.IsNaN(f) && !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+
+	return s
+}
@@ -0,0 +1,205 @@
+package metrics
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatOpenMetricsFloat(t *testing.T) {
+	tests := []struct {
+		f                   float64
+		expectedValue       string
+		expectedLabelString string
+	}{
+		{0, "0", "0.0"},
+		{1, "1", "1.0"},
+		{0.25, "0.25", "0.25"},
+		{1e+21, "1e+21", "1e+21"},
+		{math.Inf(+1), "+Inf", "+Inf"},
+		{math.Inf(-1), "-Inf", "-Inf"},
+		{math.NaN(), "NaN", "NaN"},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, tc.expectedValue, formatOpenMetricsFloat(tc.f))
+		assert.Equal(t, tc.expectedLabelString, formatOpenMetricsLabelFloat(tc.f))
+	}
+}
+
+func TestWriteOpenMetrics(t *testing.T) {
+	tests := []struct {
+		name           string
+		families       []*dto.MetricFamily
+		expectedOutput string
+	}{
+		{
+			name:           "Empty",
+			families:       nil,
+			expectedOutput: "# EOF\n",
+		},
+		{
+			name: "Counter",
+			families: []*dto.MetricFamily{
+				{
+					Name: proto.String("requests_total"),
+					Help: proto.String("total \"requests\"\nper method"),
+					Type: dto.MetricType_COUNTER.Enum(),
+					Metric: []*dto.Metric{
+						{
+							Label: []*dto.LabelPair{
+								{Name: proto.String("method"), Value: proto.String("GET")},
+								{Name: proto.String("handler"), Value: proto.String(`a\b`)},
+							},
+							Counter:     &dto.Counter{Value: proto.Float64(10)},
+							TimestampMs: proto.Int64(1500),
+						},
+					},
+				},
+			},
+			expectedOutput: "# TYPE requests counter\n" +
+				"# HELP requests total \\\"requests\\\"\\nper method\n" +
+				"requests_total{handler=\"a\\\\b\",method=\"GET\"} 10 1.5\n" +
+				"# EOF\n",
+		},
+		{
+			name: "Gauge",
+			families: []*dto.MetricFamily{
+				{
+					Name: proto.String("requests"),
+					Type: dto.MetricType_GAUGE.Enum(),
+					Metric: []*dto.Metric{
+						{Gauge: &dto.Gauge{Value: proto.Float64(2.5)}},
+					},
+				},
+			},
+			expectedOutput: "# TYPE requests gauge\n" +
+				"requests 2.5\n" +
+				"# EOF\n",
+		},
+		{
+			name: "Histogram",
+			families: []*dto.MetricFamily{
+				{
+					Name: proto.String("duration_seconds"),
+					Help: proto.String("durations"),
+					Type: dto.MetricType_HISTOGRAM.Enum(),
+					Metric: []*dto.Metric{
+						{
+							Label: []*dto.LabelPair{
+								{Name: proto.String("method"), Value: proto.String("GET")},
+							},
+							Histogram: &dto.Histogram{
+								SampleCount: proto.Uint64(3),
+								SampleSum:   proto.Float64(2.6),
+								Bucket: []*dto.Bucket{
+									{UpperBound: proto.Float64(0.1), CumulativeCount: proto.Uint64(1)},
+									{UpperBound: proto.Float64(1), CumulativeCount: proto.Uint64(2)},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedOutput: "# TYPE duration_seconds histogram\n" +
+				"# HELP duration_seconds durations\n" +
+				"duration_seconds_bucket{method=\"GET\",le=\"0.1\"} 1\n" +
+				"duration_seconds_bucket{method=\"GET\",le=\"1.0\"} 2\n" +
+				"duration_seconds_bucket{method=\"GET\",le=\"+Inf\"} 3\n" +
+				"duration_seconds_count{method=\"GET\"} 3\n" +
+				"duration_seconds_sum{method=\"GET\"} 2.6\n" +
+				"# EOF\n",
+		},
+		{
+			name: "Exemplars",
+			families: []*dto.MetricFamily{
+				{
+					Name: proto.String("duration_seconds"),
+					Type: dto.MetricType_HISTOGRAM.Enum(),
+					Metric: []*dto.Metric{
+						{
+							Histogram: &dto.Histogram{
+								SampleCount: proto.Uint64(1),
+								SampleSum:   proto.Float64(0.42),
+								Bucket: []*dto.Bucket{
+									{
+										UpperBound:      proto.Float64(0.5),
+										CumulativeCount: proto.Uint64(1),
+										Exemplar: &dto.Exemplar{
+											Label:     []*dto.LabelPair{{Name: proto.String("trace_id"), Value: proto.String("abc")}},
+											Value:     proto.Float64(0.42),
+											Timestamp: &timestamp.Timestamp{Seconds: 1500000000, Nanos: 500000000},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedOutput: "# TYPE duration_seconds histogram\n" +
+				"duration_seconds_bucket{le=\"0.5\"} 1 # {trace_id=\"abc\"} 0.42 1500000000.5\n" +
+				"duration_seconds_bucket{le=\"+Inf\"} 1\n" +
+				"duration_seconds_count 1\n" +
+				"duration_seconds_sum 0.42\n" +
+				"# EOF\n",
+		},
+		{
+			name: "Summary",
+			families: []*dto.MetricFamily{
+				{
+					Name: proto.String("duration_seconds"),
+					Type: dto.MetricType_SUMMARY.Enum(),
+					Metric: []*dto.Metric{
+						{
+							Summary: &dto.Summary{
+								SampleCount: proto.Uint64(4),
+								SampleSum:   proto.Float64(1.2),
+								Quantile: []*dto.Quantile{
+									{Quantile: proto.Float64(0.5), Value: proto.Float64(0.2)},
+									{Quantile: proto.Float64(0.99), Value: proto.Float64(0.6)},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedOutput: "# TYPE duration_seconds summary\n" +
+				"duration_seconds{quantile=\"0.5\"} 0.2\n" +
+				"duration_seconds{quantile=\"0.99\"} 0.6\n" +
+				"duration_seconds_count 4\n" +
+				"duration_seconds_sum 1.2\n" +
+				"# EOF\n",
+		},
+		{
+			name: "Untyped",
+			families: []*dto.MetricFamily{
+				{
+					Name: proto.String("value"),
+					Type: dto.MetricType_UNTYPED.Enum(),
+					Metric: []*dto.Metric{
+						{Untyped: &dto.Untyped{Value: proto.Float64(math.NaN())}},
+					},
+				},
+			},
+			expectedOutput: "# TYPE value unknown\n" +
+				"value NaN\n" +
+				"# EOF\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			err := writeOpenMetrics(buf, tc.families)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedOutput, buf.String())
+		})
+	}
+}
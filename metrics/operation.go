@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+const (
+	outcomeLabel   = "outcome"
+	outcomeSuccess = "success"
+	outcomeError   = "error"
+)
+
+// Operation creates the RED metrics for an operation or returns the existing ones
+//   {name}_in_flight is a gauge for the number of in-flight operations
+//   {name}_total is a counter for the total number of operations with an additional outcome label (success or error)
+//   {name}_duration_seconds is a histogram for the duration of operations with an additional outcome label (success or error)
+// It panics if any of the metrics is already registered with a different definition
+func (f *Factory) Operation(name string, labels []string, options ...HistogramOptions) *OperationMetrics {
+	outcomeLabels := append(append([]string{}, labels...), outcomeLabel)
+
+	return &OperationMetrics{
+		OpGauge:       f.LimitedGauge(name+"_in_flight", "gauge metric for number of in-flight "+name+" operations", labels),
+		OpCounter:     f.LimitedCounter(name+"_total", "counter metric for total number of "+name+" operations", outcomeLabels),
+		OpLatencyHist: f.LimitedHistogram(name+"_duration_seconds", "histogram metric for duration of "+name+" operations in seconds", outcomeLabels, options...),
+	}
+}
+
+// Track starts tracking an operation and returns a function for finishing it
+// The function should be called with the error returned by the operation (nil for success).
+// The trace id of the active span in context is attached to the duration observation as an exemplar.
+func (m *OperationMetrics) Track(ctx context.Context, labelValues ...string) func(err error) {
+	m.OpGauge.WithLabelValues(labelValues...).Inc()
+	start := time.Now()
+
+	return func(err error) {
+		duration := time.Since(start).Seconds()
+
+		outcome := outcomeSuccess
+		if err != nil {
+			outcome = outcomeError
+		}
+
+		outcomeValues := append(append([]string{}, labelValues...), outcome)
+
+		m.OpGauge.WithLabelValues(labelValues...).Dec()
+		m.OpCounter.WithLabelValues(outcomeValues...).Inc()
+		ObserveWithTraceID(m.OpLatencyHist.WithLabelValues(outcomeValues...), duration, opentracing.SpanFromContext(ctx))
+	}
+}
@@ -0,0 +1,116 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFactoryOperation(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	mf := NewFactory(FactoryOptions{
+		Prefix:     "service",
+		Registerer: registry,
+	})
+
+	op := mf.Operation("db_query", []string{"table"})
+	assert.NotNil(t, op.OpGauge)
+	assert.NotNil(t, op.OpCounter)
+	assert.NotNil(t, op.OpLatencyHist)
+
+	// Creating the same operation again
+	again := mf.Operation("db_query", []string{"table"})
+	assert.True(t, op.OpGauge == again.OpGauge)
+	assert.True(t, op.OpCounter == again.OpCounter)
+	assert.True(t, op.OpLatencyHist == again.OpLatencyHist)
+
+	op.Track(context.Background(), "users")(nil)
+
+	metricFamilies, err := registry.Gather()
+	assert.NoError(t, err)
+
+	names := map[string]bool{}
+	for _, metricFamily := range metricFamilies {
+		names[metricFamily.GetName()] = true
+	}
+
+	assert.True(t, names["service_db_query_in_flight"])
+	assert.True(t, names["service_db_query_total"])
+	assert.True(t, names["service_db_query_duration_seconds"])
+}
+
+func TestOperationMetricsTrack(t *testing.T) {
+	span, closer := newJaegerSpan()
+	defer closer.Close()
+	traceID, _ := TraceID(span)
+
+	tests := []struct {
+		name             string
+		ctx              context.Context
+		err              error
+		expectedOutcome  string
+		expectedExemplar bool
+	}{
+		{
+			name:             "Success",
+			ctx:              context.Background(),
+			err:              nil,
+			expectedOutcome:  "success",
+			expectedExemplar: false,
+		},
+		{
+			name:             "Error",
+			ctx:              context.Background(),
+			err:              errors.New("connection refused"),
+			expectedOutcome:  "error",
+			expectedExemplar: false,
+		},
+		{
+			name:             "WithSpan",
+			ctx:              opentracing.ContextWithSpan(context.Background(), span),
+			err:              nil,
+			expectedOutcome:  "success",
+			expectedExemplar: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mf := NewFactory(FactoryOptions{Registerer: prometheus.NewRegistry()})
+			op := mf.Operation("job", []string{"name"}, HistogramOptions{Buckets: []float64{1}})
+
+			done := op.Track(tc.ctx, "backup")
+
+			gauge := new(dto.Metric)
+			assert.NoError(t, op.OpGauge.WithLabelValues("backup").Write(gauge))
+			assert.Equal(t, 1.0, gauge.GetGauge().GetValue())
+
+			done(tc.err)
+
+			assert.NoError(t, op.OpGauge.WithLabelValues("backup").Write(gauge))
+			assert.Equal(t, 0.0, gauge.GetGauge().GetValue())
+
+			counter := new(dto.Metric)
+			assert.NoError(t, op.OpCounter.WithLabelValues("backup", tc.expectedOutcome).Write(counter))
+			assert.Equal(t, 1.0, counter.GetCounter().GetValue())
+
+			histogram := new(dto.Metric)
+			observer := op.OpLatencyHist.WithLabelValues("backup", tc.expectedOutcome).(prometheus.Metric)
+			assert.NoError(t, observer.Write(histogram))
+			assert.Equal(t, uint64(1), histogram.GetHistogram().GetSampleCount())
+
+			exemplar := histogram.GetHistogram().Bucket[0].GetExemplar()
+			if tc.expectedExemplar {
+				assert.NotNil(t, exemplar)
+				assert.Equal(t, traceID, exemplar.Label[0].GetValue())
+			} else {
+				assert.Nil(t, exemplar)
+			}
+		})
+	}
+}
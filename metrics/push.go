@@ -0,0 +1,130 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+const defaultPushInterval = 10 * time.Second
+
+type (
+	// Exporter exports gathered metrics to a remote target
+	Exporter interface {
+		Export(ctx context.Context, families []*dto.MetricFamily) error
+	}
+
+	// PusherOptions contains optional options for creating a Pusher
+	PusherOptions struct {
+		// Interval is the duration between two consecutive pushes (default 10s)
+		Interval time.Duration
+		// OnError is called with errors from pushes on interval
+		OnError func(error)
+	}
+
+	// Pusher pushes the metrics registered with a factory to exporters on an interval and on shutdown
+	// It is meant for short-lived processes (i.e. batch jobs) that may not live long enough to be scraped.
+	Pusher struct {
+		sync.Mutex
+		factory   *Factory
+		exporters []Exporter
+		interval  time.Duration
+		onError   func(error)
+		stop      chan struct{}
+		done      chan struct{}
+	}
+)
+
+// NewPusher creates a new Pusher
+func NewPusher(f *Factory, opts PusherOptions, exporters ...Exporter) *Pusher {
+	if opts.Interval <= 0 {
+		opts.Interval = defaultPushInterval
+	}
+
+	if opts.OnError == nil {
+		opts.OnError = func(error) {}
+	}
+
+	return &Pusher{
+		factory:   f,
+		exporters: exporters,
+		interval:  opts.Interval,
+		onError:   opts.OnError,
+	}
+}
+
+// Push gathers the metrics and pushes them to all exporters
+func (p *Pusher) Push(ctx context.Context) error {
+	families, err := p.factory.gatherer().Gather()
+	if err != nil {
+		return err
+	}
+
+	var msgs []string
+	for _, e := range p.exporters {
+		if err := e.Export(ctx, families); err != nil {
+			msgs = append(msgs, err.Error())
+		}
+	}
+
+	if len(msgs) > 0 {
+		return fmt.Errorf("error pushing metrics: %s", strings.Join(msgs, "; "))
+	}
+
+	return nil
+}
+
+// Start starts pushing metrics on interval in the background
+func (p *Pusher) Start() {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.stop != nil {
+		return
+	}
+
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+
+	go func(stop, done chan struct{}) {
+		defer close(done)
+
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), p.interval)
+				if err := p.Push(ctx); err != nil {
+					p.onError(err)
+				}
+				cancel()
+			case <-stop:
+				return
+			}
+		}
+	}(p.stop, p.done)
+}
+
+// Stop stops pushing metrics on interval and pushes the metrics one last time
+func (p *Pusher) Stop(ctx context.Context) error {
+	p.Lock()
+	stop, done := p.stop, p.done
+	p.stop, p.done = nil, nil
+	p.Unlock()
+
+	if stop == nil {
+		return errors.New("pusher is not started")
+	}
+
+	close(stop)
+	<-done
+
+	return p.Push(ctx)
+}
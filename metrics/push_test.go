@@ -0,0 +1,137 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockExporter struct {
+	sync.Mutex
+	ExportOutError error
+	ExportCount    int
+	ExportInNames  []string
+}
+
+func (m *mockExporter) Export(ctx context.Context, families []*dto.MetricFamily) error {
+	m.Lock()
+	defer m.Unlock()
+
+	m.ExportCount++
+	m.ExportInNames = nil
+	for _, family := range families {
+		m.ExportInNames = append(m.ExportInNames, family.GetName())
+	}
+
+	return m.ExportOutError
+}
+
+func (m *mockExporter) count() int {
+	m.Lock()
+	defer m.Unlock()
+	return m.ExportCount
+}
+
+func TestNewPusher(t *testing.T) {
+	mf := NewFactory(FactoryOptions{Registerer: prometheus.NewRegistry()})
+	p := NewPusher(mf, PusherOptions{})
+
+	assert.Equal(t, defaultPushInterval, p.interval)
+	assert.NotNil(t, p.onError)
+	assert.Empty(t, p.exporters)
+}
+
+func TestPusherPush(t *testing.T) {
+	mf := NewFactory(FactoryOptions{Registerer: prometheus.NewRegistry()})
+	mf.Counter("jobs_total", "total jobs", nil).WithLabelValues().Inc()
+
+	tests := []struct {
+		name          string
+		exporters     []*mockExporter
+		expectedError string
+	}{
+		{
+			name: "Success",
+			exporters: []*mockExporter{
+				{},
+				{},
+			},
+		},
+		{
+			name: "Failure",
+			exporters: []*mockExporter{
+				{ExportOutError: errors.New("connection refused")},
+				{},
+				{ExportOutError: errors.New("bad request")},
+			},
+			expectedError: "error pushing metrics: connection refused; bad request",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			exporters := make([]Exporter, len(tc.exporters))
+			for i, e := range tc.exporters {
+				exporters[i] = e
+			}
+
+			p := NewPusher(mf, PusherOptions{}, exporters...)
+			err := p.Push(context.Background())
+
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.expectedError)
+			}
+
+			for _, e := range tc.exporters {
+				assert.Equal(t, 1, e.ExportCount)
+				assert.Contains(t, e.ExportInNames, "jobs_total")
+			}
+		})
+	}
+}
+
+func TestPusherStartStop(t *testing.T) {
+	mf := NewFactory(FactoryOptions{Registerer: prometheus.NewRegistry()})
+	exporter := &mockExporter{ExportOutError: errors.New("connection refused")}
+
+	var mu sync.Mutex
+	var errs []error
+
+	p := NewPusher(mf, PusherOptions{
+		Interval: 10 * time.Millisecond,
+		OnError: func(err error) {
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+		},
+	}, exporter)
+
+	err := p.Stop(context.Background())
+	assert.EqualError(t, err, "pusher is not started")
+
+	p.Start()
+	p.Start()
+	time.Sleep(50 * time.Millisecond)
+
+	err = p.Stop(context.Background())
+	assert.EqualError(t, err, "error pushing metrics: connection refused")
+
+	count := exporter.count()
+	assert.True(t, count >= 2)
+
+	mu.Lock()
+	assert.Len(t, errs, count-1)
+	mu.Unlock()
+
+	// No more pushes after stop
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, count, exporter.count())
+}
@@ -0,0 +1,116 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+type (
+	// PushgatewayOptions contains options for creating a PushgatewayExporter
+	PushgatewayOptions struct {
+		// URL is the address of Pushgateway (i.e. http://pushgateway:9091)
+		URL string
+		// Job is the value of the job grouping label
+		Job string
+		// Grouping contains additional grouping labels (i.e. instance)
+		Grouping map[string]string
+		// Client is the http client for pushing metrics (default with a 10s timeout)
+		Client *http.Client
+	}
+
+	// PushgatewayExporter exports metrics to a Pushgateway-compatible http endpoint
+	// Every export replaces all metrics of the same group.
+	PushgatewayExporter struct {
+		url    string
+		client *http.Client
+	}
+)
+
+// NewPushgatewayExporter creates a new PushgatewayExporter
+func NewPushgatewayExporter(opts PushgatewayOptions) (*PushgatewayExporter, error) {
+	if opts.URL == "" {
+		return nil, errors.New("pushgateway url is required")
+	}
+
+	if opts.Job == "" {
+		return nil, errors.New("pushgateway job is required")
+	}
+
+	if opts.Client == nil {
+		opts.Client = &http.Client{
+			Timeout: 10 * time.Second,
+		}
+	}
+
+	keys := make([]string, 0, len(opts.Grouping))
+	for k := range opts.Grouping {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	path := "/metrics" + groupingPath("job", opts.Job)
+	for _, k := range keys {
+		path += groupingPath(k, opts.Grouping[k])
+	}
+
+	return &PushgatewayExporter{
+		url:    strings.TrimSuffix(opts.URL, "/") + path,
+		client: opts.Client,
+	}, nil
+}
+
+// groupingPath creates the url path for a grouping label
+// Empty values and values containing a slash are encoded in base64 as Pushgateway requires.
+func groupingPath(name, value string) string {
+	switch {
+	case value == "":
+		return "/" + url.PathEscape(name) + "@base64/="
+	case strings.Contains(value, "/"):
+		return "/" + url.PathEscape(name) + "@base64/" + base64.RawURLEncoding.EncodeToString([]byte(value))
+	default:
+		return "/" + url.PathEscape(name) + "/" + url.PathEscape(value)
+	}
+}
+
+// Export implements the Exporter interface
+func (e *PushgatewayExporter) Export(ctx context.Context, families []*dto.MetricFamily) error {
+	buf := new(bytes.Buffer)
+	for _, family := range families {
+		if _, err := expfmt.MetricFamilyToText(buf, family); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest("PUT", e.url, buf)
+	if err != nil {
+		return err
+	}
+
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", string(expfmt.FmtText))
+
+	res, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		body, _ := ioutil.ReadAll(res.Body)
+		return fmt.Errorf("pushgateway %s responded with %d: %s", e.url, res.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return nil
+}
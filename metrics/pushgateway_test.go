@@ -0,0 +1,129 @@
+package metrics
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPushgatewayExporter(t *testing.T) {
+	tests := []struct {
+		name          string
+		opts          PushgatewayOptions
+		expectedError string
+		expectedURL   string
+	}{
+		{
+			name:          "NoURL",
+			opts:          PushgatewayOptions{Job: "backup"},
+			expectedError: "pushgateway url is required",
+		},
+		{
+			name:          "NoJob",
+			opts:          PushgatewayOptions{URL: "http://localhost:9091"},
+			expectedError: "pushgateway job is required",
+		},
+		{
+			name:        "Job",
+			opts:        PushgatewayOptions{URL: "http://localhost:9091/", Job: "backup"},
+			expectedURL: "http://localhost:9091/metrics/job/backup",
+		},
+		{
+			name: "Grouping",
+			opts: PushgatewayOptions{
+				URL:      "http://localhost:9091",
+				Job:      "db backup",
+				Grouping: map[string]string{"instance": "db-1", "az": "us-east-1a"},
+			},
+			expectedURL: "http://localhost:9091/metrics/job/db%20backup/az/us-east-1a/instance/db-1",
+		},
+		{
+			name: "Base64Grouping",
+			opts: PushgatewayOptions{
+				URL:      "http://localhost:9091",
+				Job:      "backup/daily",
+				Grouping: map[string]string{"path": "/var/lib", "instance": ""},
+			},
+			expectedURL: "http://localhost:9091/metrics/job@base64/YmFja3VwL2RhaWx5/instance@base64/=/path@base64/L3Zhci9saWI",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			e, err := NewPushgatewayExporter(tc.opts)
+
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				assert.Nil(t, e)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedURL, e.url)
+				assert.NotNil(t, e.client)
+			}
+		})
+	}
+}
+
+func TestPushgatewayExporter(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	mf := NewFactory(FactoryOptions{Registerer: registry})
+	mf.Counter("jobs_total", "total jobs", []string{"status"}).WithLabelValues("success").Add(3)
+
+	tests := []struct {
+		name          string
+		statusCode    int
+		expectedError string
+	}{
+		{
+			name:       "Success",
+			statusCode: http.StatusAccepted,
+		},
+		{
+			name:          "Failure",
+			statusCode:    http.StatusBadRequest,
+			expectedError: "responded with 400: invalid metric",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var method, path, contentType, body string
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				data, _ := ioutil.ReadAll(r.Body)
+				method, path, contentType, body = r.Method, r.URL.EscapedPath(), r.Header.Get("Content-Type"), string(data)
+
+				w.WriteHeader(tc.statusCode)
+				_, _ = w.Write([]byte("invalid metric\n"))
+			}))
+			defer ts.Close()
+
+			e, err := NewPushgatewayExporter(PushgatewayOptions{
+				URL:      ts.URL,
+				Job:      "backup",
+				Grouping: map[string]string{"instance": "db-1"},
+			})
+			assert.NoError(t, err)
+
+			p := NewPusher(mf, PusherOptions{}, e)
+			err = p.Push(context.Background())
+
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tc.expectedError)
+			}
+
+			assert.Equal(t, "PUT", method)
+			assert.Equal(t, "/metrics/job/backup/instance/db-1", path)
+			assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", contentType)
+			assert.Contains(t, body, "# TYPE jobs_total counter\n")
+			assert.Contains(t, body, "jobs_total{status=\"success\"} 3\n")
+		})
+	}
+}
@@ -0,0 +1,282 @@
+package metrics
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	schedLatencySamples     = 5
+	goroutineSampleInterval = 10 * time.Second
+	maxStackDumpSize        = 64 << 20
+)
+
+var (
+	schedLatencyBuckets = []float64{0.00001, 0.00005, 0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1}
+	gcPauseBuckets      = []float64{0.00001, 0.00005, 0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1}
+)
+
+// runtimeCollector collects Go runtime metrics not provided by the Go and process collectors
+type runtimeCollector struct {
+	sync.Mutex
+	lastNumGC        uint32
+	goroutines       map[string]int
+	goroutinesSample time.Time
+
+	schedLatency  prometheus.Histogram
+	gcPause       prometheus.Histogram
+	sizeMallocs   *prometheus.Desc
+	sizeFrees     *prometheus.Desc
+	sizeObjects   *prometheus.Desc
+	goroutineDesc *prometheus.Desc
+	fdsDesc       *prometheus.Desc
+	fdsUsageDesc  *prometheus.Desc
+	procPath      string
+}
+
+func newRuntimeCollector(f *Factory) *runtimeCollector {
+	return &runtimeCollector{
+		schedLatency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    f.getMetricName("runtime_sched_latency_seconds"),
+			Help:    "sampled latencies between creating a goroutine and running it",
+			Buckets: schedLatencyBuckets,
+		}),
+		gcPause: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    f.getMetricName("runtime_gc_pause_seconds"),
+			Help:    "stop-the-world pause durations of garbage collections",
+			Buckets: gcPauseBuckets,
+		}),
+		sizeMallocs: prometheus.NewDesc(
+			f.getMetricName("runtime_heap_size_class_mallocs_total"),
+			"total number of heap objects allocated per size class",
+			[]string{"size"}, nil,
+		),
+		sizeFrees: prometheus.NewDesc(
+			f.getMetricName("runtime_heap_size_class_frees_total"),
+			"total number of heap objects freed per size class",
+			[]string{"size"}, nil,
+		),
+		sizeObjects: prometheus.NewDesc(
+			f.getMetricName("runtime_heap_size_class_objects"),
+			"number of live heap objects per size class",
+			[]string{"size"}, nil,
+		),
+		goroutineDesc: prometheus.NewDesc(
+			f.getMetricName("runtime_goroutines"),
+			"number of goroutines per state from a sampled stack dump",
+			[]string{"state"}, nil,
+		),
+		fdsDesc: prometheus.NewDesc(
+			f.getMetricName("runtime_fds"),
+			"number of open file descriptors per type",
+			[]string{"type"}, nil,
+		),
+		fdsUsageDesc: prometheus.NewDesc(
+			f.getMetricName("runtime_fds_usage_ratio"),
+			"ratio of open file descriptors to the maximum number of file descriptors",
+			nil, nil,
+		),
+		procPath: "/proc/self",
+	}
+}
+
+// Describe implements prometheus.Collector interface
+func (c *runtimeCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.schedLatency.Describe(ch)
+	c.gcPause.Describe(ch)
+	ch <- c.sizeMallocs
+	ch <- c.sizeFrees
+	ch <- c.sizeObjects
+	ch <- c.goroutineDesc
+	ch <- c.fdsDesc
+	ch <- c.fdsUsageDesc
+}
+
+// Collect implements prometheus.Collector interface
+func (c *runtimeCollector) Collect(ch chan<- prometheus.Metric) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.sampleSchedLatency()
+	c.schedLatency.Collect(ch)
+
+	ms := new(runtime.MemStats)
+	runtime.ReadMemStats(ms)
+
+	c.observeGCPauses(ms)
+	c.gcPause.Collect(ch)
+
+	for _, s := range ms.BySize {
+		if s.Size == 0 {
+			continue
+		}
+
+		size := strconv.FormatUint(uint64(s.Size), 10)
+		ch <- prometheus.MustNewConstMetric(c.sizeMallocs, prometheus.CounterValue, float64(s.Mallocs), size)
+		ch <- prometheus.MustNewConstMetric(c.sizeFrees, prometheus.CounterValue, float64(s.Frees), size)
+		ch <- prometheus.MustNewConstMetric(c.sizeObjects, prometheus.GaugeValue, float64(s.Mallocs-s.Frees), size)
+	}
+
+	if time.Since(c.goroutinesSample) >= goroutineSampleInterval {
+		c.goroutines = c.sampleGoroutines()
+		c.goroutinesSample = time.Now()
+	}
+
+	for state, n := range c.goroutines {
+		ch <- prometheus.MustNewConstMetric(c.goroutineDesc, prometheus.GaugeValue, float64(n), state)
+	}
+
+	// File descriptors are only available on systems with procfs
+	if fds, err := c.readFDs(); err == nil {
+		total := 0
+		for typ, n := range fds {
+			total += n
+			ch <- prometheus.MustNewConstMetric(c.fdsDesc, prometheus.GaugeValue, float64(n), typ)
+		}
+
+		if max, err := c.readMaxFDs(); err == nil && max > 0 {
+			ch <- prometheus.MustNewConstMetric(c.fdsUsageDesc, prometheus.GaugeValue, float64(total)/float64(max))
+		}
+	}
+}
+
+// sampleSchedLatency measures how long it takes for new goroutines to start running
+func (c *runtimeCollector) sampleSchedLatency() {
+	done := make(chan time.Duration)
+	for i := 0; i < schedLatencySamples; i++ {
+		start := time.Now()
+		go func() {
+			done <- time.Since(start)
+		}()
+		c.schedLatency.Observe((<-done).Seconds())
+	}
+}
+
+// observeGCPauses observes the pauses of garbage collections since the last collection
+// The runtime only keeps the most recent 256 pauses.
+func (c *runtimeCollector) observeGCPauses(ms *runtime.MemStats) {
+	n := ms.NumGC - c.lastNumGC
+	if n > uint32(len(ms.PauseNs)) {
+		n = uint32(len(ms.PauseNs))
+	}
+
+	for i := uint32(0); i < n; i++ {
+		idx := (ms.NumGC - 1 - i) % uint32(len(ms.PauseNs))
+		c.gcPause.Observe(float64(ms.PauseNs[idx]) / 1e9)
+	}
+
+	c.lastNumGC = ms.NumGC
+}
+
+// sampleGoroutines counts goroutines per state from a stack dump of all goroutines
+func (c *runtimeCollector) sampleGoroutines() map[string]int {
+	buf := make([]byte, 1<<20)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) || len(buf) >= maxStackDumpSize {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+
+	return parseGoroutineStates(buf)
+}
+
+// parseGoroutineStates parses the headers of goroutines in a stack dump (i.e. goroutine 7 [chan receive, 2 minutes]:)
+func parseGoroutineStates(dump []byte) map[string]int {
+	states := map[string]int{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(dump))
+	scanner.Buffer(make([]byte, 64*1024), len(dump)+1)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "goroutine ") || !strings.HasSuffix(line, "]:") {
+			continue
+		}
+
+		i := strings.IndexByte(line, '[')
+		if i == -1 {
+			continue
+		}
+
+		state := line[i+1 : len(line)-2]
+		if j := strings.IndexByte(state, ','); j != -1 {
+			state = state[:j]
+		}
+
+		states[state]++
+	}
+
+	return states
+}
+
+// readFDs counts open file descriptors per type (file, socket, pipe, anon, or other)
+func (c *runtimeCollector) readFDs() (map[string]int, error) {
+	dir := filepath.Join(c.procPath, "fd")
+	d, err := os.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := d.Readdirnames(-1)
+	d.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	fds := map[string]int{}
+	for _, name := range names {
+		target, err := os.Readlink(filepath.Join(dir, name))
+		if err != nil {
+			// The file descriptor used for reading the directory is already closed
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(target, "/"):
+			fds["file"]++
+		case strings.HasPrefix(target, "socket:"):
+			fds["socket"]++
+		case strings.HasPrefix(target, "pipe:"):
+			fds["pipe"]++
+		case strings.HasPrefix(target, "anon_inode:"):
+			fds["anon"]++
+		default:
+			fds["other"]++
+		}
+	}
+
+	return fds, nil
+}
+
+// readMaxFDs reads the soft limit on the number of open file descriptors
+func (c *runtimeCollector) readMaxFDs() (int, error) {
+	data, err := ioutil.ReadFile(filepath.Join(c.procPath, "limits"))
+	if err != nil {
+		return 0, err
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "Max open files") {
+			fields := strings.Fields(strings.TrimPrefix(line, "Max open files"))
+			if len(fields) > 0 && fields[0] != "unlimited" {
+				return strconv.Atoi(fields[0])
+			}
+			return 0, nil
+		}
+	}
+
+	return 0, nil
+}
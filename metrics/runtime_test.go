@@ -0,0 +1,135 @@
+package metrics
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseGoroutineStates(t *testing.T) {
+	dump := []byte(`goroutine 1 [running]:
+main.main()
+	/app/main.go:10 +0x20
+
+goroutine 7 [chan receive, 2 minutes]:
+main.worker()
+	/app/main.go:20 +0x30
+
+goroutine 8 [chan receive]:
+main.worker()
+	/app/main.go:20 +0x30
+
+goroutine 9 [select (no cases)]:
+main.block()
+	/app/main.go:30 +0x40
+
+goroutine 10 [IO wait, locked to thread]:
+internal/poll.runtime_pollWait()
+`)
+
+	states := parseGoroutineStates(dump)
+	assert.Equal(t, map[string]int{
+		"running":           1,
+		"chan receive":      2,
+		"select (no cases)": 1,
+		"IO wait":           1,
+	}, states)
+}
+
+func TestRuntimeCollectorObserveGCPauses(t *testing.T) {
+	c := newRuntimeCollector(NewFactory(FactoryOptions{Registerer: prometheus.NewRegistry()}))
+
+	ms := new(runtime.MemStats)
+	ms.NumGC = 2
+	ms.PauseNs[0] = 1000
+	ms.PauseNs[1] = 2000
+
+	c.observeGCPauses(ms)
+	assert.Equal(t, uint32(2), c.lastNumGC)
+
+	// Only the most recent 256 pauses are available
+	ms.NumGC = 1000
+	c.observeGCPauses(ms)
+	assert.Equal(t, uint32(1000), c.lastNumGC)
+
+	metric := new(dto.Metric)
+	assert.NoError(t, c.gcPause.Write(metric))
+	assert.Equal(t, uint64(2+256), metric.Histogram.GetSampleCount())
+	assert.InDelta(t, 2*0.000003, metric.Histogram.GetSampleSum(), 1e-12)
+}
+
+func TestRuntimeCollectorFDs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "proc")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "fd"), 0755))
+	assert.NoError(t, os.Symlink("/dev/null", filepath.Join(dir, "fd", "0")))
+	assert.NoError(t, os.Symlink("/var/log/app.log", filepath.Join(dir, "fd", "1")))
+	assert.NoError(t, os.Symlink("socket:[1234]", filepath.Join(dir, "fd", "3")))
+	assert.NoError(t, os.Symlink("pipe:[5678]", filepath.Join(dir, "fd", "4")))
+	assert.NoError(t, os.Symlink("anon_inode:[eventpoll]", filepath.Join(dir, "fd", "5")))
+
+	limits := "Limit                     Soft Limit           Hard Limit           Units\n" +
+		"Max cpu time              unlimited            unlimited            seconds\n" +
+		"Max open files            1024                 4096                 files\n"
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "limits"), []byte(limits), 0644))
+
+	c := newRuntimeCollector(NewFactory(FactoryOptions{Registerer: prometheus.NewRegistry()}))
+	c.procPath = dir
+
+	fds, err := c.readFDs()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"file": 2, "socket": 1, "pipe": 1, "anon": 1}, fds)
+
+	max, err := c.readMaxFDs()
+	assert.NoError(t, err)
+	assert.Equal(t, 1024, max)
+}
+
+func TestFactoryRuntimeMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	NewFactory(FactoryOptions{
+		Prefix:         "service",
+		Registerer:     registry,
+		RuntimeMetrics: true,
+	})
+
+	// Registering runtime metrics more than once
+	NewFactory(FactoryOptions{
+		Prefix:         "service",
+		Registerer:     registry,
+		RuntimeMetrics: true,
+	})
+
+	metricFamilies, err := registry.Gather()
+	assert.NoError(t, err)
+
+	names := map[string]bool{}
+	for _, metricFamily := range metricFamilies {
+		names[metricFamily.GetName()] = true
+	}
+
+	expectedNames := []string{
+		"service_runtime_sched_latency_seconds",
+		"service_runtime_gc_pause_seconds",
+		"service_runtime_heap_size_class_mallocs_total",
+		"service_runtime_heap_size_class_frees_total",
+		"service_runtime_heap_size_class_objects",
+		"service_runtime_goroutines",
+	}
+
+	if runtime.GOOS == "linux" {
+		expectedNames = append(expectedNames, "service_runtime_fds", "service_runtime_fds_usage_ratio")
+	}
+
+	for _, name := range expectedNames {
+		assert.True(t, names[name], name)
+	}
+}
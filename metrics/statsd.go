@@ -0,0 +1,306 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	dto "github.com/prometheus/client_model/go"
+)
+
+const defaultStatsDPacketSize = 1432
+
+var statsdEscaper = strings.NewReplacer(":", "_", "|", "_", "@", "_", "#", "_", ",", "_", "\n", "_", " ", "_")
+
+type (
+	// StatsDOptions contains options for creating a StatsDExporter
+	StatsDOptions struct {
+		// Address is the UDP address of StatsD server (i.e. localhost:8125)
+		Address string
+		// Prefix is added to all metric names followed by a dot
+		Prefix string
+		// DogStatsD enables tags and DogStatsD metric types
+		DogStatsD bool
+		// MaxPacketSize is the maximum size of a UDP packet (default 1432)
+		MaxPacketSize int
+		// HistogramScale returns the factor the values of a histogram are multiplied by before being sent
+		// It is called with the name of histogram and by default returns 1000 for StatsD timers of histograms in seconds (*_seconds) and 1 otherwise.
+		HistogramScale func(name string) float64
+	}
+
+	// StatsDExporter exports metrics to a StatsD or DogStatsD server over UDP
+	//
+	// Metric types are translated as follows:
+	//   counters are sent as counters (c) with the increment since the last export
+	//   gauges and untyped metrics are sent as gauges (g)
+	//   histograms are sent as timers (ms) or DogStatsD histograms (h) with one sample per bucket,
+	//   where the sample is the upper bound of bucket and the sample rate accounts for the new observations in bucket
+	//   histogram values are multiplied by the histogram scale (i.e. seconds are converted to milliseconds for timers)
+	//   summary quantiles are sent as gauges (g) and summary counts are sent as counters (c)
+	//
+	// Labels are sent as tags for DogStatsD and appended to metric names for StatsD.
+	StatsDExporter struct {
+		sync.Mutex
+		conn           net.Conn
+		prefix         string
+		dogStatsD      bool
+		maxPacketSize  int
+		histogramScale func(string) float64
+		last           map[string]float64
+	}
+)
+
+// NewStatsDExporter creates a new StatsDExporter
+func NewStatsDExporter(opts StatsDOptions) (*StatsDExporter, error) {
+	if opts.Address == "" {
+		return nil, errors.New("statsd address is required")
+	}
+
+	if opts.MaxPacketSize <= 0 {
+		opts.MaxPacketSize = defaultStatsDPacketSize
+	}
+
+	if opts.HistogramScale == nil {
+		opts.HistogramScale = defaultStatsDHistogramScale
+		if opts.DogStatsD {
+			opts.HistogramScale = func(string) float64 { return 1 }
+		}
+	}
+
+	conn, err := net.Dial("udp", opts.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := opts.Prefix
+	if prefix != "" && !strings.HasSuffix(prefix, ".") {
+		prefix += "."
+	}
+
+	return &StatsDExporter{
+		conn:           conn,
+		prefix:         prefix,
+		dogStatsD:      opts.DogStatsD,
+		maxPacketSize:  opts.MaxPacketSize,
+		histogramScale: opts.HistogramScale,
+		last:           map[string]float64{},
+	}, nil
+}
+
+// defaultStatsDHistogramScale converts histograms in seconds to milliseconds for StatsD timers
+func defaultStatsDHistogramScale(name string) float64 {
+	if strings.HasSuffix(name, "_seconds") {
+		return 1000
+	}
+
+	return 1
+}
+
+// Close closes the UDP connection
+func (e *StatsDExporter) Close() error {
+	return e.conn.Close()
+}
+
+// Export implements the Exporter interface
+func (e *StatsDExporter) Export(ctx context.Context, families []*dto.MetricFamily) error {
+	e.Lock()
+	defer e.Unlock()
+
+	var lines []string
+	for _, family := range families {
+		for _, m := range family.Metric {
+			lines = append(lines, e.translate(family, m)...)
+		}
+	}
+
+	return e.send(ctx, lines)
+}
+
+// translate translates a Prometheus metric into StatsD lines
+func (e *StatsDExporter) translate(family *dto.MetricFamily, m *dto.Metric) []string {
+	name := family.GetName()
+	labels := make([]*dto.LabelPair, len(m.Label))
+	copy(labels, m.Label)
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].GetName() < labels[j].GetName()
+	})
+
+	var lines []string
+
+	switch family.GetType() {
+	case dto.MetricType_COUNTER:
+		if delta := e.delta(seriesKey(name, labels), m.GetCounter().GetValue()); delta != 0 {
+			lines = append(lines, e.line(name, labels, nil, delta, "c", 1))
+		}
+
+	case dto.MetricType_GAUGE:
+		lines = append(lines, e.gauge(name, labels, nil, m.GetGauge().GetValue())...)
+
+	case dto.MetricType_HISTOGRAM:
+		typ, scale := "ms", e.histogramScale(name)
+		if e.dogStatsD {
+			typ = "h"
+		}
+
+		h := m.GetHistogram()
+		key := seriesKey(name, labels)
+		var prevCount, upperBound float64
+
+		for _, b := range h.Bucket {
+			if !math.IsInf(b.GetUpperBound(), +1) {
+				upperBound = b.GetUpperBound()
+			}
+
+			count := e.delta(key+"le="+formatOpenMetricsFloat(b.GetUpperBound()), float64(b.GetCumulativeCount()))
+			if n := count - prevCount; n > 0 {
+				lines = append(lines, e.line(name, labels, nil, upperBound*scale, typ, 1/n))
+			}
+			prevCount = count
+		}
+
+		// Observations greater than the largest upper bound
+		count := e.delta(key+"count", float64(h.GetSampleCount()))
+		if n := count - prevCount; n > 0 {
+			lines = append(lines, e.line(name, labels, nil, upperBound*scale, typ, 1/n))
+		}
+
+	case dto.MetricType_SUMMARY:
+		s := m.GetSummary()
+		for _, q := range s.Quantile {
+			quantile := &dto.LabelPair{Name: proto.String("quantile"), Value: proto.String(formatOpenMetricsLabelFloat(q.GetQuantile()))}
+			lines = append(lines, e.gauge(name, labels, quantile, q.GetValue())...)
+		}
+
+		if delta := e.delta(seriesKey(name, labels)+"count", float64(s.GetSampleCount())); delta != 0 {
+			lines = append(lines, e.line(name+"_count", labels, nil, delta, "c", 1))
+		}
+
+	default:
+		lines = append(lines, e.gauge(name, labels, nil, m.GetUntyped().GetValue())...)
+	}
+
+	return lines
+}
+
+// delta returns the increment of a cumulative value since the last export
+func (e *StatsDExporter) delta(key string, value float64) float64 {
+	last, ok := e.last[key]
+	e.last[key] = value
+
+	// The value has been reset (i.e. process restart)
+	if !ok || value < last {
+		return value
+	}
+
+	return value - last
+}
+
+// gauge creates the lines for setting a gauge
+// A negative value is sent as zero followed by a decrement, since a sign means a change in StatsD.
+func (e *StatsDExporter) gauge(name string, labels []*dto.LabelPair, extra *dto.LabelPair, value float64) []string {
+	if value < 0 {
+		return []string{
+			e.line(name, labels, extra, 0, "g", 1),
+			e.line(name, labels, extra, value, "g", 1),
+		}
+	}
+
+	return []string{
+		e.line(name, labels, extra, value, "g", 1),
+	}
+}
+
+func (e *StatsDExporter) line(name string, labels []*dto.LabelPair, extra *dto.LabelPair, value float64, typ string, rate float64) string {
+	if extra != nil {
+		labels = append(labels[:len(labels):len(labels)], extra)
+	}
+
+	var b strings.Builder
+	b.WriteString(e.prefix)
+	b.WriteString(statsdEscaper.Replace(name))
+
+	if !e.dogStatsD {
+		for _, l := range labels {
+			b.WriteString(".")
+			b.WriteString(statsdEscaper.Replace(strings.Replace(l.GetValue(), ".", "_", -1)))
+		}
+	}
+
+	b.WriteString(":")
+	b.WriteString(strconv.FormatFloat(value, 'f', -1, 64))
+	b.WriteString("|")
+	b.WriteString(typ)
+
+	if rate < 1 {
+		b.WriteString("|@")
+		b.WriteString(strconv.FormatFloat(rate, 'f', -1, 64))
+	}
+
+	if e.dogStatsD && len(labels) > 0 {
+		b.WriteString("|#")
+		for i, l := range labels {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			b.WriteString(statsdEscaper.Replace(l.GetName()))
+			b.WriteString(":")
+			b.WriteString(statsdEscaper.Replace(l.GetValue()))
+		}
+	}
+
+	return b.String()
+}
+
+// send sends lines in as few packets as possible
+func (e *StatsDExporter) send(ctx context.Context, lines []string) error {
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := e.conn.SetWriteDeadline(deadline); err != nil {
+			return err
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	for _, line := range lines {
+		if buf.Len() > 0 && buf.Len()+1+len(line) > e.maxPacketSize {
+			if _, err := e.conn.Write(buf.Bytes()); err != nil {
+				return err
+			}
+			buf.Reset()
+		}
+
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(line)
+	}
+
+	if buf.Len() > 0 {
+		if _, err := e.conn.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// seriesKey creates a unique key for a series from its name and sorted labels
+func seriesKey(name string, labels []*dto.LabelPair) string {
+	var b strings.Builder
+	b.WriteString(name)
+	for _, l := range labels {
+		b.WriteString("\xff")
+		b.WriteString(l.GetName())
+		b.WriteString("=")
+		b.WriteString(l.GetValue())
+	}
+	b.WriteString("\xff")
+
+	return b.String()
+}
@@ -0,0 +1,211 @@
+package metrics
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewStatsDExporter(t *testing.T) {
+	tests := []struct {
+		name           string
+		opts           StatsDOptions
+		expectedError  string
+		expectedPrefix string
+	}{
+		{
+			name:          "NoAddress",
+			opts:          StatsDOptions{},
+			expectedError: "statsd address is required",
+		},
+		{
+			name:           "Defaults",
+			opts:           StatsDOptions{Address: "127.0.0.1:8125"},
+			expectedPrefix: "",
+		},
+		{
+			name:           "Prefix",
+			opts:           StatsDOptions{Address: "127.0.0.1:8125", Prefix: "batch"},
+			expectedPrefix: "batch.",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			e, err := NewStatsDExporter(tc.opts)
+
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				assert.Nil(t, e)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedPrefix, e.prefix)
+				assert.Equal(t, defaultStatsDPacketSize, e.maxPacketSize)
+				assert.NoError(t, e.Close())
+			}
+		})
+	}
+}
+
+func TestStatsDExporter(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	// read reads the lines sent for test metrics and skips Go and process metrics
+	read := func() []string {
+		var lines []string
+		buf := make([]byte, 65536)
+		for {
+			_ = conn.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+			n, _, err := conn.ReadFrom(buf)
+			if err != nil {
+				return lines
+			}
+			for _, line := range strings.Split(string(buf[:n]), "\n") {
+				if strings.Contains(line, "job") || strings.Contains(line, "queue") {
+					lines = append(lines, line)
+				}
+			}
+		}
+	}
+
+	tests := []struct {
+		name          string
+		opts          StatsDOptions
+		expectedFirst []string
+		expectedNext  []string
+	}{
+		{
+			name: "StatsD",
+			opts: StatsDOptions{Address: conn.LocalAddr().String(), Prefix: "batch"},
+			expectedFirst: []string{
+				"batch.jobs_total.success:3|c",
+				"batch.queue_size:0|g",
+				"batch.queue_size:-2|g",
+				"batch.job_duration_seconds:500|ms|@0.5",
+				"batch.job_duration_seconds:1000|ms",
+				"batch.job_duration_seconds:1000|ms",
+				"batch.job_latency_seconds.0_5:0.4|g",
+				"batch.job_latency_seconds_count:1|c",
+			},
+			expectedNext: []string{
+				"batch.jobs_total.success:1|c",
+				"batch.queue_size:0|g",
+				"batch.queue_size:-2|g",
+				"batch.job_duration_seconds:1000|ms",
+				"batch.job_latency_seconds.0_5:0.4|g",
+			},
+		},
+		{
+			name: "DogStatsD",
+			opts: StatsDOptions{Address: conn.LocalAddr().String(), DogStatsD: true},
+			expectedFirst: []string{
+				"jobs_total:3|c|#status:success",
+				"queue_size:0|g",
+				"queue_size:-2|g",
+				"job_duration_seconds:0.5|h|@0.5",
+				"job_duration_seconds:1|h",
+				"job_duration_seconds:1|h",
+				"job_latency_seconds:0.4|g|#quantile:0.5",
+				"job_latency_seconds_count:1|c",
+			},
+			expectedNext: []string{
+				"jobs_total:1|c|#status:success",
+				"queue_size:0|g",
+				"queue_size:-2|g",
+				"job_duration_seconds:1|h",
+				"job_latency_seconds:0.4|g|#quantile:0.5",
+			},
+		},
+		{
+			name: "HistogramScale",
+			opts: StatsDOptions{Address: conn.LocalAddr().String(), HistogramScale: func(string) float64 { return 10 }},
+			expectedFirst: []string{
+				"jobs_total.success:3|c",
+				"queue_size:0|g",
+				"queue_size:-2|g",
+				"job_duration_seconds:5|ms|@0.5",
+				"job_duration_seconds:10|ms",
+				"job_duration_seconds:10|ms",
+				"job_latency_seconds.0_5:0.4|g",
+				"job_latency_seconds_count:1|c",
+			},
+			expectedNext: []string{
+				"jobs_total.success:1|c",
+				"queue_size:0|g",
+				"queue_size:-2|g",
+				"job_duration_seconds:10|ms",
+				"job_latency_seconds.0_5:0.4|g",
+			},
+		},
+		{
+			name: "SmallPackets",
+			opts: StatsDOptions{Address: conn.LocalAddr().String(), DogStatsD: true, MaxPacketSize: 1},
+			expectedFirst: []string{
+				"jobs_total:3|c|#status:success",
+				"queue_size:0|g",
+				"queue_size:-2|g",
+				"job_duration_seconds:0.5|h|@0.5",
+				"job_duration_seconds:1|h",
+				"job_duration_seconds:1|h",
+				"job_latency_seconds:0.4|g|#quantile:0.5",
+				"job_latency_seconds_count:1|c",
+			},
+			expectedNext: []string{
+				"jobs_total:1|c|#status:success",
+				"queue_size:0|g",
+				"queue_size:-2|g",
+				"job_duration_seconds:1|h",
+				"job_latency_seconds:0.4|g|#quantile:0.5",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mf := NewFactory(FactoryOptions{Registerer: prometheus.NewRegistry()})
+			counter := mf.Counter("jobs_total", "total jobs", []string{"status"})
+			gauge := mf.Gauge("queue_size", "queue size", nil)
+			histogram := mf.Histogram("job_duration_seconds", "job durations", nil, HistogramOptions{Buckets: []float64{0.5, 1}})
+			summary := mf.Summary("job_latency_seconds", "job latencies", nil, SummaryOptions{Quantiles: map[float64]float64{0.5: 0.05}})
+
+			counter.WithLabelValues("success").Add(3)
+			gauge.WithLabelValues().Set(-2)
+			histogram.WithLabelValues().Observe(0.1)
+			histogram.WithLabelValues().Observe(0.2)
+			histogram.WithLabelValues().Observe(0.8)
+			histogram.WithLabelValues().Observe(5)
+			summary.WithLabelValues().Observe(0.4)
+
+			e, err := NewStatsDExporter(tc.opts)
+			assert.NoError(t, err)
+			defer e.Close()
+
+			families, err := mf.gatherer().Gather()
+			assert.NoError(t, err)
+			err = e.Export(context.Background(), families)
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, tc.expectedFirst, read())
+
+			counter.WithLabelValues("success").Inc()
+			histogram.WithLabelValues().Observe(0.9)
+
+			families, err = mf.gatherer().Gather()
+			assert.NoError(t, err)
+			err = e.Export(context.Background(), families)
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, tc.expectedNext, read())
+		})
+	}
+}
+
+func TestDefaultStatsDHistogramScale(t *testing.T) {
+	assert.Equal(t, 1000.0, defaultStatsDHistogramScale("request_duration_seconds"))
+	assert.Equal(t, 1.0, defaultStatsDHistogramScale("response_size_bytes"))
+}
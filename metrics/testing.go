@@ -0,0 +1,184 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+type (
+	// TestingT is the interface implemented by *testing.T and *testing.B
+	TestingT interface {
+		Errorf(format string, args ...interface{})
+	}
+
+	// TestFactory is a Factory backed by an isolated registry for testing
+	// Metrics are looked up by their full names (including prefix and subsystem).
+	// Labels match all series having the given labels (other labels are ignored) and the values of all matching series are added up.
+	TestFactory struct {
+		*Factory
+		Registry *prometheus.Registry
+	}
+)
+
+// NewTestFactory creates a new Factory backed by an isolated registry for testing
+func NewTestFactory() *TestFactory {
+	registry := prometheus.NewRegistry()
+
+	return &TestFactory{
+		Factory: &Factory{
+			buckets:    defaultBuckets,
+			quantiles:  defaultQuantiles,
+			registerer: registry,
+			cache:      newCache(),
+		},
+		Registry: registry,
+	}
+}
+
+// series returns all series of a metric having the given labels
+func (f *TestFactory) series(name string, labels prometheus.Labels) (dto.MetricType, []*dto.Metric, bool) {
+	families, err := f.Registry.Gather()
+	if err != nil {
+		return 0, nil, false
+	}
+
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+
+		var metrics []*dto.Metric
+		for _, m := range family.Metric {
+			if matchLabels(m, labels) {
+				metrics = append(metrics, m)
+			}
+		}
+
+		return family.GetType(), metrics, len(metrics) > 0
+	}
+
+	return 0, nil, false
+}
+
+func matchLabels(m *dto.Metric, labels prometheus.Labels) bool {
+	matched := 0
+	for _, l := range m.Label {
+		if v, ok := labels[l.GetName()]; ok {
+			if v != l.GetValue() {
+				return false
+			}
+			matched++
+		}
+	}
+
+	return matched == len(labels)
+}
+
+// CounterValue returns the value of a counter metric
+func (f *TestFactory) CounterValue(name string, labels prometheus.Labels) float64 {
+	_, metrics, _ := f.series(name, labels)
+
+	var value float64
+	for _, m := range metrics {
+		value += m.GetCounter().GetValue()
+	}
+
+	return value
+}
+
+// GaugeValue returns the value of a gauge metric
+func (f *TestFactory) GaugeValue(name string, labels prometheus.Labels) float64 {
+	_, metrics, _ := f.series(name, labels)
+
+	var value float64
+	for _, m := range metrics {
+		value += m.GetGauge().GetValue()
+	}
+
+	return value
+}
+
+// HistogramCount returns the number of observations of a histogram metric
+func (f *TestFactory) HistogramCount(name string, labels prometheus.Labels) uint64 {
+	_, metrics, _ := f.series(name, labels)
+
+	var count uint64
+	for _, m := range metrics {
+		count += m.GetHistogram().GetSampleCount()
+	}
+
+	return count
+}
+
+// HistogramSum returns the sum of observations of a histogram metric
+func (f *TestFactory) HistogramSum(name string, labels prometheus.Labels) float64 {
+	_, metrics, _ := f.series(name, labels)
+
+	var sum float64
+	for _, m := range metrics {
+		sum += m.GetHistogram().GetSampleSum()
+	}
+
+	return sum
+}
+
+// SummaryCount returns the number of observations of a summary metric
+func (f *TestFactory) SummaryCount(name string, labels prometheus.Labels) uint64 {
+	_, metrics, _ := f.series(name, labels)
+
+	var count uint64
+	for _, m := range metrics {
+		count += m.GetSummary().GetSampleCount()
+	}
+
+	return count
+}
+
+// SummarySum returns the sum of observations of a summary metric
+func (f *TestFactory) SummarySum(name string, labels prometheus.Labels) float64 {
+	_, metrics, _ := f.series(name, labels)
+
+	var sum float64
+	for _, m := range metrics {
+		sum += m.GetSummary().GetSampleSum()
+	}
+
+	return sum
+}
+
+// AssertMetric asserts the value of a metric
+// The value is compared with the value of counters and gauges and the number of observations of histograms and summaries.
+// It returns true if the assertion passes.
+func (f *TestFactory) AssertMetric(t TestingT, name string, labels prometheus.Labels, value float64) bool {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+
+	typ, _, ok := f.series(name, labels)
+	if !ok {
+		t.Errorf("metric %s with labels %v not found", name, labels)
+		return false
+	}
+
+	var actual float64
+	switch typ {
+	case dto.MetricType_COUNTER:
+		actual = f.CounterValue(name, labels)
+	case dto.MetricType_GAUGE:
+		actual = f.GaugeValue(name, labels)
+	case dto.MetricType_HISTOGRAM:
+		actual = float64(f.HistogramCount(name, labels))
+	case dto.MetricType_SUMMARY:
+		actual = float64(f.SummaryCount(name, labels))
+	default:
+		t.Errorf("metric %s has an unsupported type %s", name, typ)
+		return false
+	}
+
+	if actual != value {
+		t.Errorf("metric %s with labels %v: expected %v, actual %v", name, labels, value, actual)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockT struct {
+	errors []string
+}
+
+func (m *mockT) Errorf(format string, args ...interface{}) {
+	m.errors = append(m.errors, fmt.Sprintf(format, args...))
+}
+
+func TestNewTestFactory(t *testing.T) {
+	f1 := NewTestFactory()
+	f2 := NewTestFactory()
+
+	// Factories are isolated
+	f1.Counter("requests_total", "total requests", nil).WithLabelValues().Inc()
+	f2.Gauge("requests_total", "active requests", nil).WithLabelValues().Set(2)
+
+	assert.Equal(t, 1.0, f1.CounterValue("requests_total", nil))
+	assert.Equal(t, 2.0, f2.GaugeValue("requests_total", nil))
+}
+
+func TestTestFactory(t *testing.T) {
+	f := NewTestFactory()
+
+	counter := f.Counter("requests_total", "total requests", []string{"method", "statusCode"})
+	counter.WithLabelValues("GET", "200").Add(2)
+	counter.WithLabelValues("GET", "500").Inc()
+	counter.WithLabelValues("POST", "200").Inc()
+
+	gauge := f.Gauge("requests", "active requests", []string{"method"})
+	gauge.WithLabelValues("GET").Set(3)
+
+	histogram := f.Histogram("request_duration_seconds", "request durations", []string{"method"})
+	histogram.WithLabelValues("GET").Observe(0.2)
+	histogram.WithLabelValues("GET").Observe(0.3)
+
+	summary := f.Summary("request_duration_quantiles_seconds", "request durations", []string{"method"})
+	summary.WithLabelValues("POST").Observe(1.5)
+
+	t.Run("Values", func(t *testing.T) {
+		assert.Equal(t, 4.0, f.CounterValue("requests_total", nil))
+		assert.Equal(t, 3.0, f.CounterValue("requests_total", prometheus.Labels{"method": "GET"}))
+		assert.Equal(t, 2.0, f.CounterValue("requests_total", prometheus.Labels{"method": "GET", "statusCode": "200"}))
+		assert.Equal(t, 0.0, f.CounterValue("requests_total", prometheus.Labels{"method": "PUT"}))
+		assert.Equal(t, 0.0, f.CounterValue("unknown_total", nil))
+		assert.Equal(t, 3.0, f.GaugeValue("requests", prometheus.Labels{"method": "GET"}))
+		assert.Equal(t, uint64(2), f.HistogramCount("request_duration_seconds", prometheus.Labels{"method": "GET"}))
+		assert.Equal(t, 0.5, f.HistogramSum("request_duration_seconds", prometheus.Labels{"method": "GET"}))
+		assert.Equal(t, uint64(1), f.SummaryCount("request_duration_quantiles_seconds", nil))
+		assert.Equal(t, 1.5, f.SummarySum("request_duration_quantiles_seconds", nil))
+	})
+
+	tests := []struct {
+		name           string
+		metric         string
+		labels         prometheus.Labels
+		value          float64
+		expectedResult bool
+		expectedError  string
+	}{
+		{"Counter", "requests_total", prometheus.Labels{"statusCode": "200"}, 3, true, ""},
+		{"Gauge", "requests", prometheus.Labels{"method": "GET"}, 3, true, ""},
+		{"Histogram", "request_duration_seconds", prometheus.Labels{"method": "GET"}, 2, true, ""},
+		{"Summary", "request_duration_quantiles_seconds", prometheus.Labels{"method": "POST"}, 1, true, ""},
+		{"WrongValue", "requests_total", prometheus.Labels{"method": "POST"}, 2, false, "metric requests_total with labels map[method:POST]: expected 2, actual 1"},
+		{"NotFound", "requests_total", prometheus.Labels{"method": "PUT"}, 1, false, "metric requests_total with labels map[method:PUT] not found"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mt := new(mockT)
+			result := f.AssertMetric(mt, tc.metric, tc.labels, tc.value)
+
+			assert.Equal(t, tc.expectedResult, result)
+			if tc.expectedError == "" {
+				assert.Empty(t, mt.errors)
+			} else {
+				assert.Equal(t, []string{tc.expectedError}, mt.errors)
+			}
+		})
+	}
+}
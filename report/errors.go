@@ -0,0 +1,265 @@
+package report
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+)
+
+// maxCauses is the maximum number of errors followed in a cause chain for protecting against cycles
+const maxCauses = 32
+
+type (
+	// fieldsError wraps an error with key-value pairs of context
+	fieldsError struct {
+		err    error
+		fields map[string]interface{}
+	}
+
+	// callerError wraps an error with the stack trace of the site where it was reported
+	// It is used when an error is reported to another reporter from a different stack (i.e. by ThrottledReporter).
+	callerError struct {
+		err    error
+		frames []runtime.Frame
+	}
+
+	// causeError is one error in a cause chain
+	causeError struct {
+		Type    string
+		Message string
+		Frames  []runtime.Frame
+	}
+)
+
+// WithFields wraps an error with key-value pairs of context
+// The key-value pairs are attached to reported errors as metadata.
+// Keys are converted to strings and a missing value for the last key is set to nil.
+func WithFields(err error, kv ...interface{}) error {
+	if err == nil {
+		return nil
+	}
+
+	fields := make(map[string]interface{}, (len(kv)+1)/2)
+	for i := 0; i < len(kv); i += 2 {
+		var val interface{}
+		if i+1 < len(kv) {
+			val = kv[i+1]
+		}
+		fields[fmt.Sprint(kv[i])] = val
+	}
+
+	return &fieldsError{
+		err:    err,
+		fields: fields,
+	}
+}
+
+func (e *fieldsError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the wrapped error
+func (e *fieldsError) Unwrap() error {
+	return e.err
+}
+
+// Cause returns the wrapped error
+func (e *fieldsError) Cause() error {
+	return e.err
+}
+
+// withCallerFrames wraps an error with the stack trace of its reporting site
+// An error already wrapped with a stack trace of reporting site is returned as it is.
+func withCallerFrames(err error, frames []runtime.Frame) error {
+	if err == nil || callerFramesOf(err) != nil {
+		return err
+	}
+
+	return &callerError{
+		err:    err,
+		frames: frames,
+	}
+}
+
+func (e *callerError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the wrapped error
+func (e *callerError) Unwrap() error {
+	return e.err
+}
+
+// Cause returns the wrapped error
+func (e *callerError) Cause() error {
+	return e.err
+}
+
+// callerFramesOf returns the stack trace of reporting site attached to an error if it has one
+func callerFramesOf(err error) []runtime.Frame {
+	if ce, ok := err.(*callerError); ok {
+		return ce.frames
+	}
+
+	return nil
+}
+
+// withoutCallerFrames returns an error without the stack trace of reporting site attached to it
+func withoutCallerFrames(err error) error {
+	if ce, ok := err.(*callerError); ok {
+		return ce.err
+	}
+
+	return err
+}
+
+// Fields returns the key-value pairs attached to an error and its causes by WithFields
+// When a key is attached more than once, the outermost value is returned.
+func Fields(err error) map[string]interface{} {
+	fields := map[string]interface{}{}
+	for _, e := range unwrapAll(err) {
+		if fe, ok := e.(*fieldsError); ok {
+			for k, v := range fe.fields {
+				if _, ok := fields[k]; !ok {
+					fields[k] = v
+				}
+			}
+		}
+	}
+
+	return fields
+}
+
+// Causes returns an error and all of its causes from the outermost to the innermost error
+// Causes are found by Unwrap() error and Cause() error methods.
+func Causes(err error) []error {
+	var causes []error
+	for _, e := range unwrapAll(err) {
+		switch e.(type) {
+		case *fieldsError, *callerError:
+		default:
+			causes = append(causes, e)
+		}
+	}
+
+	return causes
+}
+
+func unwrapAll(err error) []error {
+	var errs []error
+	for err != nil && len(errs) < maxCauses {
+		errs = append(errs, err)
+
+		switch e := err.(type) {
+		case interface{ Unwrap() error }:
+			err = e.Unwrap()
+		case interface{ Cause() error }:
+			err = e.Cause()
+		default:
+			err = nil
+		}
+	}
+
+	return errs
+}
+
+// causeChain returns the type, message, and embedded stack trace of an error and all of its causes
+func causeChain(err error) []causeError {
+	var chain []causeError
+	for _, e := range Causes(err) {
+		chain = append(chain, causeError{
+			Type:    fmt.Sprintf("%T", e),
+			Message: e.Error(),
+			Frames:  embeddedStack(e),
+		})
+	}
+
+	return chain
+}
+
+// hasEmbeddedStack determines whether or not any error in a cause chain has an embedded stack trace
+func hasEmbeddedStack(chain []causeError) bool {
+	for _, c := range chain {
+		if c.Frames != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// embeddedStack returns the stack trace embedded in an error if it has one
+// The following methods are supported:
+//   Stack() []runtime.Frame (i.e. rollbar.CauseStacker)
+//   StackTrace() returning a slice of program counters (i.e. github.com/pkg/errors)
+func embeddedStack(err error) []runtime.Frame {
+	if s, ok := err.(interface{ Stack() []runtime.Frame }); ok {
+		return s.Stack()
+	}
+
+	m := reflect.ValueOf(err).MethodByName("StackTrace")
+	if !m.IsValid() || m.Type().NumIn() != 0 || m.Type().NumOut() != 1 {
+		return nil
+	}
+
+	out := m.Type().Out(0)
+	if out.Kind() != reflect.Slice || out.Elem().Kind() != reflect.Uintptr {
+		return nil
+	}
+
+	trace := m.Call(nil)[0]
+	if trace.Len() == 0 {
+		return nil
+	}
+
+	pcs := make([]uintptr, trace.Len())
+	for i := range pcs {
+		pcs[i] = uintptr(trace.Index(i).Uint())
+	}
+
+	return framesOf(pcs)
+}
+
+// callerFrames returns the frames of the current goroutine stack from the newest to the oldest call
+func callerFrames(skip int) []runtime.Frame {
+	pc := make([]uintptr, 100)
+	n := runtime.Callers(skip, pc)
+
+	return framesOf(pc[:n])
+}
+
+// framesOf converts program counters to stack frames
+func framesOf(pcs []uintptr) []runtime.Frame {
+	callers := runtime.CallersFrames(pcs)
+
+	var frames []runtime.Frame
+	for {
+		frame, more := callers.Next()
+		frames = append(frames, frame)
+		if !more {
+			break
+		}
+	}
+
+	return frames
+}
+
+// mergeFields returns the key-value pairs attached to an error merged with metadata
+// Metadata takes precedence over the key-value pairs attached to the error.
+// A new map is returned, so it can be reported asynchronously while the caller changes metadata.
+func mergeFields(err error, metadata map[string]interface{}) map[string]interface{} {
+	fields := Fields(err)
+	if len(fields) == 0 && len(metadata) == 0 {
+		return nil
+	}
+
+	if fields == nil {
+		fields = make(map[string]interface{}, len(metadata))
+	}
+
+	for k, v := range metadata {
+		fields[k] = v
+	}
+
+	return fields
+}
@@ -0,0 +1,205 @@
+package report
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// wrapError wraps an error using Unwrap method
+type wrapError struct {
+	msg string
+	err error
+}
+
+func (e *wrapError) Error() string { return e.msg + ": " + e.err.Error() }
+func (e *wrapError) Unwrap() error { return e.err }
+
+// causeWrapError wraps an error using Cause method
+type causeWrapError struct {
+	msg string
+	err error
+}
+
+func (e *causeWrapError) Error() string { return e.msg + ": " + e.err.Error() }
+func (e *causeWrapError) Cause() error  { return e.err }
+
+// stackError has an embedded stack trace similar to rollbar.CauseStacker
+type stackError struct {
+	msg    string
+	frames []runtime.Frame
+}
+
+func (e *stackError) Error() string          { return e.msg }
+func (e *stackError) Stack() []runtime.Frame { return e.frames }
+
+// pkgStackError has an embedded stack trace similar to github.com/pkg/errors
+type (
+	pkgFrame      uintptr
+	pkgStackTrace []pkgFrame
+	pkgStackError struct {
+		msg string
+		pcs []uintptr
+	}
+)
+
+func (e *pkgStackError) Error() string { return e.msg }
+func (e *pkgStackError) StackTrace() pkgStackTrace {
+	st := make(pkgStackTrace, len(e.pcs))
+	for i, pc := range e.pcs {
+		st[i] = pkgFrame(pc)
+	}
+	return st
+}
+
+func newPkgStackError(msg string) *pkgStackError {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(1, pcs)
+	return &pkgStackError{msg: msg, pcs: pcs[:n]}
+}
+
+// cyclicError is its own cause
+type cyclicError struct{}
+
+func (e *cyclicError) Error() string { return "cyclic" }
+func (e *cyclicError) Unwrap() error { return e }
+
+func TestWithFields(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		kv             []interface{}
+		expectedNil    bool
+		expectedFields map[string]interface{}
+	}{
+		{
+			name:        "NilError",
+			err:         nil,
+			kv:          []interface{}{"key", "value"},
+			expectedNil: true,
+		},
+		{
+			name:           "NoField",
+			err:            errors.New("error"),
+			expectedFields: map[string]interface{}{},
+		},
+		{
+			name:           "Fields",
+			err:            errors.New("error"),
+			kv:             []interface{}{"userId", "1234", "attempt", 2},
+			expectedFields: map[string]interface{}{"userId": "1234", "attempt": 2},
+		},
+		{
+			name:           "MissingValue",
+			err:            errors.New("error"),
+			kv:             []interface{}{"userId", "1234", 7},
+			expectedFields: map[string]interface{}{"userId": "1234", "7": nil},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := WithFields(tc.err, tc.kv...)
+
+			if tc.expectedNil {
+				assert.Nil(t, err)
+			} else {
+				assert.Equal(t, tc.err.Error(), err.Error())
+				assert.Equal(t, tc.expectedFields, Fields(err))
+			}
+		})
+	}
+}
+
+func TestFields(t *testing.T) {
+	inner := WithFields(errors.New("connection refused"), "host", "db-1", "port", 5432)
+	outer := WithFields(&wrapError{"query failed", inner}, "host", "db-2", "table", "users")
+
+	assert.Equal(t, map[string]interface{}{}, Fields(errors.New("error")))
+	assert.Equal(t, map[string]interface{}{"host": "db-1", "port": 5432}, Fields(inner))
+	assert.Equal(t, map[string]interface{}{"host": "db-2", "port": 5432, "table": "users"}, Fields(outer))
+}
+
+func TestCauses(t *testing.T) {
+	root := errors.New("connection refused")
+	cause := &causeWrapError{"dial failed", WithFields(root, "host", "db-1")}
+	wrap := &wrapError{"query failed", cause}
+
+	tests := []struct {
+		name           string
+		err            error
+		expectedCauses []error
+	}{
+		{
+			name:           "Nil",
+			err:            nil,
+			expectedCauses: nil,
+		},
+		{
+			name:           "NoCause",
+			err:            root,
+			expectedCauses: []error{root},
+		},
+		{
+			name:           "Chain",
+			err:            wrap,
+			expectedCauses: []error{wrap, cause, root},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedCauses, Causes(tc.err))
+		})
+	}
+
+	t.Run("Cycle", func(t *testing.T) {
+		assert.Len(t, Causes(&cyclicError{}), maxCauses)
+	})
+}
+
+func TestCauseChain(t *testing.T) {
+	frames := []runtime.Frame{{Function: "main.main", File: "main.go", Line: 10}}
+	err := &wrapError{"query failed", &stackError{"connection refused", frames}}
+
+	chain := causeChain(err)
+
+	assert.Equal(t, []causeError{
+		{Type: "*report.wrapError", Message: "query failed: connection refused"},
+		{Type: "*report.stackError", Message: "connection refused", Frames: frames},
+	}, chain)
+	assert.True(t, hasEmbeddedStack(chain))
+	assert.False(t, hasEmbeddedStack(causeChain(errors.New("error"))))
+}
+
+func TestEmbeddedStack(t *testing.T) {
+	t.Run("NoStack", func(t *testing.T) {
+		assert.Nil(t, embeddedStack(errors.New("error")))
+	})
+
+	t.Run("Stack", func(t *testing.T) {
+		frames := []runtime.Frame{{Function: "main.main", File: "main.go", Line: 10}}
+		assert.Equal(t, frames, embeddedStack(&stackError{"error", frames}))
+	})
+
+	t.Run("StackTrace", func(t *testing.T) {
+		frames := embeddedStack(newPkgStackError("error"))
+		assert.NotEmpty(t, frames)
+		assert.Equal(t, "github.com/moorara/goto/report.newPkgStackError", frames[0].Function)
+		assert.Equal(t, "github.com/moorara/goto/report.TestEmbeddedStack.func3", frames[1].Function)
+	})
+
+	t.Run("EmptyStackTrace", func(t *testing.T) {
+		assert.Nil(t, embeddedStack(&pkgStackError{msg: "error"}))
+	})
+}
+
+func TestMergeFields(t *testing.T) {
+	err := WithFields(errors.New("error"), "userId", "1234", "code", 1)
+
+	assert.Nil(t, mergeFields(errors.New("error"), nil))
+	assert.Equal(t, map[string]interface{}{"code": 7}, mergeFields(errors.New("error"), map[string]interface{}{"code": 7}))
+	assert.Equal(t, map[string]interface{}{"userId": "1234", "code": 7}, mergeFields(err, map[string]interface{}{"code": 7}))
+}
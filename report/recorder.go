@@ -0,0 +1,115 @@
+package report
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// Levels of recorded reports
+const (
+	LevelError    = "error"
+	LevelCritical = "critical"
+)
+
+type (
+	// Report is an error reported to a Recorder
+	Report struct {
+		Level    string
+		Error    error
+		Request  *http.Request
+		Metadata map[string]interface{}
+	}
+
+	// Recorder is a reporter keeping reported errors in memory
+	// It can be used for verifying reported errors in tests.
+	Recorder struct {
+		sync.Mutex
+		reports []Report
+	}
+)
+
+// NewRecorder creates a new in-memory reporter
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+func (r *Recorder) record(report Report) {
+	// Errors reported by ThrottledReporter are recorded as they were originally reported
+	report.Error = withoutCallerFrames(report.Error)
+
+	r.Lock()
+	defer r.Unlock()
+
+	r.reports = append(r.reports, report)
+}
+
+// OnPanic records a panic and should be used with defer
+func (r *Recorder) OnPanic() {
+	if e := recover(); e != nil {
+		r.record(Report{
+			Level: LevelCritical,
+			Error: fmt.Errorf("panic occurred: %v", e),
+		})
+		panic(e)
+	}
+}
+
+// Error records an error
+func (r *Recorder) Error(err error) {
+	r.record(Report{
+		Level: LevelError,
+		Error: err,
+	})
+}
+
+// ErrorWithMetadata records an error with extra metadata
+func (r *Recorder) ErrorWithMetadata(err error, metadata map[string]interface{}) {
+	r.record(Report{
+		Level:    LevelError,
+		Error:    err,
+		Metadata: metadata,
+	})
+}
+
+// HTTPError records an error for an http request
+func (r *Recorder) HTTPError(req *http.Request, err error) {
+	r.record(Report{
+		Level:   LevelError,
+		Error:   err,
+		Request: req,
+	})
+}
+
+// HTTPErrorWithMetadata records an error for an http request with extra metdata
+func (r *Recorder) HTTPErrorWithMetadata(req *http.Request, err error, metadata map[string]interface{}) {
+	r.record(Report{
+		Level:    LevelError,
+		Error:    err,
+		Request:  req,
+		Metadata: metadata,
+	})
+}
+
+// Wait implements Reporter interface
+// Errors are recorded synchronously, so it returns immediately.
+func (r *Recorder) Wait() {}
+
+// Reset removes all recorded reports
+func (r *Recorder) Reset() {
+	r.Lock()
+	defer r.Unlock()
+
+	r.reports = nil
+}
+
+// Reports returns all recorded reports in the order they are reported
+func (r *Recorder) Reports() []Report {
+	r.Lock()
+	defer r.Unlock()
+
+	reports := make([]Report, len(r.reports))
+	copy(reports, r.reports)
+
+	return reports
+}
@@ -0,0 +1,72 @@
+package report
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecorder(t *testing.T) {
+	req := &http.Request{}
+	err := errors.New("error occurred")
+	metadata := map[string]interface{}{
+		"code": 7,
+	}
+
+	tests := []struct {
+		name           string
+		report         func(Reporter)
+		expectedReport Report
+	}{
+		{
+			name:           "Error",
+			report:         func(r Reporter) { r.Error(err) },
+			expectedReport: Report{Level: LevelError, Error: err},
+		},
+		{
+			name:           "ErrorWithMetadata",
+			report:         func(r Reporter) { r.ErrorWithMetadata(err, metadata) },
+			expectedReport: Report{Level: LevelError, Error: err, Metadata: metadata},
+		},
+		{
+			name:           "HTTPError",
+			report:         func(r Reporter) { r.HTTPError(req, err) },
+			expectedReport: Report{Level: LevelError, Error: err, Request: req},
+		},
+		{
+			name:           "HTTPErrorWithMetadata",
+			report:         func(r Reporter) { r.HTTPErrorWithMetadata(req, err, metadata) },
+			expectedReport: Report{Level: LevelError, Error: err, Request: req, Metadata: metadata},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := NewRecorder()
+			tc.report(recorder)
+			recorder.Wait()
+
+			assert.Equal(t, []Report{tc.expectedReport}, recorder.Reports())
+
+			recorder.Reset()
+			assert.Empty(t, recorder.Reports())
+		})
+	}
+}
+
+func TestRecorderOnPanic(t *testing.T) {
+	recorder := NewRecorder()
+
+	defer func() {
+		r := recover()
+		assert.Equal(t, "oops", r)
+		assert.Equal(t, []Report{
+			{Level: LevelCritical, Error: errors.New("panic occurred: oops")},
+		}, recorder.Reports())
+	}()
+
+	defer recorder.OnPanic()
+	panic("oops")
+}
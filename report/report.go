@@ -1,4 +1,4 @@
-// Package report can be used for reproting and alerting on errors using Rollbar.
+// Package report can be used for reproting and alerting on errors using Rollbar or Sentry.
 //
 // Deprecated: this package has been frozen and deprecated in favor of github.com/moorara/observe/report
 package report
@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"runtime"
+	"strings"
 
 	rollbar "github.com/rollbar/rollbar-go"
 )
@@ -14,9 +16,28 @@ import (
 const (
 	// TODO: figure out the right value!
 	defaultSkipDepth = 0
+
+	// rollbarTraceChainKey is the extras key for passing a trace chain to rollbarTransform
+	rollbarTraceChainKey = "__trace_chain"
 )
 
 type (
+	// Reporter is the interface for error reporters
+	Reporter interface {
+		// OnPanic reports a panic and should be used with defer
+		OnPanic()
+		// Error reports an error
+		Error(err error)
+		// ErrorWithMetadata reports an error with extra metadata
+		ErrorWithMetadata(err error, metadata map[string]interface{})
+		// HTTPError reports an error for an http request
+		HTTPError(req *http.Request, err error)
+		// HTTPErrorWithMetadata reports an error for an http request with extra metdata
+		HTTPErrorWithMetadata(req *http.Request, err error, metadata map[string]interface{})
+		// Wait blocks until all errors are reported
+		Wait()
+	}
+
 	rollbarClient interface {
 		ErrorWithStackSkip(level string, err error, skip int)
 		ErrorWithStackSkipWithExtras(level string, err error, skip int, extras map[string]interface{})
@@ -31,17 +52,31 @@ type (
 		Environment string
 		CodeVersion string
 		ProjectURL  string
-		skipDepth   int
+		// Endpoint is the url items are posted to (default https://api.rollbar.com/api/1/item/)
+		Endpoint string
+		// ScrubHeaders are the names of request headers replaced with [FILTERED] (default Authorization, Proxy-Authorization, Cookie, Set-Cookie, X-Api-Key)
+		ScrubHeaders []string
+		// ScrubFields are the names of query parameters and body fields replaced with [FILTERED] (default password, secret, token)
+		// A field is scrubbed if its name contains one of the names.
+		ScrubFields []string
+		// MaxBodySize is the maximum size of request bodies in bytes reported with http errors (default 4096)
+		// Larger bodies are not reported and a negative value disables reporting request bodies.
+		MaxBodySize int
+		// RequestContext returns the authenticated user and the request id for an http request
+		RequestContext func(*http.Request) RequestContext
+		skipDepth      int
 	}
 
 	// RollbarReporter simplifies rollbar client
 	RollbarReporter struct {
 		client    rollbarClient
+		scrubber  *requestScrubber
 		skipDepth int
 	}
 )
 
-var singleton = &RollbarReporter{}
+// singleton is the global reporter and nil until set
+var singleton Reporter
 
 // NewRollbarReporter creates a new instance of reporter
 func NewRollbarReporter(opts RollbarOptions) *RollbarReporter {
@@ -58,10 +93,127 @@ func (r *RollbarReporter) setOptions(opts RollbarOptions) {
 		opts.skipDepth = defaultSkipDepth
 	}
 
+	scrubber := newRequestScrubber(opts)
+	client.SetScrubHeaders(scrubber.headers)
+	client.SetScrubFields(scrubber.fields)
+	client.SetTransform(rollbarTransform)
+
+	if opts.Endpoint != "" {
+		client.SetEndpoint(opts.Endpoint)
+	}
+
 	r.client = client
+	r.scrubber = scrubber
 	r.skipDepth = opts.skipDepth
 }
 
+// rollbarExtras returns the extras for reporting an error to Rollbar
+// Extras include metadata, the key-value pairs attached to the error, and the trace chain of the error causes.
+// It returns nil if there is no extra to report.
+func rollbarExtras(err error, metadata map[string]interface{}) map[string]interface{} {
+	extras := mergeFields(err, metadata)
+
+	chain := causeChain(err)
+	reportFrames := callerFramesOf(err)
+	// rollbar client would report an error wrapped by WithFields with the class of the wrapper
+	_, hasFields := err.(*fieldsError)
+	if len(chain) > 1 || hasEmbeddedStack(chain) || reportFrames != nil || (hasFields && len(chain) > 0) {
+		extras = copyMap(extras)
+		extras[rollbarTraceChainKey] = rollbarTraceChain(chain, reportFrames)
+	}
+
+	return extras
+}
+
+// rollbarTraceChain creates a Rollbar trace chain from the cause chain of an error
+// Classes are named the same way rollbar client names them (without the leading *).
+// If no error has an embedded stack trace, the outermost error gets the frames of reporting site.
+// When the frames of reporting site are not known, the outermost error has no frames and rollbarTransform fills it with the frames of reporting site.
+func rollbarTraceChain(chain []causeError, reportFrames []runtime.Frame) []map[string]interface{} {
+	embedded := hasEmbeddedStack(chain)
+
+	traceChain := make([]map[string]interface{}, len(chain))
+	for i, c := range chain {
+		traceChain[i] = map[string]interface{}{
+			"exception": map[string]interface{}{
+				"class":   strings.TrimPrefix(c.Type, "*"),
+				"message": c.Message,
+			},
+		}
+
+		if c.Frames == nil && !embedded && i == 0 {
+			if reportFrames == nil {
+				continue
+			}
+			c.Frames = reportFrames
+		}
+
+		frames := make([]map[string]interface{}, len(c.Frames))
+		for j, f := range c.Frames {
+			frames[j] = map[string]interface{}{
+				"filename": f.File,
+				"method":   f.Function,
+				"lineno":   f.Line,
+			}
+		}
+		traceChain[i]["frames"] = frames
+	}
+
+	return traceChain
+}
+
+// rollbarTransform replaces the request and the trace chain built by rollbar client with the ones passed in extras
+// It also sets the person passed in extras.
+func rollbarTransform(data map[string]interface{}) {
+	custom, _ := data["custom"].(map[string]interface{})
+	request, hasRequest := custom[rollbarRequestKey].(map[string]interface{})
+	person, hasPerson := custom[rollbarPersonKey].(map[string]interface{})
+	traceChain, hasTraceChain := custom[rollbarTraceChainKey].([]map[string]interface{})
+
+	for _, key := range []string{rollbarRequestKey, rollbarPersonKey, rollbarTraceChainKey} {
+		delete(custom, key)
+	}
+
+	if custom != nil && len(custom) == 0 {
+		delete(data, "custom")
+	}
+
+	if hasRequest {
+		data["request"] = request
+	}
+
+	if hasPerson {
+		data["person"] = person
+	}
+
+	if !hasTraceChain {
+		return
+	}
+
+	body, ok := data["body"].(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	// Keep the frames of reporting site for the outermost error
+	if _, ok := traceChain[0]["frames"]; !ok {
+		if tc, ok := body["trace_chain"].([]map[string]interface{}); ok && len(tc) > 0 {
+			traceChain[0]["frames"] = tc[0]["frames"]
+		}
+	}
+
+	body["trace_chain"] = traceChain
+}
+
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	c := make(map[string]interface{}, len(m)+1)
+	for k, v := range m {
+		c[k] = v
+	}
+
+	return c
+}
+
 // OnPanic reports a panic and should be used with defer
 func (r *RollbarReporter) OnPanic() {
 	if e := recover(); e != nil {
@@ -76,22 +228,37 @@ func (r *RollbarReporter) OnPanic() {
 
 // Error reports an error
 func (r *RollbarReporter) Error(err error) {
-	r.client.ErrorWithStackSkip(rollbar.ERR, err, r.skipDepth)
+	if extras := rollbarExtras(err, nil); extras != nil {
+		r.client.ErrorWithStackSkipWithExtras(rollbar.ERR, err, r.skipDepth, extras)
+	} else {
+		r.client.ErrorWithStackSkip(rollbar.ERR, err, r.skipDepth)
+	}
 }
 
 // ErrorWithMetadata reports an error with extra metadata
 func (r *RollbarReporter) ErrorWithMetadata(err error, metadata map[string]interface{}) {
-	r.client.ErrorWithStackSkipWithExtras(rollbar.ERR, err, r.skipDepth, metadata)
+	r.client.ErrorWithStackSkipWithExtras(rollbar.ERR, err, r.skipDepth, rollbarExtras(err, metadata))
+}
+
+// requestExtras returns the extras for reporting an error for an http request to Rollbar
+// The request is reported with sensitive headers, query parameters, and body fields scrubbed.
+func (r *RollbarReporter) requestExtras(req *http.Request, err error, metadata map[string]interface{}) map[string]interface{} {
+	scrubber := r.scrubber
+	if scrubber == nil {
+		scrubber = defaultScrubber
+	}
+
+	return scrubber.extras(req, rollbarExtras(err, metadata))
 }
 
 // HTTPError reports an error for an http request
 func (r *RollbarReporter) HTTPError(req *http.Request, err error) {
-	r.client.RequestErrorWithStackSkip(rollbar.ERR, req, err, r.skipDepth)
+	r.client.RequestErrorWithStackSkipWithExtras(rollbar.ERR, req, err, r.skipDepth, r.requestExtras(req, err, nil))
 }
 
 // HTTPErrorWithMetadata reports an error for an http request with extra metdata
 func (r *RollbarReporter) HTTPErrorWithMetadata(req *http.Request, err error, metadata map[string]interface{}) {
-	r.client.RequestErrorWithStackSkipWithExtras(rollbar.ERR, req, err, r.skipDepth, metadata)
+	r.client.RequestErrorWithStackSkipWithExtras(rollbar.ERR, req, err, r.skipDepth, r.requestExtras(req, err, metadata))
 }
 
 // Wait blocks until all errors are reported
@@ -99,56 +266,68 @@ func (r *RollbarReporter) Wait() {
 	r.client.Wait()
 }
 
-// SetOptions sets options for singleton reporter
+// reportPanic raises a recovered panic again for a reporter to recover and report it
+func reportPanic(r Reporter, e interface{}) {
+	defer r.OnPanic()
+	panic(e)
+}
+
+// SetOptions sets a Rollbar reporter with options as singleton reporter
 func SetOptions(opts RollbarOptions) {
-	singleton.setOptions(opts)
+	singleton = NewRollbarReporter(opts)
+}
+
+// SetReporter sets any reporter implementation as singleton reporter
+func SetReporter(r Reporter) {
+	singleton = r
 }
 
 // OnPanic reports a panic and should be used with defer
 func OnPanic() {
-	if singleton.client != nil {
-		if e := recover(); e != nil {
-			err := fmt.Errorf("panic occurred: %v", e)
-			singleton.client.ErrorWithStackSkip(rollbar.CRIT, err, singleton.skipDepth+2)
-			singleton.client.Wait()
+	if e := recover(); e != nil {
+		if singleton == nil {
 			panic(e)
 		}
+
+		reportPanic(singleton, e)
 	}
 
-	singleton.client.Wait()
+	if singleton != nil {
+		singleton.Wait()
+	}
 }
 
 // Error reports an error
 func Error(err error) {
-	if singleton.client != nil {
-		singleton.client.ErrorWithStackSkip(rollbar.ERR, err, singleton.skipDepth)
+	if singleton != nil {
+		singleton.Error(err)
 	}
 }
 
 // ErrorWithMetadata reports an error with extra metadata
 func ErrorWithMetadata(err error, metadata map[string]interface{}) {
-	if singleton.client != nil {
-		singleton.client.ErrorWithStackSkipWithExtras(rollbar.ERR, err, singleton.skipDepth, metadata)
+	if singleton != nil {
+		singleton.ErrorWithMetadata(err, metadata)
 	}
 }
 
 // HTTPError reports an error for an http request
 func HTTPError(req *http.Request, err error) {
-	if singleton.client != nil {
-		singleton.client.RequestErrorWithStackSkip(rollbar.ERR, req, err, singleton.skipDepth)
+	if singleton != nil {
+		singleton.HTTPError(req, err)
 	}
 }
 
 // HTTPErrorWithMetadata reports an error for an http request with extra metdata
 func HTTPErrorWithMetadata(req *http.Request, err error, metadata map[string]interface{}) {
-	if singleton.client != nil {
-		singleton.client.RequestErrorWithStackSkipWithExtras(rollbar.ERR, req, err, singleton.skipDepth, metadata)
+	if singleton != nil {
+		singleton.HTTPErrorWithMetadata(req, err, metadata)
 	}
 }
 
 // Wait blocks until all errors are reported
 func Wait() {
-	if singleton.client != nil {
-		singleton.client.Wait()
+	if singleton != nil {
+		singleton.Wait()
 	}
 }
@@ -3,6 +3,7 @@ package report
 import (
 	"errors"
 	"net/http"
+	"runtime"
 	"testing"
 
 	rollbar "github.com/rollbar/rollbar-go"
@@ -253,10 +254,11 @@ func TestReporterHTTPError(t *testing.T) {
 
 		reporter.HTTPError(tc.req, tc.err)
 
-		assert.Equal(t, rollbar.ERR, tc.client.RequestErrorWithStackSkipInLevel)
-		assert.Equal(t, tc.req, tc.client.RequestErrorWithStackSkipInRequest)
-		assert.Equal(t, tc.err, tc.client.RequestErrorWithStackSkipInError)
-		assert.Equal(t, tc.skipDepth, tc.client.RequestErrorWithStackSkipInSkip)
+		assert.Equal(t, rollbar.ERR, tc.client.RequestErrorWithStackSkipWithExtrasInLevel)
+		assert.Equal(t, tc.req, tc.client.RequestErrorWithStackSkipWithExtrasInRequest)
+		assert.Equal(t, tc.err, tc.client.RequestErrorWithStackSkipWithExtrasInError)
+		assert.Equal(t, tc.skipDepth, tc.client.RequestErrorWithStackSkipWithExtrasInSkip)
+		assert.Contains(t, tc.client.RequestErrorWithStackSkipWithExtrasInExtras, rollbarRequestKey)
 	}
 }
 
@@ -291,7 +293,10 @@ func TestReporterHTTPErrorWithMetadata(t *testing.T) {
 		assert.Equal(t, tc.req, tc.client.RequestErrorWithStackSkipWithExtrasInRequest)
 		assert.Equal(t, tc.err, tc.client.RequestErrorWithStackSkipWithExtrasInError)
 		assert.Equal(t, tc.skipDepth, tc.client.RequestErrorWithStackSkipWithExtrasInSkip)
-		assert.Equal(t, tc.metadata, tc.client.RequestErrorWithStackSkipWithExtrasInExtras)
+		assert.Contains(t, tc.client.RequestErrorWithStackSkipWithExtrasInExtras, rollbarRequestKey)
+		for key, val := range tc.metadata {
+			assert.Equal(t, val, tc.client.RequestErrorWithStackSkipWithExtrasInExtras[key])
+		}
 	}
 }
 
@@ -338,8 +343,8 @@ func TestSingletonSetOptions(t *testing.T) {
 	for _, tc := range tests {
 		SetOptions(tc.opts)
 
-		assert.NotNil(t, singleton.client)
-		assert.Equal(t, tc.expectedSkipDepth, singleton.skipDepth)
+		assert.NotNil(t, singleton.(*RollbarReporter).client)
+		assert.Equal(t, tc.expectedSkipDepth, singleton.(*RollbarReporter).skipDepth)
 	}
 }
 
@@ -480,10 +485,11 @@ func TestSingletonHTTPError(t *testing.T) {
 
 		HTTPError(tc.req, tc.err)
 
-		assert.Equal(t, rollbar.ERR, tc.client.RequestErrorWithStackSkipInLevel)
-		assert.Equal(t, tc.req, tc.client.RequestErrorWithStackSkipInRequest)
-		assert.Equal(t, tc.err, tc.client.RequestErrorWithStackSkipInError)
-		assert.Equal(t, tc.skipDepth, tc.client.RequestErrorWithStackSkipInSkip)
+		assert.Equal(t, rollbar.ERR, tc.client.RequestErrorWithStackSkipWithExtrasInLevel)
+		assert.Equal(t, tc.req, tc.client.RequestErrorWithStackSkipWithExtrasInRequest)
+		assert.Equal(t, tc.err, tc.client.RequestErrorWithStackSkipWithExtrasInError)
+		assert.Equal(t, tc.skipDepth, tc.client.RequestErrorWithStackSkipWithExtrasInSkip)
+		assert.Contains(t, tc.client.RequestErrorWithStackSkipWithExtrasInExtras, rollbarRequestKey)
 	}
 }
 
@@ -518,7 +524,10 @@ func TestSingletonHTTPErrorWithMetadata(t *testing.T) {
 		assert.Equal(t, tc.req, tc.client.RequestErrorWithStackSkipWithExtrasInRequest)
 		assert.Equal(t, tc.err, tc.client.RequestErrorWithStackSkipWithExtrasInError)
 		assert.Equal(t, tc.skipDepth, tc.client.RequestErrorWithStackSkipWithExtrasInSkip)
-		assert.Equal(t, tc.metadata, tc.client.RequestErrorWithStackSkipWithExtrasInExtras)
+		assert.Contains(t, tc.client.RequestErrorWithStackSkipWithExtrasInExtras, rollbarRequestKey)
+		for key, val := range tc.metadata {
+			assert.Equal(t, val, tc.client.RequestErrorWithStackSkipWithExtrasInExtras[key])
+		}
 	}
 }
 
@@ -540,3 +549,258 @@ func TestSingletonWait(t *testing.T) {
 		assert.True(t, tc.client.WaitCalled)
 	}
 }
+
+func TestReporterImplementations(t *testing.T) {
+	var _ Reporter = &RollbarReporter{}
+	var _ Reporter = &SentryReporter{}
+	var _ Reporter = &Recorder{}
+	var _ Reporter = &ThrottledReporter{}
+}
+
+func TestSingletonSetReporter(t *testing.T) {
+	defer SetReporter(nil)
+
+	recorder := NewRecorder()
+	SetReporter(recorder)
+
+	req := &http.Request{}
+	err := errors.New("error occurred")
+	metadata := map[string]interface{}{
+		"code": 7,
+	}
+
+	Error(err)
+	ErrorWithMetadata(err, metadata)
+	HTTPError(req, err)
+	HTTPErrorWithMetadata(req, err, metadata)
+	Wait()
+
+	assert.Equal(t, []Report{
+		{Level: LevelError, Error: err},
+		{Level: LevelError, Error: err, Metadata: metadata},
+		{Level: LevelError, Error: err, Request: req},
+		{Level: LevelError, Error: err, Request: req, Metadata: metadata},
+	}, recorder.Reports())
+}
+
+func TestSingletonOnPanicWithReporter(t *testing.T) {
+	tests := []struct {
+		name            string
+		reporter        Reporter
+		expectedReports []Report
+	}{
+		{
+			name:     "NoReporter",
+			reporter: nil,
+		},
+		{
+			name:     "Recorder",
+			reporter: NewRecorder(),
+			expectedReports: []Report{
+				{Level: LevelCritical, Error: errors.New("panic occurred: oops")},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			SetReporter(tc.reporter)
+			defer SetReporter(nil)
+
+			defer func() {
+				r := recover()
+				assert.Equal(t, "oops", r)
+				if recorder, ok := tc.reporter.(*Recorder); ok {
+					assert.Equal(t, tc.expectedReports, recorder.Reports())
+				}
+			}()
+
+			defer OnPanic()
+			panic("oops")
+		})
+	}
+}
+
+func TestRollbarExtras(t *testing.T) {
+	frames := []runtime.Frame{{Function: "main.main", File: "main.go", Line: 10}}
+
+	tests := []struct {
+		name           string
+		err            error
+		metadata       map[string]interface{}
+		expectedExtras map[string]interface{}
+	}{
+		{
+			name:           "NoExtras",
+			err:            errors.New("error"),
+			expectedExtras: nil,
+		},
+		{
+			name:           "Metadata",
+			err:            errors.New("error"),
+			metadata:       map[string]interface{}{"code": 7},
+			expectedExtras: map[string]interface{}{"code": 7},
+		},
+		{
+			name:     "Fields",
+			err:      WithFields(errors.New("error"), "userId", "1234"),
+			metadata: map[string]interface{}{"code": 7},
+			expectedExtras: map[string]interface{}{
+				"userId": "1234",
+				"code":   7,
+				rollbarTraceChainKey: []map[string]interface{}{
+					{
+						"exception": map[string]interface{}{"class": "errors.errorString", "message": "error"},
+					},
+				},
+			},
+		},
+		{
+			name: "CauseChain",
+			err:  &wrapError{"query failed", errors.New("connection refused")},
+			expectedExtras: map[string]interface{}{
+				rollbarTraceChainKey: []map[string]interface{}{
+					{
+						"exception": map[string]interface{}{"class": "report.wrapError", "message": "query failed: connection refused"},
+					},
+					{
+						"frames":    []map[string]interface{}{},
+						"exception": map[string]interface{}{"class": "errors.errorString", "message": "connection refused"},
+					},
+				},
+			},
+		},
+		{
+			name:     "EmbeddedStack",
+			err:      &wrapError{"query failed", &stackError{"connection refused", frames}},
+			metadata: map[string]interface{}{"code": 7},
+			expectedExtras: map[string]interface{}{
+				"code": 7,
+				rollbarTraceChainKey: []map[string]interface{}{
+					{
+						"frames":    []map[string]interface{}{},
+						"exception": map[string]interface{}{"class": "report.wrapError", "message": "query failed: connection refused"},
+					},
+					{
+						"frames": []map[string]interface{}{
+							{"filename": "main.go", "method": "main.main", "lineno": 10},
+						},
+						"exception": map[string]interface{}{"class": "report.stackError", "message": "connection refused"},
+					},
+				},
+			},
+		},
+		{
+			name: "CallerFrames",
+			err:  withCallerFrames(errors.New("error"), frames),
+			expectedExtras: map[string]interface{}{
+				rollbarTraceChainKey: []map[string]interface{}{
+					{
+						"frames": []map[string]interface{}{
+							{"filename": "main.go", "method": "main.main", "lineno": 10},
+						},
+						"exception": map[string]interface{}{"class": "errors.errorString", "message": "error"},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			extras := rollbarExtras(tc.err, tc.metadata)
+			assert.Equal(t, tc.expectedExtras, extras)
+		})
+	}
+}
+
+func TestRollbarTransform(t *testing.T) {
+	reportFrames := []map[string]interface{}{
+		{"filename": "main.go", "method": "main.main", "lineno": 10},
+	}
+
+	tests := []struct {
+		name         string
+		data         map[string]interface{}
+		expectedData map[string]interface{}
+	}{
+		{
+			name: "NoTraceChain",
+			data: map[string]interface{}{
+				"custom": map[string]interface{}{"code": 7},
+			},
+			expectedData: map[string]interface{}{
+				"custom": map[string]interface{}{"code": 7},
+			},
+		},
+		{
+			name: "TraceChain",
+			data: map[string]interface{}{
+				"custom": map[string]interface{}{
+					rollbarTraceChainKey: []map[string]interface{}{
+						{"exception": map[string]interface{}{"class": "outer"}},
+						{"frames": []map[string]interface{}{}, "exception": map[string]interface{}{"class": "inner"}},
+					},
+				},
+				"body": map[string]interface{}{
+					"trace_chain": []map[string]interface{}{
+						{"frames": reportFrames, "exception": map[string]interface{}{"class": "outer"}},
+					},
+				},
+			},
+			expectedData: map[string]interface{}{
+				"body": map[string]interface{}{
+					"trace_chain": []map[string]interface{}{
+						{"frames": reportFrames, "exception": map[string]interface{}{"class": "outer"}},
+						{"frames": []map[string]interface{}{}, "exception": map[string]interface{}{"class": "inner"}},
+					},
+				},
+			},
+		},
+		{
+			name: "RequestAndPerson",
+			data: map[string]interface{}{
+				"custom": map[string]interface{}{
+					"code":            7,
+					rollbarRequestKey: map[string]interface{}{"url": "http://example.com/"},
+					rollbarPersonKey:  map[string]interface{}{"id": "1234"},
+				},
+				"request": map[string]interface{}{"url": "/?token=secret"},
+			},
+			expectedData: map[string]interface{}{
+				"custom":  map[string]interface{}{"code": 7},
+				"request": map[string]interface{}{"url": "http://example.com/"},
+				"person":  map[string]interface{}{"id": "1234"},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rollbarTransform(tc.data)
+			assert.Equal(t, tc.expectedData, tc.data)
+		})
+	}
+}
+
+func TestReporterErrorWithCauses(t *testing.T) {
+	client := &mockRollbarClient{}
+	reporter := &RollbarReporter{
+		client: client,
+	}
+
+	err := WithFields(&wrapError{"query failed", errors.New("connection refused")}, "table", "users")
+	reporter.Error(err)
+
+	assert.Nil(t, client.ErrorWithStackSkipInError)
+	assert.Equal(t, err, client.ErrorWithStackSkipWithExtrasInError)
+	assert.Equal(t, "users", client.ErrorWithStackSkipWithExtrasInExtras["table"])
+	assert.Len(t, client.ErrorWithStackSkipWithExtrasInExtras[rollbarTraceChainKey], 2)
+
+	req := &http.Request{}
+	reporter.HTTPError(req, err)
+
+	assert.Nil(t, client.RequestErrorWithStackSkipInError)
+	assert.Equal(t, err, client.RequestErrorWithStackSkipWithExtrasInError)
+	assert.Equal(t, "users", client.RequestErrorWithStackSkipWithExtrasInExtras["table"])
+}
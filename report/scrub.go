@@ -0,0 +1,288 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	rollbar "github.com/rollbar/rollbar-go"
+)
+
+const (
+	defaultMaxBodySize = 4096
+
+	// rollbarRequestKey is the extras key for passing a scrubbed request to rollbarTransform
+	rollbarRequestKey = "__request"
+	// rollbarPersonKey is the extras key for passing the authenticated user to rollbarTransform
+	rollbarPersonKey = "__person"
+)
+
+var (
+	defaultScrubHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+	defaultScrubFields  = []string{"password", "secret", "token"}
+
+	defaultScrubber = newRequestScrubber(RollbarOptions{})
+)
+
+// RequestContext contains the authenticated user and the id of an http request
+// The user is only reported if UserID is set.
+type RequestContext struct {
+	UserID    string
+	Username  string
+	Email     string
+	RequestID string
+}
+
+// requestScrubber creates scrubbed payloads of http requests for reporting
+type requestScrubber struct {
+	headers     *regexp.Regexp
+	fields      *regexp.Regexp
+	maxBodySize int
+	context     func(*http.Request) RequestContext
+}
+
+func newRequestScrubber(opts RollbarOptions) *requestScrubber {
+	if len(opts.ScrubHeaders) == 0 {
+		opts.ScrubHeaders = defaultScrubHeaders
+	}
+
+	if len(opts.ScrubFields) == 0 {
+		opts.ScrubFields = defaultScrubFields
+	}
+
+	if opts.MaxBodySize == 0 {
+		opts.MaxBodySize = defaultMaxBodySize
+	}
+
+	return &requestScrubber{
+		headers:     namesRegexp(opts.ScrubHeaders, true),
+		fields:      namesRegexp(opts.ScrubFields, false),
+		maxBodySize: opts.MaxBodySize,
+		context:     opts.RequestContext,
+	}
+}
+
+// namesRegexp creates a case-insensitive regular expression matching any of the names
+// If exact is false, any string containing one of the names matches.
+func namesRegexp(names []string, exact bool) *regexp.Regexp {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = regexp.QuoteMeta(name)
+	}
+
+	expr := strings.Join(quoted, "|")
+	if exact {
+		return regexp.MustCompile("(?i)^(?:" + expr + ")$")
+	}
+
+	return regexp.MustCompile("(?i)" + expr)
+}
+
+// extras adds the scrubbed request, the authenticated user, and the request id to the extras of an error report
+// Metadata already in extras takes precedence over the request id.
+func (s *requestScrubber) extras(req *http.Request, extras map[string]interface{}) map[string]interface{} {
+	extras = copyMap(extras)
+	extras[rollbarRequestKey] = s.request(req)
+
+	if s.context != nil {
+		rc := s.context(req)
+
+		if rc.UserID != "" {
+			person := map[string]interface{}{"id": rc.UserID}
+			if rc.Username != "" {
+				person["username"] = rc.Username
+			}
+			if rc.Email != "" {
+				person["email"] = rc.Email
+			}
+			extras[rollbarPersonKey] = person
+		}
+
+		if _, ok := extras["requestId"]; !ok && rc.RequestID != "" {
+			extras["requestId"] = rc.RequestID
+		}
+	}
+
+	return extras
+}
+
+// request creates a Rollbar request payload with sensitive headers, query parameters, and body fields replaced
+// Only JSON and form bodies not larger than the maximum body size are reported, since other bodies cannot be scrubbed.
+func (s *requestScrubber) request(req *http.Request) map[string]interface{} {
+	payload := map[string]interface{}{
+		"method":  req.Method,
+		"user_ip": remoteIP(req),
+	}
+
+	headers := map[string]interface{}{}
+	for name, values := range req.Header {
+		if s.headers.MatchString(name) {
+			headers[name] = rollbar.FILTERED
+		} else {
+			headers[name] = strings.Join(values, ", ")
+		}
+	}
+	payload["headers"] = headers
+
+	if req.URL != nil {
+		query := s.scrubValues(req.URL.Query())
+		u := *req.URL
+		u.User = nil
+		u.RawQuery = query.Encode()
+
+		// Server requests only have the path and the query in their urls
+		if u.Host == "" && req.Host != "" {
+			u.Host = req.Host
+			u.Scheme = "http"
+			if req.TLS != nil {
+				u.Scheme = "https"
+			}
+		}
+
+		payload["url"] = u.String()
+		payload["query_string"] = u.RawQuery
+		payload["GET"] = flattenValues(query)
+	}
+
+	body, form := s.body(req)
+	if body != "" {
+		payload["body"] = body
+	}
+
+	if form != nil {
+		payload["POST"] = flattenValues(s.scrubValues(form))
+	} else if len(req.PostForm) > 0 {
+		payload["POST"] = flattenValues(s.scrubValues(req.PostForm))
+	}
+
+	return payload
+}
+
+// body returns a scrubbed JSON body or an unscrubbed form body of a request
+func (s *requestScrubber) body(req *http.Request) (string, url.Values) {
+	if s.maxBodySize < 0 {
+		return "", nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if mediaType != "application/json" && mediaType != "application/x-www-form-urlencoded" {
+		return "", nil
+	}
+
+	b, ok := readBody(req, s.maxBodySize)
+	if !ok || len(b) == 0 {
+		return "", nil
+	}
+
+	if mediaType == "application/x-www-form-urlencoded" {
+		form, err := url.ParseQuery(string(b))
+		if err != nil {
+			return "", nil
+		}
+		return "", form
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return "", nil
+	}
+
+	b, err := json.Marshal(s.scrubJSON(v))
+	if err != nil {
+		return "", nil
+	}
+
+	return string(b), nil
+}
+
+func (s *requestScrubber) scrubValues(values url.Values) url.Values {
+	scrubbed := url.Values{}
+	for name, vals := range values {
+		if s.fields.MatchString(name) {
+			scrubbed[name] = []string{rollbar.FILTERED}
+		} else {
+			scrubbed[name] = vals
+		}
+	}
+
+	return scrubbed
+}
+
+func (s *requestScrubber) scrubJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, val := range v {
+			if s.fields.MatchString(key) {
+				v[key] = rollbar.FILTERED
+			} else {
+				v[key] = s.scrubJSON(val)
+			}
+		}
+	case []interface{}:
+		for i, val := range v {
+			v[i] = s.scrubJSON(val)
+		}
+	}
+
+	return v
+}
+
+// readBody reads up to max bytes of a request body without consuming it
+// It returns false if the body is larger than max bytes or cannot be read.
+func readBody(req *http.Request, max int) ([]byte, bool) {
+	if req.GetBody != nil {
+		rc, err := req.GetBody()
+		if err != nil {
+			return nil, false
+		}
+		defer rc.Close()
+
+		b, err := ioutil.ReadAll(io.LimitReader(rc, int64(max)+1))
+		return b, err == nil && len(b) <= max
+	}
+
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, true
+	}
+
+	b, err := ioutil.ReadAll(io.LimitReader(req.Body, int64(max)+1))
+
+	// Put back the bytes read for the handler
+	req.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(b), req.Body), req.Body}
+
+	return b, err == nil && len(b) <= max
+}
+
+func flattenValues(values url.Values) map[string]interface{} {
+	flat := make(map[string]interface{}, len(values))
+	for name, vals := range values {
+		if len(vals) == 1 {
+			flat[name] = vals[0]
+		} else {
+			flat[name] = vals
+		}
+	}
+
+	return flat
+}
+
+func remoteIP(req *http.Request) string {
+	if ip := req.Header.Get("X-Real-IP"); ip != "" {
+		return ip
+	}
+
+	if ips := req.Header.Get("X-Forwarded-For"); ips != "" {
+		return strings.TrimSpace(strings.Split(ips, ",")[0])
+	}
+
+	return req.RemoteAddr
+}
@@ -0,0 +1,467 @@
+package report
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	rollbar "github.com/rollbar/rollbar-go"
+)
+
+const (
+	sentryVersion = 7
+	sentryClient  = "goto-report/1.0"
+
+	defaultSentryQueueSize = 100
+
+	sentryLevelError = "error"
+	sentryLevelFatal = "fatal"
+
+	// Number of frames for runtime.Callers, callerFrames, newEvent, report, and the public method of SentryReporter
+	sentrySkipFrames = 5
+)
+
+type (
+	// SentryOptions contains optional options for SentryReporter
+	SentryOptions struct {
+		// DSN is the Sentry data source name (i.e. https://public-key@o0.ingest.sentry.io/project-id)
+		DSN         string
+		Environment string
+		Release     string
+		// ServerName is the name of host reporting errors (default hostname)
+		ServerName string
+		// Client is the http client for sending events (default with a 10s timeout)
+		Client *http.Client
+		// OnError is called with errors from sending events
+		OnError func(error)
+		// QueueSize is the maximum number of events waiting to be sent (default 100)
+		// Events reported while the queue is full are dropped.
+		QueueSize int
+		// ScrubHeaders are the names of request headers replaced with [FILTERED] (default Authorization, Proxy-Authorization, Cookie, Set-Cookie, X-Api-Key)
+		ScrubHeaders []string
+		// ScrubFields are the names of query parameters replaced with [FILTERED] (default password, secret, token)
+		// A field is scrubbed if its name contains one of the names.
+		ScrubFields []string
+		skipDepth   int
+	}
+
+	// SentryReporter reports errors to Sentry or any service implementing Sentry envelope protocol
+	// Errors are sent asynchronously and Wait can be used for blocking until they are sent.
+	SentryReporter struct {
+		sync.Mutex
+		opts      SentryOptions
+		endpoint  string
+		auth      string
+		skipDepth int
+		scrubber  *requestScrubber
+		queue     chan *sentryEvent
+		dropped   int64
+		once      sync.Once
+		// pending is the number of queued events not sent yet and sent is signaled when it drops to zero
+		pending int
+		sent    *sync.Cond
+		closed  bool
+	}
+
+	sentryEvent struct {
+		EventID     string                 `json:"event_id"`
+		Timestamp   string                 `json:"timestamp"`
+		Level       string                 `json:"level"`
+		Platform    string                 `json:"platform"`
+		Environment string                 `json:"environment,omitempty"`
+		Release     string                 `json:"release,omitempty"`
+		ServerName  string                 `json:"server_name,omitempty"`
+		Exception   sentryExceptions       `json:"exception"`
+		Request     *sentryRequest         `json:"request,omitempty"`
+		Extra       map[string]interface{} `json:"extra,omitempty"`
+	}
+
+	sentryExceptions struct {
+		Values []sentryException `json:"values"`
+	}
+
+	sentryException struct {
+		Type       string            `json:"type"`
+		Value      string            `json:"value"`
+		Stacktrace *sentryStacktrace `json:"stacktrace,omitempty"`
+	}
+
+	sentryStacktrace struct {
+		Frames []sentryFrame `json:"frames"`
+	}
+
+	sentryFrame struct {
+		Function string `json:"function"`
+		Filename string `json:"filename"`
+		Lineno   int    `json:"lineno"`
+	}
+
+	sentryRequest struct {
+		URL         string            `json:"url"`
+		Method      string            `json:"method"`
+		QueryString string            `json:"query_string,omitempty"`
+		Headers     map[string]string `json:"headers,omitempty"`
+	}
+)
+
+// NewSentryReporter creates a new reporter for Sentry
+func NewSentryReporter(opts SentryOptions) (*SentryReporter, error) {
+	endpoint, auth, err := parseSentryDSN(opts.DSN)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.ServerName == "" {
+		opts.ServerName, _ = os.Hostname()
+	}
+
+	if opts.Client == nil {
+		opts.Client = &http.Client{
+			Timeout: 10 * time.Second,
+		}
+	}
+
+	if opts.OnError == nil {
+		opts.OnError = func(error) {}
+	}
+
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = defaultSentryQueueSize
+	}
+
+	if opts.skipDepth == 0 {
+		opts.skipDepth = defaultSkipDepth
+	}
+
+	r := &SentryReporter{
+		opts:      opts,
+		endpoint:  endpoint,
+		auth:      auth,
+		skipDepth: opts.skipDepth,
+		scrubber: newRequestScrubber(RollbarOptions{
+			ScrubHeaders: opts.ScrubHeaders,
+			ScrubFields:  opts.ScrubFields,
+		}),
+		queue: make(chan *sentryEvent, opts.QueueSize),
+	}
+
+	r.sent = sync.NewCond(r)
+
+	return r, nil
+}
+
+// parseSentryDSN returns the envelope endpoint and the authentication header for a DSN
+func parseSentryDSN(dsn string) (string, string, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", "", err
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", "", fmt.Errorf("invalid sentry dsn scheme: %q", u.Scheme)
+	}
+
+	if u.User == nil || u.User.Username() == "" {
+		return "", "", errors.New("sentry dsn has no public key")
+	}
+
+	path := strings.TrimSuffix(u.Path, "/")
+	i := strings.LastIndex(path, "/")
+	projectID := path[i+1:]
+	if projectID == "" {
+		return "", "", errors.New("sentry dsn has no project id")
+	}
+
+	endpoint := fmt.Sprintf("%s://%s%s/api/%s/envelope/", u.Scheme, u.Host, path[:i], projectID)
+	auth := fmt.Sprintf("Sentry sentry_version=%d, sentry_client=%s, sentry_key=%s", sentryVersion, sentryClient, u.User.Username())
+
+	return endpoint, auth, nil
+}
+
+// OnPanic reports a panic and should be used with defer
+func (r *SentryReporter) OnPanic() {
+	if e := recover(); e != nil {
+		err := fmt.Errorf("panic occurred: %v", e)
+		// Skip the frame of runtime panic too
+		r.report(sentryLevelFatal, err, nil, nil, r.skipDepth+1)
+		r.Wait()
+		panic(e)
+	}
+
+	r.Wait()
+}
+
+// Error reports an error
+func (r *SentryReporter) Error(err error) {
+	r.report(sentryLevelError, err, nil, nil, r.skipDepth)
+}
+
+// ErrorWithMetadata reports an error with extra metadata
+func (r *SentryReporter) ErrorWithMetadata(err error, metadata map[string]interface{}) {
+	r.report(sentryLevelError, err, nil, metadata, r.skipDepth)
+}
+
+// HTTPError reports an error for an http request
+func (r *SentryReporter) HTTPError(req *http.Request, err error) {
+	r.report(sentryLevelError, err, req, nil, r.skipDepth)
+}
+
+// HTTPErrorWithMetadata reports an error for an http request with extra metdata
+func (r *SentryReporter) HTTPErrorWithMetadata(req *http.Request, err error, metadata map[string]interface{}) {
+	r.report(sentryLevelError, err, req, metadata, r.skipDepth)
+}
+
+// Wait blocks until all errors are reported
+func (r *SentryReporter) Wait() {
+	r.Lock()
+	defer r.Unlock()
+
+	for r.pending > 0 {
+		r.sent.Wait()
+	}
+}
+
+// Close blocks until all errors are reported and stops the reporter
+// Errors reported after Close are dropped.
+func (r *SentryReporter) Close() {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.closed {
+		return
+	}
+
+	r.closed = true
+	for r.pending > 0 {
+		r.sent.Wait()
+	}
+
+	// Nothing is queued after the reporter is closed
+	close(r.queue)
+}
+
+// Dropped returns the number of errors dropped so far because the queue was full
+func (r *SentryReporter) Dropped() int64 {
+	return atomic.LoadInt64(&r.dropped)
+}
+
+func (r *SentryReporter) report(level string, err error, req *http.Request, metadata map[string]interface{}, skip int) {
+	if err == nil {
+		return
+	}
+
+	event := r.newEvent(level, err, req, metadata, skip)
+
+	r.once.Do(func() {
+		go r.run()
+	})
+
+	r.Lock()
+	defer r.Unlock()
+
+	if r.closed {
+		atomic.AddInt64(&r.dropped, 1)
+		return
+	}
+
+	select {
+	case r.queue <- event:
+		r.pending++
+	default:
+		atomic.AddInt64(&r.dropped, 1)
+	}
+}
+
+// run sends queued events one at a time
+func (r *SentryReporter) run() {
+	for event := range r.queue {
+		if err := r.send(event); err != nil {
+			r.opts.OnError(err)
+		}
+
+		r.Lock()
+		r.pending--
+		if r.pending == 0 {
+			r.sent.Broadcast()
+		}
+		r.Unlock()
+	}
+}
+
+func (r *SentryReporter) newEvent(level string, err error, req *http.Request, metadata map[string]interface{}, skip int) *sentryEvent {
+	frames := callerFramesOf(err)
+	if frames == nil {
+		frames = callerFrames(sentrySkipFrames + skip)
+	}
+
+	event := &sentryEvent{
+		EventID:     newSentryEventID(),
+		Timestamp:   time.Now().UTC().Format(time.RFC3339Nano),
+		Level:       level,
+		Platform:    "go",
+		Environment: r.opts.Environment,
+		Release:     r.opts.Release,
+		ServerName:  r.opts.ServerName,
+		Exception: sentryExceptions{
+			Values: sentryExceptionValues(err, frames),
+		},
+		Extra: mergeFields(err, metadata),
+	}
+
+	if req != nil {
+		event.Request = r.newRequest(req)
+	}
+
+	return event
+}
+
+// newRequest creates a Sentry request with sensitive headers and query parameters replaced
+func (r *SentryReporter) newRequest(req *http.Request) *sentryRequest {
+	headers := map[string]string{}
+	for k := range req.Header {
+		if r.scrubber.headers.MatchString(k) {
+			headers[k] = rollbar.FILTERED
+		} else {
+			headers[k] = req.Header.Get(k)
+		}
+	}
+
+	var u, query string
+	if req.URL != nil {
+		query = r.scrubber.scrubValues(req.URL.Query()).Encode()
+
+		c := *req.URL
+		c.User = nil
+		c.RawQuery = query
+		if req.Host != "" && c.Host == "" {
+			c.Host = req.Host
+			c.Scheme = "http"
+			if req.TLS != nil {
+				c.Scheme = "https"
+			}
+		}
+		u = c.String()
+	}
+
+	return &sentryRequest{
+		URL:         u,
+		Method:      req.Method,
+		QueryString: query,
+		Headers:     headers,
+	}
+}
+
+// sentryExceptionValues returns the exceptions for an error and all of its causes ordered from the innermost to the outermost error
+// Errors with an embedded stack trace keep their stack traces.
+// If no error has an embedded stack trace, the outermost error gets the stack trace of reporting site.
+func sentryExceptionValues(err error, reportFrames []runtime.Frame) []sentryException {
+	chain := causeChain(err)
+	if len(chain) == 0 {
+		return nil
+	}
+
+	if !hasEmbeddedStack(chain) {
+		chain[0].Frames = reportFrames
+	}
+
+	values := make([]sentryException, len(chain))
+	for i, c := range chain {
+		e := sentryException{
+			Type:  c.Type,
+			Value: c.Message,
+		}
+
+		if c.Frames != nil {
+			e.Stacktrace = &sentryStacktrace{
+				Frames: sentryFrames(c.Frames),
+			}
+		}
+
+		values[len(chain)-1-i] = e
+	}
+
+	return values
+}
+
+// sentryFrames converts stack frames to Sentry frames ordered from the oldest to the newest call
+func sentryFrames(frames []runtime.Frame) []sentryFrame {
+	result := make([]sentryFrame, len(frames))
+	for i, f := range frames {
+		result[len(frames)-1-i] = sentryFrame{
+			Function: f.Function,
+			Filename: f.File,
+			Lineno:   f.Line,
+		}
+	}
+
+	return result
+}
+
+func newSentryEventID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// send posts an event in an envelope
+// See https://develop.sentry.dev/sdk/envelopes
+func (r *SentryReporter) send(event *sentryEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	header, _ := json.Marshal(map[string]string{
+		"event_id": event.EventID,
+		"sent_at":  time.Now().UTC().Format(time.RFC3339Nano),
+	})
+
+	itemHeader, _ := json.Marshal(map[string]interface{}{
+		"type":   "event",
+		"length": len(payload),
+	})
+
+	body := new(bytes.Buffer)
+	body.Write(header)
+	body.WriteByte('\n')
+	body.Write(itemHeader)
+	body.WriteByte('\n')
+	body.Write(payload)
+	body.WriteByte('\n')
+
+	req, err := http.NewRequest("POST", r.endpoint, body)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", r.auth)
+
+	res, err := r.opts.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		data, _ := ioutil.ReadAll(res.Body)
+		return fmt.Errorf("sentry endpoint %s responded with %d: %s", r.endpoint, res.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	_, _ = io.Copy(ioutil.Discard, res.Body)
+
+	return nil
+}
@@ -0,0 +1,306 @@
+package report
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultThrottleWindow      = time.Minute
+	defaultThrottleStackFrames = 3
+
+	// OccurrencesKey is the metadata key for the number of errors folded into one report
+	OccurrencesKey = "occurrences"
+
+	// Number of frames for runtime.Callers, callerFrames, report, and the public method of ThrottledReporter
+	throttleSkipFrames = 4
+)
+
+// Patterns replaced in error messages for creating message templates
+var templatePatterns = []struct {
+	re          *regexp.Regexp
+	placeholder string
+}{
+	{regexp.MustCompile(`"[^"]*"|'[^']*'`), "<str>"},
+	{regexp.MustCompile(`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b`), "<uuid>"},
+	{regexp.MustCompile(`\b0x[0-9a-fA-F]+\b`), "<hex>"},
+	{regexp.MustCompile(`\d+`), "<num>"},
+}
+
+type (
+	// ThrottleOptions contains optional options for ThrottledReporter
+	ThrottleOptions struct {
+		// Window is the duration in which repeats of an error are folded into one report (default 1m)
+		Window time.Duration
+		// StackFrames is the number of top stack frames used for grouping errors (default 3)
+		StackFrames int
+		// MaxPerMinute is the maximum number of reports sent per minute (default 0 no limit)
+		// Reports exceeding the limit are suppressed.
+		MaxPerMinute int
+		now          func() time.Time
+	}
+
+	// ThrottledReporter groups, deduplicates, and rate limits errors before reporting them to another reporter
+	// Errors are grouped by a fingerprint of their type, message template, and top stack frames.
+	// The first error of a group is reported right away and its repeats inside the window are counted.
+	// When the window is over or when Wait is called, the last repeat is reported with the number of repeats in its metadata.
+	// Errors are passed to the next reporter with the stack trace of the site where they were reported.
+	// Panics are always reported.
+	ThrottledReporter struct {
+		sync.Mutex
+		next       Reporter
+		opts       ThrottleOptions
+		groups     map[string]*errorGroup
+		minute     time.Time
+		sent       int
+		suppressed int64
+	}
+
+	errorGroup struct {
+		expires time.Time
+		repeats int
+		last    pendingReport
+	}
+
+	pendingReport struct {
+		err      error
+		req      *http.Request
+		metadata map[string]interface{}
+	}
+)
+
+// NewThrottledReporter creates a new reporter grouping and rate limiting errors
+func NewThrottledReporter(next Reporter, opts ThrottleOptions) *ThrottledReporter {
+	if opts.Window <= 0 {
+		opts.Window = defaultThrottleWindow
+	}
+
+	if opts.StackFrames <= 0 {
+		opts.StackFrames = defaultThrottleStackFrames
+	}
+
+	if opts.now == nil {
+		opts.now = time.Now
+	}
+
+	return &ThrottledReporter{
+		next:   next,
+		opts:   opts,
+		groups: map[string]*errorGroup{},
+	}
+}
+
+// OnPanic reports a panic and should be used with defer
+func (r *ThrottledReporter) OnPanic() {
+	if e := recover(); e != nil {
+		r.flush()
+		reportPanic(r.next, e)
+	}
+
+	r.Wait()
+}
+
+// Error reports an error
+func (r *ThrottledReporter) Error(err error) {
+	r.report(pendingReport{err: err})
+}
+
+// ErrorWithMetadata reports an error with extra metadata
+func (r *ThrottledReporter) ErrorWithMetadata(err error, metadata map[string]interface{}) {
+	r.report(pendingReport{err: err, metadata: metadata})
+}
+
+// HTTPError reports an error for an http request
+func (r *ThrottledReporter) HTTPError(req *http.Request, err error) {
+	r.report(pendingReport{err: err, req: req})
+}
+
+// HTTPErrorWithMetadata reports an error for an http request with extra metdata
+func (r *ThrottledReporter) HTTPErrorWithMetadata(req *http.Request, err error, metadata map[string]interface{}) {
+	r.report(pendingReport{err: err, req: req, metadata: metadata})
+}
+
+// Wait reports the repeats of all groups and blocks until all errors are reported
+func (r *ThrottledReporter) Wait() {
+	r.flush()
+	r.next.Wait()
+}
+
+// Suppressed returns the number of reports suppressed so far for exceeding the rate limit
+func (r *ThrottledReporter) Suppressed() int64 {
+	r.Lock()
+	defer r.Unlock()
+
+	return r.suppressed
+}
+
+func (r *ThrottledReporter) report(p pendingReport) {
+	if p.err == nil {
+		return
+	}
+
+	// Errors may be reported to the next reporter later and from another goroutine
+	frames := callerFrames(throttleSkipFrames)
+	fp := fingerprint(p.err, frames, r.opts.StackFrames)
+	p.err = withCallerFrames(p.err, frames)
+
+	r.Lock()
+
+	now := r.opts.now()
+	reports := r.expired(now)
+
+	if g, ok := r.groups[fp]; ok {
+		g.repeats++
+		g.last = p
+	} else {
+		r.groups[fp] = &errorGroup{
+			expires: now.Add(r.opts.Window),
+		}
+		reports = append(reports, p)
+		time.AfterFunc(r.opts.Window, r.flushExpired)
+	}
+
+	reports = r.limit(now, reports)
+
+	r.Unlock()
+
+	for _, p := range reports {
+		r.send(p)
+	}
+}
+
+// flush reports the repeats of all groups
+func (r *ThrottledReporter) flush() {
+	r.Lock()
+
+	var reports []pendingReport
+	for _, g := range r.groups {
+		if g.repeats > 0 {
+			reports = append(reports, g.summary())
+			g.repeats = 0
+		}
+	}
+
+	reports = r.limit(r.opts.now(), reports)
+
+	r.Unlock()
+
+	for _, p := range reports {
+		r.send(p)
+	}
+}
+
+// flushExpired reports the repeats of expired groups
+func (r *ThrottledReporter) flushExpired() {
+	r.Lock()
+
+	now := r.opts.now()
+	reports := r.limit(now, r.expired(now))
+
+	r.Unlock()
+
+	for _, p := range reports {
+		r.send(p)
+	}
+}
+
+// expired removes expired groups and returns the reports for their repeats
+func (r *ThrottledReporter) expired(now time.Time) []pendingReport {
+	var reports []pendingReport
+	for fp, g := range r.groups {
+		if !now.Before(g.expires) {
+			if g.repeats > 0 {
+				reports = append(reports, g.summary())
+			}
+			delete(r.groups, fp)
+		}
+	}
+
+	return reports
+}
+
+// limit returns the reports allowed by the rate limit and counts the rest as suppressed
+func (r *ThrottledReporter) limit(now time.Time, reports []pendingReport) []pendingReport {
+	if r.opts.MaxPerMinute <= 0 {
+		return reports
+	}
+
+	if minute := now.Truncate(time.Minute); !minute.Equal(r.minute) {
+		r.minute = minute
+		r.sent = 0
+	}
+
+	allowed := r.opts.MaxPerMinute - r.sent
+	if allowed < 0 {
+		allowed = 0
+	}
+
+	if len(reports) > allowed {
+		r.suppressed += int64(len(reports) - allowed)
+		reports = reports[:allowed]
+	}
+
+	r.sent += len(reports)
+
+	return reports
+}
+
+func (r *ThrottledReporter) send(p pendingReport) {
+	switch {
+	case p.req != nil && p.metadata != nil:
+		r.next.HTTPErrorWithMetadata(p.req, p.err, p.metadata)
+	case p.req != nil:
+		r.next.HTTPError(p.req, p.err)
+	case p.metadata != nil:
+		r.next.ErrorWithMetadata(p.err, p.metadata)
+	default:
+		r.next.Error(p.err)
+	}
+}
+
+// summary returns the last repeat of a group with the number of repeats in its metadata
+func (g *errorGroup) summary() pendingReport {
+	metadata := map[string]interface{}{}
+	for k, v := range g.last.metadata {
+		metadata[k] = v
+	}
+	metadata[OccurrencesKey] = g.repeats
+
+	return pendingReport{
+		err:      g.last.err,
+		req:      g.last.req,
+		metadata: metadata,
+	}
+}
+
+// messageTemplate replaces variable parts of an error message such as numbers and ids with placeholders
+func messageTemplate(msg string) string {
+	for _, p := range templatePatterns {
+		msg = p.re.ReplaceAllString(msg, p.placeholder)
+	}
+
+	return msg
+}
+
+// fingerprint creates a fingerprint for an error from its type, message template, and top n stack frames of its caller
+func fingerprint(err error, frames []runtime.Frame, n int) string {
+	if len(frames) > n {
+		frames = frames[:n]
+	}
+
+	functions := make([]string, len(frames))
+	for i, f := range frames {
+		functions[i] = f.Function
+	}
+
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%T\n%s\n%s", err, messageTemplate(err.Error()), strings.Join(functions, "\n"))
+
+	return fmt.Sprintf("%016x", h.Sum64())
+}
@@ -0,0 +1,252 @@
+package report
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type customError struct {
+	msg string
+}
+
+func (e *customError) Error() string {
+	return e.msg
+}
+
+// fakeClock is a clock that only moves when it is advanced
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.t
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+func newTestThrottledReporter(opts ThrottleOptions) (*ThrottledReporter, *Recorder, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	recorder := NewRecorder()
+	opts.now = clock.now
+
+	return NewThrottledReporter(recorder, opts), recorder, clock
+}
+
+func TestMessageTemplate(t *testing.T) {
+	tests := []struct {
+		msg              string
+		expectedTemplate string
+	}{
+		{"connection refused", "connection refused"},
+		{"dial tcp 10.0.0.1:5432: connection refused", "dial tcp <num>.<num>.<num>.<num>:<num>: connection refused"},
+		{`user "alice" not found`, "user <str> not found"},
+		{"order 'b-12' is invalid", "order <str> is invalid"},
+		{"request 123e4567-e89b-12d3-a456-426614174000 timed out", "request <uuid> timed out"},
+		{"invalid address 0xc000123456", "invalid address <hex>"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.msg, func(t *testing.T) {
+			assert.Equal(t, tc.expectedTemplate, messageTemplate(tc.msg))
+		})
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	fp := func(err error) string {
+		return fingerprint(err, callerFrames(3), 3)
+	}
+
+	fp1 := fp(errors.New("query 1 failed"))
+	fp2 := fp(errors.New("query 2 failed"))
+	fp3 := fp(&customError{"query 1 failed"})
+	fp4 := fp(errors.New("insert failed"))
+	fp5 := func() string { return fp(errors.New("query 1 failed")) }()
+
+	assert.Len(t, fp1, 16)
+	assert.Equal(t, fp1, fp2, "same type, template, and stack frames")
+	assert.NotEqual(t, fp1, fp3, "different types")
+	assert.NotEqual(t, fp1, fp4, "different templates")
+	assert.NotEqual(t, fp1, fp5, "different stack frames")
+}
+
+func TestNewThrottledReporter(t *testing.T) {
+	reporter := NewThrottledReporter(NewRecorder(), ThrottleOptions{})
+
+	assert.NotNil(t, reporter)
+	assert.Equal(t, defaultThrottleWindow, reporter.opts.Window)
+	assert.Equal(t, defaultThrottleStackFrames, reporter.opts.StackFrames)
+	assert.Equal(t, 0, reporter.opts.MaxPerMinute)
+	assert.NotNil(t, reporter.opts.now)
+}
+
+func TestThrottledReporterGrouping(t *testing.T) {
+	reporter, recorder, clock := newTestThrottledReporter(ThrottleOptions{
+		Window: time.Minute,
+	})
+
+	for i := 0; i < 5; i++ {
+		reporter.ErrorWithMetadata(fmt.Errorf("query %d failed", i), map[string]interface{}{"table": "users"})
+	}
+
+	// Only the first error is reported right away
+	assert.Equal(t, []Report{
+		{Level: LevelError, Error: errors.New("query 0 failed"), Metadata: map[string]interface{}{"table": "users"}},
+	}, recorder.Reports())
+
+	// Repeats are folded into one report after the window is over
+	clock.advance(time.Minute)
+	reporter.Error(errors.New("another error"))
+
+	assert.Equal(t, []Report{
+		{Level: LevelError, Error: errors.New("query 0 failed"), Metadata: map[string]interface{}{"table": "users"}},
+		{Level: LevelError, Error: errors.New("query 4 failed"), Metadata: map[string]interface{}{"table": "users", OccurrencesKey: 4}},
+		{Level: LevelError, Error: errors.New("another error")},
+	}, recorder.Reports())
+
+	// The window of the first group is over, so the next error is reported right away
+	recorder.Reset()
+	reporter.Error(errors.New("query 5 failed"))
+	assert.Len(t, recorder.Reports(), 1)
+}
+
+func TestThrottledReporterWait(t *testing.T) {
+	reporter, recorder, _ := newTestThrottledReporter(ThrottleOptions{})
+
+	req := &http.Request{}
+	for i := 0; i < 3; i++ {
+		reporter.HTTPError(req, fmt.Errorf("request %d failed", i))
+	}
+
+	reporter.Wait()
+
+	assert.Equal(t, []Report{
+		{Level: LevelError, Error: errors.New("request 0 failed"), Request: req},
+		{Level: LevelError, Error: errors.New("request 2 failed"), Request: req, Metadata: map[string]interface{}{OccurrencesKey: 2}},
+	}, recorder.Reports())
+
+	// Waiting again does not report anything
+	recorder.Reset()
+	reporter.Wait()
+	assert.Empty(t, recorder.Reports())
+}
+
+func TestThrottledReporterNilError(t *testing.T) {
+	reporter, recorder, _ := newTestThrottledReporter(ThrottleOptions{})
+
+	reporter.Error(nil)
+	reporter.ErrorWithMetadata(nil, map[string]interface{}{"id": 1})
+	reporter.HTTPError(&http.Request{}, nil)
+	reporter.Wait()
+
+	assert.Empty(t, recorder.Reports())
+	assert.Zero(t, reporter.Suppressed())
+}
+
+func TestThrottledReporterRateLimit(t *testing.T) {
+	reporter, recorder, clock := newTestThrottledReporter(ThrottleOptions{
+		MaxPerMinute: 2,
+	})
+
+	errs := []error{
+		errors.New("first"),
+		errors.New("second"),
+		errors.New("third"),
+		errors.New("fourth"),
+	}
+
+	for _, err := range errs {
+		reporter.HTTPErrorWithMetadata(&http.Request{}, err, nil)
+	}
+
+	assert.Len(t, recorder.Reports(), 2)
+	assert.Equal(t, int64(2), reporter.Suppressed())
+
+	// The limit is reset every minute
+	clock.advance(time.Minute)
+	reporter.Error(errors.New("fifth"))
+
+	assert.Len(t, recorder.Reports(), 3)
+	assert.Equal(t, int64(2), reporter.Suppressed())
+}
+
+func TestThrottledReporterOnPanic(t *testing.T) {
+	reporter, recorder, _ := newTestThrottledReporter(ThrottleOptions{
+		MaxPerMinute: 1,
+	})
+
+	reporter.Error(errors.New("error occurred"))
+
+	defer func() {
+		r := recover()
+		assert.Equal(t, "oops", r)
+
+		// Panics are not rate limited
+		assert.Equal(t, []Report{
+			{Level: LevelError, Error: errors.New("error occurred")},
+			{Level: LevelCritical, Error: errors.New("panic occurred: oops")},
+		}, recorder.Reports())
+	}()
+
+	defer reporter.OnPanic()
+	panic("oops")
+}
+
+func TestThrottledReporterStackTrace(t *testing.T) {
+	sentry := newMockSentry(t, http.StatusOK)
+	defer sentry.server.Close()
+
+	next, err := NewSentryReporter(SentryOptions{
+		DSN: sentry.dsn(),
+	})
+	assert.NoError(t, err)
+
+	reporter := NewThrottledReporter(next, ThrottleOptions{})
+	reportError := func() {
+		reporter.Error(errors.New("error occurred"))
+	}
+
+	reportError()
+	reportError()
+
+	// The repeat is sent to the next reporter from Wait
+	reporter.Wait()
+
+	assert.Len(t, sentry.events, 2)
+	for _, event := range sentry.events {
+		exception := event["exception"].(map[string]interface{})["values"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, "*errors.errorString", exception["type"])
+
+		frames := exception["stacktrace"].(map[string]interface{})["frames"].([]interface{})
+		lastFrame := frames[len(frames)-1].(map[string]interface{})
+		assert.Equal(t, "github.com/moorara/goto/report.TestThrottledReporterStackTrace.func1", lastFrame["function"])
+	}
+}
+
+func TestThrottledReporterWindowTimer(t *testing.T) {
+	recorder := NewRecorder()
+	reporter := NewThrottledReporter(recorder, ThrottleOptions{
+		Window: 20 * time.Millisecond,
+	})
+
+	for i := 0; i < 3; i++ {
+		reporter.Error(fmt.Errorf("query %d failed", i))
+	}
+
+	assert.Len(t, recorder.Reports(), 1)
+
+	// Repeats are reported when the window is over without another report or Wait
+	time.Sleep(100 * time.Millisecond)
+
+	assert.Equal(t, []Report{
+		{Level: LevelError, Error: errors.New("query 0 failed")},
+		{Level: LevelError, Error: errors.New("query 2 failed"), Metadata: map[string]interface{}{OccurrencesKey: 2}},
+	}, recorder.Reports())
+}
@@ -0,0 +1,198 @@
+package trace
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	opentracing "github.com/opentracing/opentracing-go"
+	opentracingLog "github.com/opentracing/opentracing-go/log"
+	jaeger "github.com/uber/jaeger-client-go"
+)
+
+// Keys for stamping trace and span ids on log lines
+const (
+	traceIDLogKey = "traceId"
+	spanIDLogKey  = "spanId"
+	spanLogKey    = "span"
+)
+
+// bridgeTracer wraps a tracer and mirrors span log events into a logger
+type bridgeTracer struct {
+	opentracing.Tracer
+	logger log.Logger
+}
+
+func newBridgeTracer(tracer opentracing.Tracer, logger log.Logger) *bridgeTracer {
+	if logger == nil {
+		logger = log.NewNopLogger()
+	}
+
+	return &bridgeTracer{
+		Tracer: tracer,
+		logger: logger,
+	}
+}
+
+// StartSpan implements opentracing.Tracer interface
+func (t *bridgeTracer) StartSpan(operationName string, opts ...opentracing.StartSpanOption) opentracing.Span {
+	return &bridgeSpan{
+		Span:      t.Tracer.StartSpan(operationName, opts...),
+		tracer:    t,
+		operation: operationName,
+	}
+}
+
+// bridgeSpan wraps a span and mirrors its log events into the logger of its tracer
+type bridgeSpan struct {
+	opentracing.Span
+	sync.Mutex
+	tracer    *bridgeTracer
+	operation string
+}
+
+// Tracer implements opentracing.Span interface
+func (s *bridgeSpan) Tracer() opentracing.Tracer {
+	return s.tracer
+}
+
+// SetOperationName implements opentracing.Span interface
+func (s *bridgeSpan) SetOperationName(operationName string) opentracing.Span {
+	s.Lock()
+	s.operation = operationName
+	s.Unlock()
+
+	s.Span.SetOperationName(operationName)
+
+	return s
+}
+
+// SetTag implements opentracing.Span interface
+func (s *bridgeSpan) SetTag(key string, value interface{}) opentracing.Span {
+	s.Span.SetTag(key, value)
+	return s
+}
+
+// SetBaggageItem implements opentracing.Span interface
+func (s *bridgeSpan) SetBaggageItem(key, val string) opentracing.Span {
+	s.Span.SetBaggageItem(key, val)
+	return s
+}
+
+// LogFields implements opentracing.Span interface
+func (s *bridgeSpan) LogFields(fields ...opentracingLog.Field) {
+	s.Span.LogFields(fields...)
+
+	kv := make([]interface{}, 0, 2*len(fields))
+	for _, f := range fields {
+		kv = append(kv, f.Key(), f.Value())
+	}
+
+	s.mirror(kv)
+}
+
+// LogKV implements opentracing.Span interface
+func (s *bridgeSpan) LogKV(alternatingKeyValues ...interface{}) {
+	s.Span.LogKV(alternatingKeyValues...)
+	s.mirror(alternatingKeyValues)
+}
+
+// mirror logs the key-value pairs of a span log event with the trace and span ids
+// Events with an error event or an error field are logged at error level and other events are logged at info level.
+func (s *bridgeSpan) mirror(kv []interface{}) {
+	s.Lock()
+	operation := s.operation
+	s.Unlock()
+
+	logger := log.With(s.tracer.logger, spanLogKey, operation)
+	if traceID, spanID, ok := spanIDs(s); ok {
+		logger = log.With(logger, traceIDLogKey, traceID, spanIDLogKey, spanID)
+	}
+
+	isError := false
+	for i := 0; i+1 < len(kv); i += 2 {
+		if k := fmt.Sprint(kv[i]); (k == "event" && kv[i+1] == "error") || k == "error" || k == "error.object" {
+			isError = true
+		}
+	}
+
+	if isError {
+		_ = level.Error(logger).Log(kv...)
+	} else {
+		_ = level.Info(logger).Log(kv...)
+	}
+}
+
+// spanIDs returns the trace and span ids of a Jaeger span
+func spanIDs(span opentracing.Span) (string, string, bool) {
+	ctx, ok := span.Context().(jaeger.SpanContext)
+	if !ok {
+		return "", "", false
+	}
+
+	return ctx.TraceID().String(), ctx.SpanID().String(), true
+}
+
+// spanLogger is a logger attaching log events to a span
+type spanLogger struct {
+	logger log.Logger
+	span   opentracing.Span
+}
+
+// Log implements log.Logger interface
+func (l *spanLogger) Log(keyvals ...interface{}) error {
+	fields := make([]opentracingLog.Field, 0, (len(keyvals)+1)/2)
+	for i := 0; i < len(keyvals); i += 2 {
+		key := fmt.Sprint(keyvals[i])
+
+		var val interface{} = log.ErrMissingValue
+		if i+1 < len(keyvals) {
+			val = keyvals[i+1]
+		}
+
+		switch v := val.(type) {
+		case string:
+			fields = append(fields, opentracingLog.String(key, v))
+		case bool:
+			fields = append(fields, opentracingLog.Bool(key, v))
+		case int:
+			fields = append(fields, opentracingLog.Int(key, v))
+		case int64:
+			fields = append(fields, opentracingLog.Int64(key, v))
+		case float64:
+			fields = append(fields, opentracingLog.Float64(key, v))
+		default:
+			fields = append(fields, opentracingLog.String(key, fmt.Sprint(v)))
+		}
+	}
+
+	l.span.LogFields(fields...)
+
+	return l.logger.Log(keyvals...)
+}
+
+// LoggerWithSpan returns a logger stamping the trace and span ids of the span in a context on every log line
+// If the span is created by a tracer with LogBridge option, log events are also attached to the span.
+// If the context has no span, the logger is returned as it is.
+func LoggerWithSpan(ctx context.Context, logger log.Logger) log.Logger {
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil {
+		return logger
+	}
+
+	if traceID, spanID, ok := spanIDs(span); ok {
+		logger = log.With(logger, traceIDLogKey, traceID, spanIDLogKey, spanID)
+	}
+
+	if bs, ok := span.(*bridgeSpan); ok {
+		// Log events are attached to the wrapped span, so they are not mirrored back into the logger of tracer
+		return &spanLogger{
+			logger: logger,
+			span:   bs.Span,
+		}
+	}
+
+	return logger
+}
@@ -0,0 +1,208 @@
+package trace
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	opentracing "github.com/opentracing/opentracing-go"
+	opentracingLog "github.com/opentracing/opentracing-go/log"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/stretchr/testify/assert"
+	jaeger "github.com/uber/jaeger-client-go"
+)
+
+func decodeLogLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	lines := []map[string]interface{}{}
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var line map[string]interface{}
+		assert.NoError(t, dec.Decode(&line))
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+func TestBridgeTracer(t *testing.T) {
+	tests := []struct {
+		name          string
+		log           func(opentracing.Span)
+		expectedLevel string
+		expectedKV    map[string]interface{}
+	}{
+		{
+			name: "LogFields",
+			log: func(span opentracing.Span) {
+				span.LogFields(opentracingLog.String("event", "cache miss"), opentracingLog.Int("attempt", 2))
+			},
+			expectedLevel: "info",
+			expectedKV:    map[string]interface{}{"event": "cache miss", "attempt": float64(2)},
+		},
+		{
+			name: "LogKV",
+			log: func(span opentracing.Span) {
+				span.LogKV("event", "retry", "delay", "100ms")
+			},
+			expectedLevel: "info",
+			expectedKV:    map[string]interface{}{"event": "retry", "delay": "100ms"},
+		},
+		{
+			name: "ErrorEvent",
+			log: func(span opentracing.Span) {
+				span.LogKV("event", "error", "message", "connection refused")
+			},
+			expectedLevel: "error",
+			expectedKV:    map[string]interface{}{"event": "error", "message": "connection refused"},
+		},
+		{
+			name: "ErrorField",
+			log: func(span opentracing.Span) {
+				span.LogFields(opentracingLog.Error(errors.New("connection refused")))
+			},
+			expectedLevel: "error",
+			expectedKV:    map[string]interface{}{"error": "connection refused"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			recorder := NewRecorder()
+			jtracer, close := newTestTracer(recorder)
+			defer close()
+
+			tracer := newBridgeTracer(jtracer, log.NewJSONLogger(buf))
+			span := tracer.StartSpan("query")
+			assert.Equal(t, tracer, span.Tracer())
+
+			tc.log(span)
+			span.Finish()
+
+			ctx := span.Context().(jaeger.SpanContext)
+			lines := decodeLogLines(t, buf)
+			assert.Len(t, lines, 1)
+			assert.Equal(t, tc.expectedLevel, lines[0]["level"])
+			assert.Equal(t, "query", lines[0]["span"])
+			assert.Equal(t, ctx.TraceID().String(), lines[0]["traceId"])
+			assert.Equal(t, ctx.SpanID().String(), lines[0]["spanId"])
+			for k, v := range tc.expectedKV {
+				assert.Equal(t, v, lines[0][k])
+			}
+
+			// Log events are still recorded on the span
+			spans := recorder.Spans()
+			assert.Len(t, spans, 1)
+			assert.Len(t, spans[0].Logs, 1)
+		})
+	}
+}
+
+func TestBridgeSpan(t *testing.T) {
+	buf := new(bytes.Buffer)
+	recorder := NewRecorder()
+	jtracer, close := newTestTracer(recorder)
+	defer close()
+
+	tracer := newBridgeTracer(jtracer, log.NewJSONLogger(buf))
+	parent := tracer.StartSpan("parent")
+
+	// Chained calls return the bridge span
+	span := tracer.StartSpan("child", opentracing.ChildOf(parent.Context()))
+	span = span.SetOperationName("renamed").SetTag("key", "value").SetBaggageItem("tenant", "acme")
+	assert.IsType(t, &bridgeSpan{}, span)
+
+	span.LogKV("event", "renamed")
+	span.Finish()
+	parent.Finish()
+
+	lines := decodeLogLines(t, buf)
+	assert.Len(t, lines, 1)
+	assert.Equal(t, "renamed", lines[0]["span"])
+
+	spans := recorder.SpansByOperation("renamed")
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "value", spans[0].Tags["key"])
+	assert.Equal(t, parent.Context().(jaeger.SpanContext).SpanID().String(), spans[0].ParentID)
+	assert.Equal(t, "acme", span.BaggageItem("tenant"))
+}
+
+func TestBridgeTracerNilLogger(t *testing.T) {
+	tracer := newBridgeTracer(mocktracer.New(), nil)
+	span := tracer.StartSpan("test")
+	span.LogKV("event", "test")
+	span.Finish()
+}
+
+func TestLoggerWithSpan(t *testing.T) {
+	t.Run("WithoutSpan", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		logger := LoggerWithSpan(context.Background(), log.NewJSONLogger(buf))
+		assert.NoError(t, logger.Log("message", "hello"))
+
+		lines := decodeLogLines(t, buf)
+		assert.Len(t, lines, 1)
+		assert.Nil(t, lines[0]["traceId"])
+	})
+
+	t.Run("NonBridgeSpan", func(t *testing.T) {
+		recorder := NewRecorder()
+		tracer, close := newTestTracer(recorder)
+		defer close()
+
+		span := tracer.StartSpan("test")
+		ctx := opentracing.ContextWithSpan(context.Background(), span)
+
+		buf := new(bytes.Buffer)
+		logger := LoggerWithSpan(ctx, log.NewJSONLogger(buf))
+		assert.NoError(t, logger.Log("message", "hello"))
+		span.Finish()
+
+		lines := decodeLogLines(t, buf)
+		assert.Len(t, lines, 1)
+		assert.Equal(t, span.Context().(jaeger.SpanContext).TraceID().String(), lines[0]["traceId"])
+		assert.Equal(t, span.Context().(jaeger.SpanContext).SpanID().String(), lines[0]["spanId"])
+
+		// Log events are not attached to spans without the bridge
+		assert.Len(t, recorder.Spans()[0].Logs, 0)
+	})
+
+	t.Run("BridgeSpan", func(t *testing.T) {
+		tracerBuf := new(bytes.Buffer)
+		recorder := NewRecorder()
+		jtracer, close := newTestTracer(recorder)
+		defer close()
+
+		tracer := newBridgeTracer(jtracer, log.NewJSONLogger(tracerBuf))
+		span := tracer.StartSpan("test")
+		ctx := opentracing.ContextWithSpan(context.Background(), span)
+
+		buf := new(bytes.Buffer)
+		logger := LoggerWithSpan(ctx, log.NewJSONLogger(buf))
+		assert.NoError(t, logger.Log("message", "hello", "count", 3, "ratio", 0.5, "ok", true, "size", int64(10), "err", errors.New("oops"), "missing"))
+		span.Finish()
+
+		lines := decodeLogLines(t, buf)
+		assert.Len(t, lines, 1)
+		assert.Equal(t, "hello", lines[0]["message"])
+		assert.Equal(t, span.Context().(jaeger.SpanContext).TraceID().String(), lines[0]["traceId"])
+
+		// Log events attached to spans are not mirrored back into the logger of tracer
+		assert.Equal(t, 0, tracerBuf.Len())
+
+		logs := recorder.Spans()[0].Logs
+		assert.Len(t, logs, 1)
+		assert.Equal(t, map[string]interface{}{
+			"message": "hello",
+			"count":   int64(3),
+			"ratio":   0.5,
+			"ok":      true,
+			"size":    int64(10),
+			"err":     "oops",
+			"missing": "(MISSING)",
+		}, logs[0].Fields)
+	})
+}
@@ -0,0 +1,51 @@
+package trace
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+
+	jaeger "github.com/uber/jaeger-client-go"
+)
+
+// FileReporter is a span reporter writing finished spans to a file in JSON lines format
+// It can be used for debugging traces offline.
+type FileReporter struct {
+	sync.Mutex
+	writer  io.WriteCloser
+	encoder *json.Encoder
+}
+
+// NewFileReporter creates a new span reporter appending spans to a file
+// The file is created if it does not exist.
+func NewFileReporter(path string) (*FileReporter, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileReporter{
+		writer:  f,
+		encoder: json.NewEncoder(f),
+	}, nil
+}
+
+// Report implements jaeger.Reporter interface
+// Spans that cannot be written are dropped.
+func (r *FileReporter) Report(span *jaeger.Span) {
+	data := newSpanData(span)
+
+	r.Lock()
+	defer r.Unlock()
+
+	_ = r.encoder.Encode(data)
+}
+
+// Close implements jaeger.Reporter interface
+func (r *FileReporter) Close() {
+	r.Lock()
+	defer r.Unlock()
+
+	_ = r.writer.Close()
+}
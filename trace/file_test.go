@@ -0,0 +1,98 @@
+package trace
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewFileReporter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "trace")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name          string
+		path          string
+		expectedError bool
+	}{
+		{
+			name:          "InvalidPath",
+			path:          filepath.Join(dir, "missing", "spans.jsonl"),
+			expectedError: true,
+		},
+		{
+			name:          "Success",
+			path:          filepath.Join(dir, "spans.jsonl"),
+			expectedError: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			reporter, err := NewFileReporter(tc.path)
+
+			if tc.expectedError {
+				assert.Error(t, err)
+				assert.Nil(t, reporter)
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, reporter)
+				reporter.Close()
+			}
+		})
+	}
+}
+
+func TestFileReporter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "trace")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "spans.jsonl")
+	reporter, err := NewFileReporter(path)
+	assert.NoError(t, err)
+
+	tracer, close := newTestTracer(reporter)
+
+	span := tracer.StartSpan("query")
+	span.SetTag("db.type", "sql")
+	span.SetTag("db.rows", 10)
+	span.LogKV("event", "rows fetched")
+	span.Finish()
+
+	span = tracer.StartSpan("publish")
+	span.Finish()
+
+	close()
+
+	f, err := os.Open(path)
+	assert.NoError(t, err)
+	defer f.Close()
+
+	var spans []SpanData
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var s SpanData
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &s))
+		spans = append(spans, s)
+	}
+
+	assert.Len(t, spans, 2)
+
+	assert.NotEmpty(t, spans[0].TraceID)
+	assert.NotEmpty(t, spans[0].SpanID)
+	assert.Equal(t, "test_service", spans[0].Service)
+	assert.Equal(t, "query", spans[0].Operation)
+	assert.Equal(t, "sql", spans[0].Tags["db.type"])
+	assert.Equal(t, float64(10), spans[0].Tags["db.rows"])
+	assert.Len(t, spans[0].Logs, 1)
+	assert.Equal(t, "rows fetched", spans[0].Logs[0].Fields["event"])
+
+	assert.Equal(t, "publish", spans[1].Operation)
+}
@@ -0,0 +1,124 @@
+package trace
+
+import (
+	"context"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	opentracingLog "github.com/opentracing/opentracing-go/log"
+)
+
+// FinishFunc finishes a span and tags the span with an error if the error is not nil
+type FinishFunc func(err error)
+
+// tracerFromContext returns the tracer of the span in a context or the global tracer
+func tracerFromContext(ctx context.Context) (opentracing.Tracer, opentracing.Span) {
+	if parent := opentracing.SpanFromContext(ctx); parent != nil {
+		return parent.Tracer(), parent
+	}
+
+	return opentracing.GlobalTracer(), nil
+}
+
+// finishFunc creates a FinishFunc for a span
+func finishFunc(span opentracing.Span) FinishFunc {
+	return func(err error) {
+		if err != nil {
+			ext.Error.Set(span, true)
+			span.LogFields(
+				opentracingLog.String("event", "error"),
+				opentracingLog.String("message", err.Error()),
+			)
+		}
+
+		span.Finish()
+	}
+}
+
+// StartSpan starts a new span as a child of the span in a context
+// If the context has no span, a root span is started using the global tracer.
+// It returns a new context with the new span and a function for finishing the span.
+func StartSpan(ctx context.Context, name string, tags ...opentracing.Tag) (context.Context, FinishFunc) {
+	tracer, parent := tracerFromContext(ctx)
+
+	opts := make([]opentracing.StartSpanOption, 0, len(tags)+1)
+	if parent != nil {
+		opts = append(opts, opentracing.ChildOf(parent.Context()))
+	}
+	for _, tag := range tags {
+		opts = append(opts, tag)
+	}
+
+	span := tracer.StartSpan(name, opts...)
+	ctx = opentracing.ContextWithSpan(ctx, span)
+
+	return ctx, finishFunc(span)
+}
+
+// Go runs a function in a new goroutine with a new span following from the span in a context
+// The span is finished when the function returns and tagged with the error returned from the function.
+// The error is also sent to the returned channel.
+func Go(ctx context.Context, name string, fn func(context.Context) error) <-chan error {
+	tracer, parent := tracerFromContext(ctx)
+
+	var opts []opentracing.StartSpanOption
+	if parent != nil {
+		opts = append(opts, opentracing.FollowsFrom(parent.Context()))
+	}
+
+	// The span is started before the goroutine, so its start time includes scheduling the goroutine
+	span := tracer.StartSpan(name, opts...)
+	ctx = opentracing.ContextWithSpan(ctx, span)
+	finish := finishFunc(span)
+
+	errCh := make(chan error, 1)
+	go func() {
+		err := fn(ctx)
+		finish(err)
+		errCh <- err
+	}()
+
+	return errCh
+}
+
+// SetBaggageItem sets a baggage item on the span in a context
+// Baggage items are propagated to all descendant spans including the ones in other processes.
+// It returns false if the context has no span.
+func SetBaggageItem(ctx context.Context, key, value string) bool {
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil {
+		return false
+	}
+
+	span.SetBaggageItem(key, value)
+
+	return true
+}
+
+// BaggageItem returns the value of a baggage item from the span in a context
+// It returns an empty string if the context has no span or the baggage item is not set.
+func BaggageItem(ctx context.Context, key string) string {
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil {
+		return ""
+	}
+
+	return span.BaggageItem(key)
+}
+
+// BaggageItems returns all baggage items from the span in a context
+func BaggageItems(ctx context.Context) map[string]string {
+	items := map[string]string{}
+
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil {
+		return items
+	}
+
+	span.Context().ForeachBaggageItem(func(k, v string) bool {
+		items[k] = v
+		return true
+	})
+
+	return items
+}
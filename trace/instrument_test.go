@@ -0,0 +1,216 @@
+package trace
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/stretchr/testify/assert"
+)
+
+// referenceTracer is a mock tracer recording the reference types of started spans
+type referenceTracer struct {
+	*mocktracer.MockTracer
+	references []opentracing.SpanReferenceType
+}
+
+func (t *referenceTracer) StartSpan(name string, opts ...opentracing.StartSpanOption) opentracing.Span {
+	sso := opentracing.StartSpanOptions{}
+	for _, o := range opts {
+		o.Apply(&sso)
+	}
+
+	for _, ref := range sso.References {
+		t.references = append(t.references, ref.Type)
+	}
+
+	return t.MockTracer.StartSpan(name, opts...)
+}
+
+// referenceSpan is a mock span returning a referenceTracer as its tracer
+type referenceSpan struct {
+	opentracing.Span
+	tracer *referenceTracer
+}
+
+func (s *referenceSpan) Tracer() opentracing.Tracer {
+	return s.tracer
+}
+
+func TestStartSpan(t *testing.T) {
+	tests := []struct {
+		name          string
+		withParent    bool
+		tags          []opentracing.Tag
+		err           error
+		expectedTags  map[string]interface{}
+		expectedLogs  int
+		expectedError bool
+	}{
+		{
+			name:         "RootSpan",
+			withParent:   false,
+			tags:         []opentracing.Tag{{Key: "db.type", Value: "sql"}},
+			err:          nil,
+			expectedTags: map[string]interface{}{"db.type": "sql"},
+		},
+		{
+			name:         "ChildSpan",
+			withParent:   true,
+			tags:         []opentracing.Tag{{Key: "db.type", Value: "sql"}, {Key: "db.instance", Value: "users"}},
+			err:          nil,
+			expectedTags: map[string]interface{}{"db.type": "sql", "db.instance": "users"},
+		},
+		{
+			name:         "Error",
+			withParent:   true,
+			tags:         nil,
+			err:          errors.New("query error"),
+			expectedTags: map[string]interface{}{"error": true},
+			expectedLogs: 1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tracer := mocktracer.New()
+			ctx := context.Background()
+
+			var parent *mocktracer.MockSpan
+			if tc.withParent {
+				parent = tracer.StartSpan("parent").(*mocktracer.MockSpan)
+				ctx = opentracing.ContextWithSpan(ctx, parent)
+			} else {
+				opentracing.SetGlobalTracer(tracer)
+				defer opentracing.SetGlobalTracer(opentracing.NoopTracer{})
+			}
+
+			spanCtx, finish := StartSpan(ctx, "query", tc.tags...)
+			span := opentracing.SpanFromContext(spanCtx).(*mocktracer.MockSpan)
+			finish(tc.err)
+
+			assert.Len(t, tracer.FinishedSpans(), 1)
+			assert.Equal(t, span, tracer.FinishedSpans()[0])
+			assert.Equal(t, "query", span.OperationName)
+			assert.Equal(t, tc.expectedTags, span.Tags())
+			assert.Len(t, span.Logs(), tc.expectedLogs)
+
+			if tc.withParent {
+				assert.Equal(t, parent.SpanContext.SpanID, span.ParentID)
+				assert.Equal(t, parent.SpanContext.TraceID, span.SpanContext.TraceID)
+			} else {
+				assert.Equal(t, 0, span.ParentID)
+			}
+
+			if tc.err != nil {
+				fields := span.Logs()[0].Fields
+				assert.Equal(t, "event", fields[0].Key)
+				assert.Equal(t, "error", fields[0].ValueString)
+				assert.Equal(t, "message", fields[1].Key)
+				assert.Equal(t, tc.err.Error(), fields[1].ValueString)
+			}
+		})
+	}
+}
+
+func TestGo(t *testing.T) {
+	tests := []struct {
+		name               string
+		withParent         bool
+		err                error
+		expectedReferences []opentracing.SpanReferenceType
+	}{
+		{
+			name:               "WithoutParent",
+			withParent:         false,
+			expectedReferences: nil,
+		},
+		{
+			name:               "WithParent",
+			withParent:         true,
+			expectedReferences: []opentracing.SpanReferenceType{opentracing.FollowsFromRef},
+		},
+		{
+			name:               "Error",
+			withParent:         true,
+			err:                errors.New("publish error"),
+			expectedReferences: []opentracing.SpanReferenceType{opentracing.FollowsFromRef},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tracer := &referenceTracer{MockTracer: mocktracer.New()}
+			ctx := context.Background()
+
+			var parent opentracing.Span
+			if tc.withParent {
+				parent = &referenceSpan{tracer.MockTracer.StartSpan("parent"), tracer}
+				ctx = opentracing.ContextWithSpan(ctx, parent)
+			} else {
+				opentracing.SetGlobalTracer(tracer)
+				defer opentracing.SetGlobalTracer(opentracing.NoopTracer{})
+			}
+
+			var goSpan opentracing.Span
+			errCh := Go(ctx, "publish", func(ctx context.Context) error {
+				goSpan = opentracing.SpanFromContext(ctx)
+				return tc.err
+			})
+
+			assert.Equal(t, tc.err, <-errCh)
+			assert.Equal(t, tc.expectedReferences, tracer.references)
+
+			spans := tracer.FinishedSpans()
+			assert.Len(t, spans, 1)
+			assert.Equal(t, goSpan, spans[0])
+			assert.Equal(t, "publish", spans[0].OperationName)
+
+			if tc.withParent {
+				assert.Equal(t, parent.Context().(mocktracer.MockSpanContext).SpanID, spans[0].ParentID)
+			}
+
+			if tc.err != nil {
+				assert.Equal(t, true, spans[0].Tag("error"))
+			} else {
+				assert.Nil(t, spans[0].Tag("error"))
+			}
+		})
+	}
+}
+
+func TestBaggage(t *testing.T) {
+	t.Run("WithoutSpan", func(t *testing.T) {
+		ctx := context.Background()
+
+		assert.False(t, SetBaggageItem(ctx, "tenant", "acme"))
+		assert.Equal(t, "", BaggageItem(ctx, "tenant"))
+		assert.Equal(t, map[string]string{}, BaggageItems(ctx))
+	})
+
+	t.Run("WithSpan", func(t *testing.T) {
+		tracer := mocktracer.New()
+		ctx := opentracing.ContextWithSpan(context.Background(), tracer.StartSpan("parent"))
+
+		assert.True(t, SetBaggageItem(ctx, "tenant", "acme"))
+		assert.True(t, SetBaggageItem(ctx, "user", "1234"))
+		assert.Equal(t, "acme", BaggageItem(ctx, "tenant"))
+		assert.Equal(t, "", BaggageItem(ctx, "region"))
+		assert.Equal(t, map[string]string{"tenant": "acme", "user": "1234"}, BaggageItems(ctx))
+
+		// Baggage items are propagated to child spans
+		childCtx, finish := StartSpan(ctx, "child")
+		defer finish(nil)
+		assert.Equal(t, "acme", BaggageItem(childCtx, "tenant"))
+
+		errCh := Go(ctx, "async", func(ctx context.Context) error {
+			if BaggageItem(ctx, "user") != "1234" {
+				return errors.New("baggage item not propagated")
+			}
+			return nil
+		})
+		assert.NoError(t, <-errCh)
+	})
+}
@@ -0,0 +1,308 @@
+package trace
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	jaeger "github.com/uber/jaeger-client-go"
+)
+
+// OTLPEncoding is the encoding of OTLP/HTTP requests
+type OTLPEncoding int
+
+const (
+	// OTLPProtobuf encodes requests in binary protobuf format (application/x-protobuf)
+	OTLPProtobuf OTLPEncoding = iota
+	// OTLPJSON encodes requests in JSON format (application/json)
+	OTLPJSON
+)
+
+const (
+	defaultOTLPEndpoint       = "http://localhost:4318/v1/traces"
+	defaultOTLPBatchSize      = 512
+	defaultOTLPQueueSize      = 2048
+	defaultOTLPFlushInterval  = 5 * time.Second
+	defaultOTLPMaxRetries     = 5
+	defaultOTLPInitialBackoff = 100 * time.Millisecond
+	defaultOTLPMaxBackoff     = 5 * time.Second
+	defaultOTLPCloseTimeout   = 5 * time.Second
+)
+
+type (
+	// OTLPOptions contains optional options for creating an OTLPReporter
+	OTLPOptions struct {
+		// Endpoint is the full url of OTLP/HTTP traces endpoint (default http://localhost:4318/v1/traces)
+		Endpoint string
+		// Encoding is the encoding of requests (default protobuf)
+		Encoding OTLPEncoding
+		// Headers are additional http headers sent with every request (i.e. authentication)
+		Headers map[string]string
+		// Client is the http client for exporting spans (default with a 10s timeout)
+		Client *http.Client
+		// BatchSize is the maximum number of spans exported in one request (default 512)
+		BatchSize int
+		// QueueSize is the maximum number of spans waiting to be exported (default 2048)
+		// Spans reported when the queue is full are dropped.
+		QueueSize int
+		// FlushInterval is the maximum duration a span waits before being exported (default 5s)
+		FlushInterval time.Duration
+		// MaxRetries is the maximum number of retries for a failed request (default 5, negative for no retry)
+		// Requests are retried on network errors and 429, 502, 503, and 504 responses.
+		MaxRetries int
+		// InitialBackoff is the duration before the first retry and doubles after each retry (default 100ms)
+		InitialBackoff time.Duration
+		// MaxBackoff is the maximum duration between two retries (default 5s)
+		MaxBackoff time.Duration
+		// CloseTimeout is the maximum duration Close waits for failed requests to be retried (default 5s)
+		// Failed requests are not retried anymore once the timeout is over.
+		CloseTimeout time.Duration
+		// OnError is called with errors from exporting spans and dropped spans
+		OnError func(error)
+	}
+
+	// OTLPReporter is a span reporter exporting finished spans in batches to an OTLP/HTTP endpoint
+	OTLPReporter struct {
+		sync.RWMutex
+		opts   OTLPOptions
+		queue  chan SpanData
+		closed bool
+		stop   chan struct{}
+		abort  chan struct{}
+		done   chan struct{}
+		once   sync.Once
+	}
+)
+
+// NewOTLPReporter creates a new span reporter exporting spans to an OpenTelemetry collector
+func NewOTLPReporter(opts OTLPOptions) *OTLPReporter {
+	if opts.Endpoint == "" {
+		opts.Endpoint = defaultOTLPEndpoint
+	}
+
+	if opts.Client == nil {
+		opts.Client = &http.Client{
+			Timeout: 10 * time.Second,
+		}
+	}
+
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultOTLPBatchSize
+	}
+
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = defaultOTLPQueueSize
+	}
+
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = defaultOTLPFlushInterval
+	}
+
+	if opts.MaxRetries < 0 {
+		opts.MaxRetries = 0
+	} else if opts.MaxRetries == 0 {
+		opts.MaxRetries = defaultOTLPMaxRetries
+	}
+
+	if opts.InitialBackoff <= 0 {
+		opts.InitialBackoff = defaultOTLPInitialBackoff
+	}
+
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = defaultOTLPMaxBackoff
+	}
+
+	if opts.CloseTimeout <= 0 {
+		opts.CloseTimeout = defaultOTLPCloseTimeout
+	}
+
+	if opts.OnError == nil {
+		opts.OnError = func(error) {}
+	}
+
+	r := &OTLPReporter{
+		opts:  opts,
+		queue: make(chan SpanData, opts.QueueSize),
+		stop:  make(chan struct{}),
+		abort: make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+
+	go r.run()
+
+	return r
+}
+
+// Report implements jaeger.Reporter interface
+func (r *OTLPReporter) Report(span *jaeger.Span) {
+	r.RLock()
+	defer r.RUnlock()
+
+	if r.closed {
+		r.opts.OnError(errors.New("otlp reporter is closed, span dropped"))
+		return
+	}
+
+	select {
+	case r.queue <- newSpanData(span):
+	default:
+		r.opts.OnError(errors.New("otlp queue is full, span dropped"))
+	}
+}
+
+// Close implements jaeger.Reporter interface
+// It exports all queued spans and blocks until they are exported.
+// Failed requests are not retried after CloseTimeout.
+func (r *OTLPReporter) Close() {
+	r.once.Do(func() {
+		// No span is queued after the reporter is closed, so all queued spans are exported
+		r.Lock()
+		r.closed = true
+		r.Unlock()
+
+		close(r.stop)
+
+		timer := time.NewTimer(r.opts.CloseTimeout)
+		defer timer.Stop()
+
+		select {
+		case <-r.done:
+		case <-timer.C:
+			close(r.abort)
+		}
+	})
+
+	<-r.done
+}
+
+func (r *OTLPReporter) run() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.opts.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]SpanData, 0, r.opts.BatchSize)
+
+	for {
+		select {
+		case s := <-r.queue:
+			batch = append(batch, s)
+			if len(batch) >= r.opts.BatchSize {
+				r.export(batch)
+				batch = make([]SpanData, 0, r.opts.BatchSize)
+			}
+
+		case <-ticker.C:
+			if len(batch) > 0 {
+				r.export(batch)
+				batch = make([]SpanData, 0, r.opts.BatchSize)
+			}
+
+		case <-r.stop:
+			for {
+				select {
+				case s := <-r.queue:
+					batch = append(batch, s)
+					if len(batch) >= r.opts.BatchSize {
+						r.export(batch)
+						batch = make([]SpanData, 0, r.opts.BatchSize)
+					}
+				default:
+					if len(batch) > 0 {
+						r.export(batch)
+					}
+					return
+				}
+			}
+		}
+	}
+}
+
+// export sends a batch of spans and retries with exponential backoff
+func (r *OTLPReporter) export(batch []SpanData) {
+	req := newOTLPRequest(batch)
+
+	var body []byte
+	var contentType string
+	var err error
+
+	if r.opts.Encoding == OTLPJSON {
+		body, err = req.marshalJSON()
+		contentType = "application/json"
+	} else {
+		body, err = req.marshalProto()
+		contentType = "application/x-protobuf"
+	}
+
+	if err != nil {
+		r.opts.OnError(err)
+		return
+	}
+
+	backoff := r.opts.InitialBackoff
+	for retry := 0; ; retry++ {
+		retryable, err := r.send(body, contentType)
+		if err == nil {
+			return
+		}
+
+		if !retryable || retry >= r.opts.MaxRetries {
+			r.opts.OnError(fmt.Errorf("error exporting %d spans: %s", len(batch), err))
+			return
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-r.abort:
+			timer.Stop()
+			r.opts.OnError(fmt.Errorf("error exporting %d spans: %s", len(batch), err))
+			return
+		}
+
+		if backoff *= 2; backoff > r.opts.MaxBackoff {
+			backoff = r.opts.MaxBackoff
+		}
+	}
+}
+
+// send sends one request and returns whether or not a failed request can be retried
+func (r *OTLPReporter) send(body []byte, contentType string) (bool, error) {
+	req, err := http.NewRequest("POST", r.opts.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range r.opts.Headers {
+		req.Header.Set(k, v)
+	}
+
+	res, err := r.opts.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		data, _ := ioutil.ReadAll(res.Body)
+		err := fmt.Errorf("otlp endpoint %s responded with %d: %s", r.opts.Endpoint, res.StatusCode, strings.TrimSpace(string(data)))
+
+		switch res.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true, err
+		default:
+			return false, err
+		}
+	}
+
+	_, _ = io.Copy(ioutil.Discard, res.Body)
+
+	return false, nil
+}
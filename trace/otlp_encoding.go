@@ -0,0 +1,371 @@
+package trace
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/golang/protobuf/proto"
+	jaeger "github.com/uber/jaeger-client-go"
+)
+
+// otlpScopeName is the name of instrumentation scope for exported spans
+const otlpScopeName = "github.com/moorara/goto/trace"
+
+// OTLP span kinds and status codes
+const (
+	otlpSpanKindInternal = 1
+	otlpSpanKindServer   = 2
+	otlpSpanKindClient   = 3
+	otlpSpanKindProducer = 4
+	otlpSpanKindConsumer = 5
+	otlpStatusCodeError  = 2
+)
+
+// The following types model an OTLP ExportTraceServiceRequest
+// See https://github.com/open-telemetry/opentelemetry-proto/blob/main/opentelemetry/proto/trace/v1/trace.proto
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes,omitempty"`
+	}
+
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+
+	otlpSpan struct {
+		TraceID           string         `json:"traceId"`
+		SpanID            string         `json:"spanId"`
+		ParentSpanID      string         `json:"parentSpanId,omitempty"`
+		Name              string         `json:"name"`
+		Kind              int            `json:"kind"`
+		StartTimeUnixNano uint64         `json:"startTimeUnixNano,string"`
+		EndTimeUnixNano   uint64         `json:"endTimeUnixNano,string"`
+		Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+		Events            []otlpEvent    `json:"events,omitempty"`
+		Status            *otlpStatus    `json:"status,omitempty"`
+	}
+
+	otlpEvent struct {
+		TimeUnixNano uint64         `json:"timeUnixNano,string"`
+		Name         string         `json:"name"`
+		Attributes   []otlpKeyValue `json:"attributes,omitempty"`
+	}
+
+	otlpStatus struct {
+		Message string `json:"message,omitempty"`
+		Code    int    `json:"code"`
+	}
+
+	otlpKeyValue struct {
+		Key   string       `json:"key"`
+		Value otlpAnyValue `json:"value"`
+	}
+
+	otlpAnyValue struct {
+		StringValue *string  `json:"stringValue,omitempty"`
+		BoolValue   *bool    `json:"boolValue,omitempty"`
+		IntValue    *int64   `json:"intValue,string,omitempty"`
+		DoubleValue *float64 `json:"doubleValue,omitempty"`
+		BytesValue  []byte   `json:"bytesValue,omitempty"`
+	}
+)
+
+// newOTLPRequest converts a batch of spans to an OTLP request with one resource per service
+func newOTLPRequest(spans []SpanData) *otlpRequest {
+	var services []string
+	byService := map[string][]otlpSpan{}
+	for _, s := range spans {
+		if _, ok := byService[s.Service]; !ok {
+			services = append(services, s.Service)
+		}
+		byService[s.Service] = append(byService[s.Service], newOTLPSpan(s))
+	}
+
+	req := &otlpRequest{}
+	for _, service := range services {
+		req.ResourceSpans = append(req.ResourceSpans, otlpResourceSpans{
+			Resource: otlpResource{
+				Attributes: []otlpKeyValue{newOTLPKeyValue("service.name", service)},
+			},
+			ScopeSpans: []otlpScopeSpans{
+				{
+					Scope: otlpScope{Name: otlpScopeName},
+					Spans: byService[service],
+				},
+			},
+		})
+	}
+
+	return req
+}
+
+func newOTLPSpan(s SpanData) otlpSpan {
+	span := otlpSpan{
+		TraceID:           otlpTraceID(s.TraceID),
+		SpanID:            otlpSpanID(s.SpanID),
+		Name:              s.Operation,
+		Kind:              otlpSpanKindInternal,
+		StartTimeUnixNano: uint64(s.StartTime.UnixNano()),
+		EndTimeUnixNano:   uint64(s.StartTime.Add(s.Duration).UnixNano()),
+	}
+
+	if s.ParentID != "" {
+		span.ParentSpanID = otlpSpanID(s.ParentID)
+	}
+
+	for _, key := range sortedKeys(s.Tags) {
+		val := s.Tags[key]
+
+		switch key {
+		case "span.kind":
+			switch val {
+			case "server", "rpc-server":
+				span.Kind = otlpSpanKindServer
+			case "client", "rpc-client":
+				span.Kind = otlpSpanKindClient
+			case "producer":
+				span.Kind = otlpSpanKindProducer
+			case "consumer":
+				span.Kind = otlpSpanKindConsumer
+			}
+			continue
+		case "error":
+			if val == true {
+				span.Status = &otlpStatus{Code: otlpStatusCodeError}
+			}
+			continue
+		}
+
+		span.Attributes = append(span.Attributes, newOTLPKeyValue(key, val))
+	}
+
+	for _, l := range s.Logs {
+		event := otlpEvent{
+			TimeUnixNano: uint64(l.Timestamp.UnixNano()),
+			Name:         "log",
+		}
+
+		for _, key := range sortedKeys(l.Fields) {
+			if key == "event" {
+				event.Name = fmt.Sprintf("%v", l.Fields[key])
+				continue
+			}
+			event.Attributes = append(event.Attributes, newOTLPKeyValue(key, l.Fields[key]))
+		}
+
+		span.Events = append(span.Events, event)
+	}
+
+	return span
+}
+
+func newOTLPKeyValue(key string, val interface{}) otlpKeyValue {
+	kv := otlpKeyValue{Key: key}
+
+	switch v := val.(type) {
+	case string:
+		kv.Value.StringValue = &v
+	case bool:
+		kv.Value.BoolValue = &v
+	case int64:
+		kv.Value.IntValue = &v
+	case float64:
+		kv.Value.DoubleValue = &v
+	case []byte:
+		kv.Value.BytesValue = v
+	default:
+		s := fmt.Sprintf("%v", v)
+		kv.Value.StringValue = &s
+	}
+
+	return kv
+}
+
+// otlpTraceID converts a Jaeger trace id to a 32-character hex string
+func otlpTraceID(s string) string {
+	id, _ := jaeger.TraceIDFromString(s)
+	return fmt.Sprintf("%016x%016x", id.High, id.Low)
+}
+
+// otlpSpanID converts a Jaeger span id to a 16-character hex string
+func otlpSpanID(s string) string {
+	id, _ := jaeger.SpanIDFromString(s)
+	return fmt.Sprintf("%016x", uint64(id))
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// marshalJSON encodes a request in OTLP/HTTP JSON format
+// Trace and span ids are hex-encoded and 64-bit integers are encoded as strings.
+func (r *otlpRequest) marshalJSON() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+// marshalProto encodes a request in OTLP/HTTP protobuf format
+func (r *otlpRequest) marshalProto() ([]byte, error) {
+	b := proto.NewBuffer(nil)
+	for _, rs := range r.ResourceSpans {
+		protoMessage(b, 1, func(b *proto.Buffer) {
+			protoMessage(b, 1, func(b *proto.Buffer) {
+				for _, kv := range rs.Resource.Attributes {
+					protoKeyValue(b, 1, kv)
+				}
+			})
+
+			for _, ss := range rs.ScopeSpans {
+				protoMessage(b, 2, func(b *proto.Buffer) {
+					protoMessage(b, 1, func(b *proto.Buffer) {
+						protoString(b, 1, ss.Scope.Name)
+					})
+
+					for _, s := range ss.Spans {
+						protoMessage(b, 2, func(b *proto.Buffer) {
+							protoSpan(b, s)
+						})
+					}
+				})
+			}
+		})
+	}
+
+	return b.Bytes(), nil
+}
+
+// Protobuf wire types
+const (
+	protoWireVarint  = 0
+	protoWireFixed64 = 1
+	protoWireBytes   = 2
+)
+
+func protoSpan(b *proto.Buffer, s otlpSpan) {
+	traceID, _ := hex.DecodeString(s.TraceID)
+	spanID, _ := hex.DecodeString(s.SpanID)
+	parentSpanID, _ := hex.DecodeString(s.ParentSpanID)
+
+	protoBytes(b, 1, traceID)
+	protoBytes(b, 2, spanID)
+	protoBytes(b, 4, parentSpanID)
+	protoString(b, 5, s.Name)
+	protoVarint(b, 6, uint64(s.Kind))
+	protoFixed64(b, 7, s.StartTimeUnixNano)
+	protoFixed64(b, 8, s.EndTimeUnixNano)
+
+	for _, kv := range s.Attributes {
+		protoKeyValue(b, 9, kv)
+	}
+
+	for _, e := range s.Events {
+		protoMessage(b, 11, func(b *proto.Buffer) {
+			protoFixed64(b, 1, e.TimeUnixNano)
+			protoString(b, 2, e.Name)
+			for _, kv := range e.Attributes {
+				protoKeyValue(b, 3, kv)
+			}
+		})
+	}
+
+	if s.Status != nil {
+		protoMessage(b, 15, func(b *proto.Buffer) {
+			protoString(b, 2, s.Status.Message)
+			protoVarint(b, 3, uint64(s.Status.Code))
+		})
+	}
+}
+
+func protoKeyValue(b *proto.Buffer, field uint64, kv otlpKeyValue) {
+	protoMessage(b, field, func(b *proto.Buffer) {
+		protoString(b, 1, kv.Key)
+		protoMessage(b, 2, func(b *proto.Buffer) {
+			v := kv.Value
+			switch {
+			case v.StringValue != nil:
+				protoTag(b, 1, protoWireBytes)
+				_ = b.EncodeStringBytes(*v.StringValue)
+			case v.BoolValue != nil:
+				var x uint64
+				if *v.BoolValue {
+					x = 1
+				}
+				protoTag(b, 2, protoWireVarint)
+				_ = b.EncodeVarint(x)
+			case v.IntValue != nil:
+				protoTag(b, 3, protoWireVarint)
+				_ = b.EncodeVarint(uint64(*v.IntValue))
+			case v.DoubleValue != nil:
+				protoTag(b, 4, protoWireFixed64)
+				_ = b.EncodeFixed64(math.Float64bits(*v.DoubleValue))
+			case v.BytesValue != nil:
+				protoTag(b, 7, protoWireBytes)
+				_ = b.EncodeRawBytes(v.BytesValue)
+			}
+		})
+	})
+}
+
+// protoMessage encodes an embedded message
+func protoMessage(b *proto.Buffer, field uint64, encode func(*proto.Buffer)) {
+	sub := proto.NewBuffer(nil)
+	encode(sub)
+	protoTag(b, field, protoWireBytes)
+	_ = b.EncodeRawBytes(sub.Bytes())
+}
+
+func protoTag(b *proto.Buffer, field, wireType uint64) {
+	_ = b.EncodeVarint(field<<3 | wireType)
+}
+
+// The following functions skip default values as proto3 does
+
+func protoString(b *proto.Buffer, field uint64, s string) {
+	if s != "" {
+		protoTag(b, field, protoWireBytes)
+		_ = b.EncodeStringBytes(s)
+	}
+}
+
+func protoBytes(b *proto.Buffer, field uint64, data []byte) {
+	if len(data) > 0 {
+		protoTag(b, field, protoWireBytes)
+		_ = b.EncodeRawBytes(data)
+	}
+}
+
+func protoVarint(b *proto.Buffer, field, x uint64) {
+	if x != 0 {
+		protoTag(b, field, protoWireVarint)
+		_ = b.EncodeVarint(x)
+	}
+}
+
+func protoFixed64(b *proto.Buffer, field, x uint64) {
+	if x != 0 {
+		protoTag(b, field, protoWireFixed64)
+		_ = b.EncodeFixed64(x)
+	}
+}
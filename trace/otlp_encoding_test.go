@@ -0,0 +1,236 @@
+package trace
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+// protoFields decodes the fields of a protobuf message
+// Varint and fixed64 fields are decoded as uint64 and length-delimited fields are decoded as []byte.
+func protoFields(t *testing.T, data []byte) map[uint64][]interface{} {
+	fields := map[uint64][]interface{}{}
+	b := proto.NewBuffer(data)
+
+	for {
+		tag, err := b.DecodeVarint()
+		if err != nil {
+			break
+		}
+
+		field, wireType := tag>>3, tag&7
+		switch wireType {
+		case protoWireVarint:
+			v, err := b.DecodeVarint()
+			assert.NoError(t, err)
+			fields[field] = append(fields[field], v)
+		case protoWireFixed64:
+			v, err := b.DecodeFixed64()
+			assert.NoError(t, err)
+			fields[field] = append(fields[field], v)
+		case protoWireBytes:
+			v, err := b.DecodeRawBytes(true)
+			assert.NoError(t, err)
+			fields[field] = append(fields[field], v)
+		default:
+			t.Fatalf("unexpected wire type %d", wireType)
+		}
+	}
+
+	return fields
+}
+
+func testSpanData() SpanData {
+	start := time.Unix(1500000000, 123000)
+
+	return SpanData{
+		TraceID:   "4bf92f3577b34da6a3ce929d0e0e4736",
+		SpanID:    "f067aa0ba902b7",
+		ParentID:  "5e3ac9a4f6e3b90",
+		Service:   "hello_service",
+		Operation: "GET /hello",
+		StartTime: start,
+		Duration:  2 * time.Second,
+		Tags: map[string]interface{}{
+			"span.kind":        "server",
+			"error":            true,
+			"http.method":      "GET",
+			"http.status_code": int64(500),
+			"sampler.param":    0.5,
+			"sampled":          true,
+		},
+		Logs: []LogData{
+			{
+				Timestamp: start.Add(time.Second),
+				Fields: map[string]interface{}{
+					"event":   "error",
+					"message": "internal error",
+				},
+			},
+		},
+	}
+}
+
+func TestNewOTLPRequest(t *testing.T) {
+	s1 := testSpanData()
+	s2 := testSpanData()
+	s2.Service = "world_service"
+	s2.ParentID = ""
+	s2.Tags = map[string]interface{}{"span.kind": "client"}
+	s2.Logs = nil
+	s3 := testSpanData()
+	s3.Tags = nil
+	s3.Logs = []LogData{{Timestamp: s3.StartTime, Fields: map[string]interface{}{"message": "no event"}}}
+
+	req := newOTLPRequest([]SpanData{s1, s2, s3})
+
+	assert.Len(t, req.ResourceSpans, 2)
+	assert.Equal(t, "service.name", req.ResourceSpans[0].Resource.Attributes[0].Key)
+	assert.Equal(t, "hello_service", *req.ResourceSpans[0].Resource.Attributes[0].Value.StringValue)
+	assert.Equal(t, "world_service", *req.ResourceSpans[1].Resource.Attributes[0].Value.StringValue)
+	assert.Equal(t, otlpScopeName, req.ResourceSpans[0].ScopeSpans[0].Scope.Name)
+	assert.Len(t, req.ResourceSpans[0].ScopeSpans[0].Spans, 2)
+	assert.Len(t, req.ResourceSpans[1].ScopeSpans[0].Spans, 1)
+
+	span := req.ResourceSpans[0].ScopeSpans[0].Spans[0]
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.TraceID)
+	assert.Equal(t, "00f067aa0ba902b7", span.SpanID)
+	assert.Equal(t, "05e3ac9a4f6e3b90", span.ParentSpanID)
+	assert.Equal(t, "GET /hello", span.Name)
+	assert.Equal(t, otlpSpanKindServer, span.Kind)
+	assert.Equal(t, uint64(1500000000000123000), span.StartTimeUnixNano)
+	assert.Equal(t, uint64(1500000002000123000), span.EndTimeUnixNano)
+	assert.Equal(t, &otlpStatus{Code: otlpStatusCodeError}, span.Status)
+	assert.Len(t, span.Attributes, 4)
+	assert.Equal(t, "http.method", span.Attributes[0].Key)
+	assert.Equal(t, "GET", *span.Attributes[0].Value.StringValue)
+	assert.Equal(t, "http.status_code", span.Attributes[1].Key)
+	assert.Equal(t, int64(500), *span.Attributes[1].Value.IntValue)
+	assert.Equal(t, "sampled", span.Attributes[2].Key)
+	assert.Equal(t, true, *span.Attributes[2].Value.BoolValue)
+	assert.Equal(t, "sampler.param", span.Attributes[3].Key)
+	assert.Equal(t, 0.5, *span.Attributes[3].Value.DoubleValue)
+	assert.Len(t, span.Events, 1)
+	assert.Equal(t, "error", span.Events[0].Name)
+	assert.Equal(t, uint64(1500000001000123000), span.Events[0].TimeUnixNano)
+	assert.Equal(t, "message", span.Events[0].Attributes[0].Key)
+
+	span = req.ResourceSpans[1].ScopeSpans[0].Spans[0]
+	assert.Empty(t, span.ParentSpanID)
+	assert.Equal(t, otlpSpanKindClient, span.Kind)
+	assert.Nil(t, span.Status)
+	assert.Empty(t, span.Attributes)
+	assert.Empty(t, span.Events)
+
+	span = req.ResourceSpans[0].ScopeSpans[0].Spans[1]
+	assert.Equal(t, otlpSpanKindInternal, span.Kind)
+	assert.Equal(t, "log", span.Events[0].Name)
+}
+
+func TestNewOTLPKeyValue(t *testing.T) {
+	tests := []struct {
+		val      interface{}
+		expected string
+	}{
+		{"value", `{"key":"key","value":{"stringValue":"value"}}`},
+		{true, `{"key":"key","value":{"boolValue":true}}`},
+		{int64(10), `{"key":"key","value":{"intValue":"10"}}`},
+		{0.5, `{"key":"key","value":{"doubleValue":0.5}}`},
+		{[]byte("data"), `{"key":"key","value":{"bytesValue":"ZGF0YQ=="}}`},
+		{struct{ ID int }{1}, `{"key":"key","value":{"stringValue":"{1}"}}`},
+	}
+
+	for _, tc := range tests {
+		data, err := json.Marshal(newOTLPKeyValue("key", tc.val))
+		assert.NoError(t, err)
+		assert.JSONEq(t, tc.expected, string(data))
+	}
+}
+
+func TestOTLPRequestMarshalJSON(t *testing.T) {
+	s := testSpanData()
+	s.Tags = map[string]interface{}{"http.status_code": int64(200)}
+	s.Logs = nil
+
+	data, err := newOTLPRequest([]SpanData{s}).marshalJSON()
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"resourceSpans": [{
+			"resource": {
+				"attributes": [{"key": "service.name", "value": {"stringValue": "hello_service"}}]
+			},
+			"scopeSpans": [{
+				"scope": {"name": "github.com/moorara/goto/trace"},
+				"spans": [{
+					"traceId": "4bf92f3577b34da6a3ce929d0e0e4736",
+					"spanId": "00f067aa0ba902b7",
+					"parentSpanId": "05e3ac9a4f6e3b90",
+					"name": "GET /hello",
+					"kind": 1,
+					"startTimeUnixNano": "1500000000000123000",
+					"endTimeUnixNano": "1500000002000123000",
+					"attributes": [{"key": "http.status_code", "value": {"intValue": "200"}}]
+				}]
+			}]
+		}]
+	}`, string(data))
+}
+
+func TestOTLPRequestMarshalProto(t *testing.T) {
+	data, err := newOTLPRequest([]SpanData{testSpanData()}).marshalProto()
+	assert.NoError(t, err)
+
+	// ExportTraceServiceRequest
+	request := protoFields(t, data)
+	assert.Len(t, request[1], 1)
+
+	// ResourceSpans
+	resourceSpans := protoFields(t, request[1][0].([]byte))
+	resource := protoFields(t, resourceSpans[1][0].([]byte))
+	attr := protoFields(t, resource[1][0].([]byte))
+	assert.Equal(t, []byte("service.name"), attr[1][0])
+	assert.Equal(t, []byte("hello_service"), protoFields(t, attr[2][0].([]byte))[1][0])
+
+	// ScopeSpans
+	scopeSpans := protoFields(t, resourceSpans[2][0].([]byte))
+	scope := protoFields(t, scopeSpans[1][0].([]byte))
+	assert.Equal(t, []byte(otlpScopeName), scope[1][0])
+	assert.Len(t, scopeSpans[2], 1)
+
+	// Span
+	span := protoFields(t, scopeSpans[2][0].([]byte))
+	assert.Equal(t, []byte{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36}, span[1][0])
+	assert.Equal(t, []byte{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7}, span[2][0])
+	assert.Equal(t, []byte{0x05, 0xe3, 0xac, 0x9a, 0x4f, 0x6e, 0x3b, 0x90}, span[4][0])
+	assert.Equal(t, []byte("GET /hello"), span[5][0])
+	assert.Equal(t, uint64(otlpSpanKindServer), span[6][0])
+	assert.Equal(t, uint64(1500000000000123000), span[7][0])
+	assert.Equal(t, uint64(1500000002000123000), span[8][0])
+	assert.Len(t, span[9], 4)
+	assert.Len(t, span[11], 1)
+
+	// Attributes
+	attr = protoFields(t, span[9][1].([]byte))
+	assert.Equal(t, []byte("http.status_code"), attr[1][0])
+	assert.Equal(t, uint64(500), protoFields(t, attr[2][0].([]byte))[3][0])
+	attr = protoFields(t, span[9][2].([]byte))
+	assert.Equal(t, []byte("sampled"), attr[1][0])
+	assert.Equal(t, uint64(1), protoFields(t, attr[2][0].([]byte))[2][0])
+	attr = protoFields(t, span[9][3].([]byte))
+	assert.Equal(t, []byte("sampler.param"), attr[1][0])
+	assert.Equal(t, math.Float64bits(0.5), protoFields(t, attr[2][0].([]byte))[4][0])
+
+	// Event
+	event := protoFields(t, span[11][0].([]byte))
+	assert.Equal(t, uint64(1500000001000123000), event[1][0])
+	assert.Equal(t, []byte("error"), event[2][0])
+	assert.Len(t, event[3], 1)
+
+	// Status
+	status := protoFields(t, span[15][0].([]byte))
+	assert.Equal(t, uint64(otlpStatusCodeError), status[3][0])
+}
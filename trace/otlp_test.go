@@ -0,0 +1,410 @@
+package trace
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mockCollector is an OTLP/HTTP collector for testing
+type mockCollector struct {
+	sync.Mutex
+	server      *httptest.Server
+	statusCodes []int
+	requests    []*http.Request
+	bodies      [][]byte
+}
+
+func newMockCollector(statusCodes ...int) *mockCollector {
+	c := &mockCollector{
+		statusCodes: statusCodes,
+	}
+
+	c.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+
+		c.Lock()
+		defer c.Unlock()
+
+		c.requests = append(c.requests, r)
+		c.bodies = append(c.bodies, body)
+
+		statusCode := http.StatusOK
+		if len(c.statusCodes) > 0 {
+			statusCode, c.statusCodes = c.statusCodes[0], c.statusCodes[1:]
+		}
+
+		w.WriteHeader(statusCode)
+	}))
+
+	return c
+}
+
+func (c *mockCollector) Close() {
+	c.server.Close()
+}
+
+// spans returns the number of spans in every request with JSON encoding
+func (c *mockCollector) spans(t *testing.T) []int {
+	c.Lock()
+	defer c.Unlock()
+
+	counts := []int{}
+	for _, body := range c.bodies {
+		req := new(otlpRequest)
+		assert.NoError(t, json.Unmarshal(body, req))
+
+		count := 0
+		for _, rs := range req.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				count += len(ss.Spans)
+			}
+		}
+		counts = append(counts, count)
+	}
+
+	return counts
+}
+
+func TestNewOTLPReporter(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         OTLPOptions
+		expectedOpts OTLPOptions
+	}{
+		{
+			name: "Defaults",
+			opts: OTLPOptions{},
+			expectedOpts: OTLPOptions{
+				Endpoint:       "http://localhost:4318/v1/traces",
+				Encoding:       OTLPProtobuf,
+				BatchSize:      512,
+				QueueSize:      2048,
+				FlushInterval:  5 * time.Second,
+				MaxRetries:     5,
+				InitialBackoff: 100 * time.Millisecond,
+				MaxBackoff:     5 * time.Second,
+			},
+		},
+		{
+			name: "WithOptions",
+			opts: OTLPOptions{
+				Endpoint:       "http://otel-collector:4318/v1/traces",
+				Encoding:       OTLPJSON,
+				Headers:        map[string]string{"Authorization": "Bearer token"},
+				BatchSize:      100,
+				QueueSize:      1000,
+				FlushInterval:  time.Second,
+				MaxRetries:     -1,
+				InitialBackoff: time.Second,
+				MaxBackoff:     time.Minute,
+			},
+			expectedOpts: OTLPOptions{
+				Endpoint:       "http://otel-collector:4318/v1/traces",
+				Encoding:       OTLPJSON,
+				Headers:        map[string]string{"Authorization": "Bearer token"},
+				BatchSize:      100,
+				QueueSize:      1000,
+				FlushInterval:  time.Second,
+				MaxRetries:     0,
+				InitialBackoff: time.Second,
+				MaxBackoff:     time.Minute,
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewOTLPReporter(tc.opts)
+			defer r.Close()
+
+			assert.NotNil(t, r.opts.Client)
+			assert.NotNil(t, r.opts.OnError)
+			assert.Equal(t, tc.expectedOpts.Endpoint, r.opts.Endpoint)
+			assert.Equal(t, tc.expectedOpts.Encoding, r.opts.Encoding)
+			assert.Equal(t, tc.expectedOpts.Headers, r.opts.Headers)
+			assert.Equal(t, tc.expectedOpts.BatchSize, r.opts.BatchSize)
+			assert.Equal(t, tc.expectedOpts.QueueSize, cap(r.queue))
+			assert.Equal(t, tc.expectedOpts.FlushInterval, r.opts.FlushInterval)
+			assert.Equal(t, tc.expectedOpts.MaxRetries, r.opts.MaxRetries)
+			assert.Equal(t, tc.expectedOpts.InitialBackoff, r.opts.InitialBackoff)
+			assert.Equal(t, tc.expectedOpts.MaxBackoff, r.opts.MaxBackoff)
+		})
+	}
+}
+
+func TestOTLPReporter(t *testing.T) {
+	tests := []struct {
+		name               string
+		encoding           OTLPEncoding
+		batchSize          int
+		statusCodes        []int
+		maxRetries         int
+		spans              int
+		expectedRequests   int
+		expectedBatches    []int
+		expectedErrors     int
+		expectedContenType string
+	}{
+		{
+			name:               "JSON",
+			encoding:           OTLPJSON,
+			batchSize:          10,
+			spans:              3,
+			expectedRequests:   1,
+			expectedBatches:    []int{3},
+			expectedContenType: "application/json",
+		},
+		{
+			name:               "Protobuf",
+			encoding:           OTLPProtobuf,
+			batchSize:          10,
+			spans:              3,
+			expectedRequests:   1,
+			expectedContenType: "application/x-protobuf",
+		},
+		{
+			name:               "Batches",
+			encoding:           OTLPJSON,
+			batchSize:          2,
+			spans:              5,
+			expectedRequests:   3,
+			expectedBatches:    []int{2, 2, 1},
+			expectedContenType: "application/json",
+		},
+		{
+			name:               "RetrySuccess",
+			encoding:           OTLPJSON,
+			batchSize:          10,
+			statusCodes:        []int{503, 429, 200},
+			maxRetries:         3,
+			spans:              1,
+			expectedRequests:   3,
+			expectedBatches:    []int{1, 1, 1},
+			expectedContenType: "application/json",
+		},
+		{
+			name:               "RetryFailure",
+			encoding:           OTLPJSON,
+			batchSize:          10,
+			statusCodes:        []int{502, 504, 503},
+			maxRetries:         2,
+			spans:              1,
+			expectedRequests:   3,
+			expectedBatches:    []int{1, 1, 1},
+			expectedErrors:     1,
+			expectedContenType: "application/json",
+		},
+		{
+			name:               "NoRetry",
+			encoding:           OTLPJSON,
+			batchSize:          10,
+			statusCodes:        []int{400},
+			maxRetries:         3,
+			spans:              1,
+			expectedRequests:   1,
+			expectedBatches:    []int{1},
+			expectedErrors:     1,
+			expectedContenType: "application/json",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			collector := newMockCollector(tc.statusCodes...)
+			defer collector.Close()
+
+			var mu sync.Mutex
+			var errs []error
+
+			reporter := NewOTLPReporter(OTLPOptions{
+				Endpoint:       collector.server.URL + "/v1/traces",
+				Encoding:       tc.encoding,
+				Headers:        map[string]string{"Authorization": "Bearer token"},
+				BatchSize:      tc.batchSize,
+				FlushInterval:  time.Minute,
+				MaxRetries:     tc.maxRetries,
+				InitialBackoff: time.Millisecond,
+				MaxBackoff:     2 * time.Millisecond,
+				OnError: func(err error) {
+					mu.Lock()
+					defer mu.Unlock()
+					errs = append(errs, err)
+				},
+			})
+
+			tracer, close := newTestTracer(reporter)
+			for i := 0; i < tc.spans; i++ {
+				span := tracer.StartSpan("test")
+				span.Finish()
+			}
+			close()
+
+			assert.Len(t, collector.requests, tc.expectedRequests)
+			for _, r := range collector.requests {
+				assert.Equal(t, "POST", r.Method)
+				assert.Equal(t, "/v1/traces", r.URL.Path)
+				assert.Equal(t, tc.expectedContenType, r.Header.Get("Content-Type"))
+				assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+			}
+
+			if tc.expectedBatches != nil {
+				assert.Equal(t, tc.expectedBatches, collector.spans(t))
+			}
+
+			assert.Len(t, errs, tc.expectedErrors)
+		})
+	}
+}
+
+func TestOTLPReporterFlushInterval(t *testing.T) {
+	collector := newMockCollector()
+	defer collector.Close()
+
+	reporter := NewOTLPReporter(OTLPOptions{
+		Endpoint:      collector.server.URL,
+		Encoding:      OTLPJSON,
+		FlushInterval: 10 * time.Millisecond,
+	})
+	defer reporter.Close()
+
+	tracer, close := newTestTracer(reporter)
+	defer close()
+
+	span := tracer.StartSpan("test")
+	span.Finish()
+
+	assert.Eventually(t, func() bool {
+		collector.Lock()
+		defer collector.Unlock()
+		return len(collector.requests) == 1
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestOTLPReporterNetworkError(t *testing.T) {
+	collector := newMockCollector()
+	collector.Close()
+
+	var errs []error
+	reporter := NewOTLPReporter(OTLPOptions{
+		Endpoint:       collector.server.URL,
+		MaxRetries:     2,
+		InitialBackoff: time.Millisecond,
+		OnError: func(err error) {
+			errs = append(errs, err)
+		},
+	})
+
+	tracer, close := newTestTracer(reporter)
+	span := tracer.StartSpan("test")
+	span.Finish()
+	close()
+
+	assert.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Error(), "error exporting 1 spans")
+}
+
+func TestOTLPReporterQueueFull(t *testing.T) {
+	block := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-block
+	}))
+	defer server.Close()
+
+	var mu sync.Mutex
+	var errs []error
+
+	reporter := NewOTLPReporter(OTLPOptions{
+		Endpoint:  server.URL,
+		BatchSize: 1,
+		QueueSize: 1,
+		OnError: func(err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			errs = append(errs, err)
+		},
+	})
+
+	tracer, closeTracer := newTestTracer(reporter)
+
+	// The first span is being exported, the second span is queued, and the rest are dropped
+	for i := 0; i < 5; i++ {
+		span := tracer.StartSpan("test")
+		span.Finish()
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	mu.Lock()
+	assert.Len(t, errs, 3)
+	for _, err := range errs {
+		assert.EqualError(t, err, "otlp queue is full, span dropped")
+	}
+	mu.Unlock()
+
+	close(block)
+	closeTracer()
+
+	// Spans reported after closing are dropped
+	span := tracer.StartSpan("test")
+	span.Finish()
+	assert.Len(t, errs, 4)
+	assert.EqualError(t, errs[3], "otlp reporter is closed, span dropped")
+}
+
+func TestOTLPReporterClose(t *testing.T) {
+	collector := newMockCollector(http.StatusServiceUnavailable)
+	defer collector.Close()
+
+	var mu sync.Mutex
+	var errs []error
+
+	reporter := NewOTLPReporter(OTLPOptions{
+		Endpoint:       collector.server.URL,
+		BatchSize:      1,
+		InitialBackoff: time.Hour,
+		CloseTimeout:   10 * time.Millisecond,
+		OnError: func(err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			errs = append(errs, err)
+		},
+	})
+
+	tracer, closeTracer := newTestTracer(reporter)
+	tracer.StartSpan("test").Finish()
+
+	assert.Eventually(t, func() bool {
+		collector.Lock()
+		defer collector.Unlock()
+		return len(collector.requests) == 1
+	}, time.Second, 5*time.Millisecond)
+
+	// Closing does not wait for the backoff of failed request after the timeout
+	closed := make(chan struct{})
+	go func() {
+		closeTracer()
+		close(closed)
+	}()
+
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("reporter is not closed")
+	}
+
+	// Spans reported after closing are dropped
+	tracer.StartSpan("test").Finish()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	assert.Len(t, errs, 2)
+	assert.Contains(t, errs[0].Error(), "error exporting 1 spans")
+	assert.EqualError(t, errs[1], "otlp reporter is closed, span dropped")
+}
@@ -0,0 +1,418 @@
+package trace
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	jaeger "github.com/uber/jaeger-client-go"
+)
+
+// Propagation is a format for propagating span contexts across process boundaries
+type Propagation int
+
+const (
+	// PropagationJaeger is the Jaeger format (uber-trace-id and uberctx-* headers)
+	PropagationJaeger Propagation = iota
+	// PropagationW3C is the W3C Trace Context format (traceparent and tracestate headers)
+	PropagationW3C
+	// PropagationB3Single is the B3 single header format (b3 header)
+	PropagationB3Single
+	// PropagationB3Multi is the B3 multiple headers format (x-b3-* headers)
+	PropagationB3Multi
+)
+
+const (
+	// maxTraceStates is the maximum number of W3C tracestate headers kept for injecting into outgoing requests
+	maxTraceStates = 4096
+
+	w3cTraceParentHeader = "traceparent"
+	w3cTraceStateHeader  = "tracestate"
+	b3SingleHeader       = "b3"
+	b3TraceIDHeader      = "x-b3-traceid"
+	b3SpanIDHeader       = "x-b3-spanid"
+	b3ParentSpanIDHeader = "x-b3-parentspanid"
+	b3SampledHeader      = "x-b3-sampled"
+	b3FlagsHeader        = "x-b3-flags"
+
+	// b3DebugFlags are the sampled and debug flags of jaeger.SpanContext
+	b3DebugFlags = 3
+)
+
+// propagator is a combined jaeger.Injector and jaeger.Extractor
+type propagator interface {
+	jaeger.Injector
+	jaeger.Extractor
+}
+
+// newPropagator creates a propagator for a list of formats
+// If more than one format is given, a span context is injected in all formats and extracted from the first format found.
+// The sampler makes the sampling decision for extracted span contexts without one (B3 formats only).
+// The W3C tracestate headers are kept in states and shared between propagators.
+func newPropagator(format interface{}, propagations []Propagation, sampler jaeger.Sampler, states *traceStates) (propagator, error) {
+	ps := make([]propagator, 0, len(propagations))
+	for _, p := range propagations {
+		switch p {
+		case PropagationJaeger:
+			headers := (&jaeger.HeadersConfig{}).ApplyDefaults()
+			if format == opentracing.HTTPHeaders {
+				ps = append(ps, jaeger.NewHTTPHeaderPropagator(headers, *jaeger.NewNullMetrics()))
+			} else {
+				ps = append(ps, jaeger.NewTextMapPropagator(headers, *jaeger.NewNullMetrics()))
+			}
+		case PropagationW3C:
+			ps = append(ps, &w3cPropagator{states: states})
+		case PropagationB3Single:
+			ps = append(ps, &b3Propagator{single: true, sampler: sampler})
+		case PropagationB3Multi:
+			ps = append(ps, &b3Propagator{single: false, sampler: sampler})
+		default:
+			return nil, fmt.Errorf("unknown propagation format: %d", p)
+		}
+	}
+
+	if len(ps) == 1 {
+		return ps[0], nil
+	}
+
+	return &compositePropagator{ps}, nil
+}
+
+// compositePropagator injects a span context in all formats and extracts it from the first format found
+type compositePropagator struct {
+	propagators []propagator
+}
+
+// Inject implements jaeger.Injector interface
+func (p *compositePropagator) Inject(ctx jaeger.SpanContext, carrier interface{}) error {
+	for _, propagator := range p.propagators {
+		if err := propagator.Inject(ctx, carrier); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Extract implements jaeger.Extractor interface
+func (p *compositePropagator) Extract(carrier interface{}) (jaeger.SpanContext, error) {
+	var firstErr error
+	for _, propagator := range p.propagators {
+		ctx, err := propagator.Extract(carrier)
+		if err == nil {
+			return ctx, nil
+		}
+
+		if err != opentracing.ErrSpanContextNotFound && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	if firstErr != nil {
+		return jaeger.SpanContext{}, firstErr
+	}
+
+	return jaeger.SpanContext{}, opentracing.ErrSpanContextNotFound
+}
+
+// traceStates keeps the W3C tracestate headers of the most recent traces
+// Jaeger span contexts cannot carry a tracestate, and carrying it as a baggage item would propagate it in other formats too.
+type traceStates struct {
+	sync.Mutex
+	states map[jaeger.TraceID]string
+	order  []jaeger.TraceID
+	next   int
+}
+
+func newTraceStates(size int) *traceStates {
+	return &traceStates{
+		states: make(map[jaeger.TraceID]string, size),
+		order:  make([]jaeger.TraceID, size),
+	}
+}
+
+// get returns the tracestate of a trace
+func (s *traceStates) get(traceID jaeger.TraceID) string {
+	if s == nil {
+		return ""
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	return s.states[traceID]
+}
+
+// set keeps the tracestate of a trace and evicts the oldest one if there is no room
+func (s *traceStates) set(traceID jaeger.TraceID, state string) {
+	if s == nil {
+		return
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.states[traceID]; !ok {
+		delete(s.states, s.order[s.next])
+		s.order[s.next] = traceID
+		s.next = (s.next + 1) % len(s.order)
+	}
+
+	s.states[traceID] = state
+}
+
+// w3cPropagator implements the W3C Trace Context format
+// The tracestate header of an incoming request is injected into outgoing requests of the same trace.
+// See https://www.w3.org/TR/trace-context
+type w3cPropagator struct {
+	states *traceStates
+}
+
+// Inject implements jaeger.Injector interface
+func (p *w3cPropagator) Inject(ctx jaeger.SpanContext, carrier interface{}) error {
+	w, ok := carrier.(opentracing.TextMapWriter)
+	if !ok {
+		return opentracing.ErrInvalidCarrier
+	}
+
+	flags := "00"
+	if ctx.IsSampled() {
+		flags = "01"
+	}
+
+	traceID := ctx.TraceID()
+	w.Set(w3cTraceParentHeader, fmt.Sprintf("00-%016x%016x-%016x-%s", traceID.High, traceID.Low, uint64(ctx.SpanID()), flags))
+
+	if state := p.states.get(traceID); state != "" {
+		w.Set(w3cTraceStateHeader, state)
+	}
+
+	return nil
+}
+
+// Extract implements jaeger.Extractor interface
+func (p *w3cPropagator) Extract(carrier interface{}) (jaeger.SpanContext, error) {
+	r, ok := carrier.(opentracing.TextMapReader)
+	if !ok {
+		return jaeger.SpanContext{}, opentracing.ErrInvalidCarrier
+	}
+
+	var traceParent, traceState string
+	err := r.ForeachKey(func(key, val string) error {
+		switch strings.ToLower(key) {
+		case w3cTraceParentHeader:
+			traceParent = val
+		case w3cTraceStateHeader:
+			traceState = val
+		}
+		return nil
+	})
+
+	if err != nil {
+		return jaeger.SpanContext{}, err
+	}
+
+	if traceParent == "" {
+		return jaeger.SpanContext{}, opentracing.ErrSpanContextNotFound
+	}
+
+	// {version}-{trace-id}-{parent-id}-{trace-flags}
+	parts := strings.Split(strings.TrimSpace(traceParent), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return jaeger.SpanContext{}, opentracing.ErrSpanContextCorrupted
+	}
+
+	if len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return jaeger.SpanContext{}, opentracing.ErrSpanContextCorrupted
+	}
+
+	traceID, err := jaeger.TraceIDFromString(parts[1])
+	if err != nil || !traceID.IsValid() {
+		return jaeger.SpanContext{}, opentracing.ErrSpanContextCorrupted
+	}
+
+	spanID, err := jaeger.SpanIDFromString(parts[2])
+	if err != nil || spanID == 0 {
+		return jaeger.SpanContext{}, opentracing.ErrSpanContextCorrupted
+	}
+
+	var flags byte
+	if _, err := fmt.Sscanf(parts[3], "%02x", &flags); err != nil {
+		return jaeger.SpanContext{}, opentracing.ErrSpanContextCorrupted
+	}
+
+	if traceState != "" {
+		p.states.set(traceID, traceState)
+	}
+
+	return jaeger.NewSpanContext(traceID, spanID, 0, flags&0x01 == 0x01, nil), nil
+}
+
+// b3Propagator implements the B3 single header and multiple headers formats
+// When the sampling decision is deferred (no sampled flag), the sampler makes the decision.
+// The debug flag (d or x-b3-flags: 1) is kept as the debug flag of span context, so it is propagated to the next hops.
+// See https://github.com/openzipkin/b3-propagation
+type b3Propagator struct {
+	single  bool
+	sampler jaeger.Sampler
+}
+
+// Inject implements jaeger.Injector interface
+func (p *b3Propagator) Inject(ctx jaeger.SpanContext, carrier interface{}) error {
+	w, ok := carrier.(opentracing.TextMapWriter)
+	if !ok {
+		return opentracing.ErrInvalidCarrier
+	}
+
+	traceID := ctx.TraceID()
+	tid := fmt.Sprintf("%016x", traceID.Low)
+	if traceID.High != 0 {
+		tid = fmt.Sprintf("%016x%016x", traceID.High, traceID.Low)
+	}
+
+	sid := fmt.Sprintf("%016x", uint64(ctx.SpanID()))
+
+	var pid string
+	if ctx.ParentID() != 0 {
+		pid = fmt.Sprintf("%016x", uint64(ctx.ParentID()))
+	}
+
+	sampled := "0"
+	if ctx.IsDebug() {
+		sampled = "d"
+	} else if ctx.IsSampled() {
+		sampled = "1"
+	}
+
+	if p.single {
+		// {trace-id}-{span-id}-{sampled}-{parent-span-id}
+		val := tid + "-" + sid + "-" + sampled
+		if pid != "" {
+			val += "-" + pid
+		}
+		w.Set(b3SingleHeader, val)
+		return nil
+	}
+
+	w.Set(b3TraceIDHeader, tid)
+	w.Set(b3SpanIDHeader, sid)
+	if pid != "" {
+		w.Set(b3ParentSpanIDHeader, pid)
+	}
+
+	if sampled == "d" {
+		w.Set(b3FlagsHeader, "1")
+	} else {
+		w.Set(b3SampledHeader, sampled)
+	}
+
+	return nil
+}
+
+// Extract implements jaeger.Extractor interface
+func (p *b3Propagator) Extract(carrier interface{}) (jaeger.SpanContext, error) {
+	r, ok := carrier.(opentracing.TextMapReader)
+	if !ok {
+		return jaeger.SpanContext{}, opentracing.ErrInvalidCarrier
+	}
+
+	headers := map[string]string{}
+	err := r.ForeachKey(func(key, val string) error {
+		key = strings.ToLower(key)
+		switch key {
+		case b3SingleHeader, b3TraceIDHeader, b3SpanIDHeader, b3ParentSpanIDHeader, b3SampledHeader, b3FlagsHeader:
+			headers[key] = strings.TrimSpace(val)
+		}
+		return nil
+	})
+
+	if err != nil {
+		return jaeger.SpanContext{}, err
+	}
+
+	var tid, sid, pid, sampled string
+
+	if p.single {
+		val, ok := headers[b3SingleHeader]
+		if !ok {
+			return jaeger.SpanContext{}, opentracing.ErrSpanContextNotFound
+		}
+
+		parts := strings.Split(val, "-")
+		switch len(parts) {
+		case 1:
+			// A sampling decision only (0, 1, or d) without a span context
+			return jaeger.SpanContext{}, opentracing.ErrSpanContextNotFound
+		case 2:
+			tid, sid = parts[0], parts[1]
+		case 3:
+			tid, sid, sampled = parts[0], parts[1], parts[2]
+		case 4:
+			tid, sid, sampled, pid = parts[0], parts[1], parts[2], parts[3]
+		default:
+			return jaeger.SpanContext{}, opentracing.ErrSpanContextCorrupted
+		}
+	} else {
+		tid, sid, pid = headers[b3TraceIDHeader], headers[b3SpanIDHeader], headers[b3ParentSpanIDHeader]
+		if tid == "" && sid == "" {
+			return jaeger.SpanContext{}, opentracing.ErrSpanContextNotFound
+		}
+
+		sampled = headers[b3SampledHeader]
+		if headers[b3FlagsHeader] == "1" {
+			sampled = "d"
+		}
+	}
+
+	if (len(tid) != 16 && len(tid) != 32) || len(sid) != 16 || (pid != "" && len(pid) != 16) {
+		return jaeger.SpanContext{}, opentracing.ErrSpanContextCorrupted
+	}
+
+	traceID, err := jaeger.TraceIDFromString(tid)
+	if err != nil || !traceID.IsValid() {
+		return jaeger.SpanContext{}, opentracing.ErrSpanContextCorrupted
+	}
+
+	spanID, err := jaeger.SpanIDFromString(sid)
+	if err != nil || spanID == 0 {
+		return jaeger.SpanContext{}, opentracing.ErrSpanContextCorrupted
+	}
+
+	var parentID jaeger.SpanID
+	if pid != "" {
+		if parentID, err = jaeger.SpanIDFromString(pid); err != nil {
+			return jaeger.SpanContext{}, opentracing.ErrSpanContextCorrupted
+		}
+	}
+
+	var isSampled bool
+	switch sampled {
+	case "d":
+		return newDebugSpanContext(traceID, spanID, parentID)
+	case "1", "true":
+		isSampled = true
+	case "0", "false":
+		isSampled = false
+	case "":
+		// The upstream deferred the sampling decision
+		if p.sampler != nil {
+			isSampled, _ = p.sampler.IsSampled(traceID, "")
+		}
+	default:
+		return jaeger.SpanContext{}, opentracing.ErrSpanContextCorrupted
+	}
+
+	return jaeger.NewSpanContext(traceID, spanID, parentID, isSampled, nil), nil
+}
+
+// newDebugSpanContext creates a sampled span context with the debug flag
+// jaeger.NewSpanContext cannot set the debug flag, so the span context is created from its string form.
+func newDebugSpanContext(traceID jaeger.TraceID, spanID, parentID jaeger.SpanID) (jaeger.SpanContext, error) {
+	ctx, err := jaeger.ContextFromString(fmt.Sprintf("%s:%s:%s:%d", traceID, spanID, parentID, b3DebugFlags))
+	if err != nil {
+		return jaeger.SpanContext{}, opentracing.ErrSpanContextCorrupted
+	}
+
+	return ctx, nil
+}
@@ -0,0 +1,439 @@
+package trace
+
+import (
+	"net/http"
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	jaeger "github.com/uber/jaeger-client-go"
+)
+
+func TestNewPropagator(t *testing.T) {
+	tests := []struct {
+		name          string
+		format        interface{}
+		propagations  []Propagation
+		expectedError string
+	}{
+		{"Jaeger", opentracing.HTTPHeaders, []Propagation{PropagationJaeger}, ""},
+		{"W3C", opentracing.TextMap, []Propagation{PropagationW3C}, ""},
+		{"Composite", opentracing.HTTPHeaders, []Propagation{PropagationW3C, PropagationB3Single, PropagationB3Multi, PropagationJaeger}, ""},
+		{"Unknown", opentracing.HTTPHeaders, []Propagation{Propagation(99)}, "unknown propagation format: 99"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := newPropagator(tc.format, tc.propagations, nil, newTraceStates(10))
+
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				assert.Nil(t, p)
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, p)
+			}
+		})
+	}
+}
+
+func TestW3CPropagator(t *testing.T) {
+	traceID := jaeger.TraceID{High: 0x4bf92f3577b34da6, Low: 0xa3ce929d0e0e4736}
+	ctx := jaeger.NewSpanContext(traceID, jaeger.SpanID(0x00f067aa0ba902b7), 0, true, nil)
+
+	t.Run("Inject", func(t *testing.T) {
+		states := newTraceStates(10)
+		states.set(traceID, "congo=t61rcWkgMzE")
+
+		carrier := opentracing.HTTPHeadersCarrier(http.Header{})
+		err := (&w3cPropagator{states: states}).Inject(ctx, carrier)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", http.Header(carrier).Get("traceparent"))
+		assert.Equal(t, "congo=t61rcWkgMzE", http.Header(carrier).Get("tracestate"))
+	})
+
+	t.Run("InjectWithoutState", func(t *testing.T) {
+		carrier := opentracing.HTTPHeadersCarrier(http.Header{})
+		err := (&w3cPropagator{}).Inject(ctx, carrier)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", http.Header(carrier).Get("traceparent"))
+		assert.Empty(t, http.Header(carrier).Get("tracestate"))
+	})
+
+	t.Run("InjectInvalidCarrier", func(t *testing.T) {
+		err := (&w3cPropagator{}).Inject(ctx, "invalid")
+		assert.Equal(t, opentracing.ErrInvalidCarrier, err)
+	})
+
+	tests := []struct {
+		name            string
+		headers         map[string]string
+		expectedError   error
+		expectedTraceID string
+		expectedSpanID  string
+		expectedSampled bool
+		expectedState   string
+	}{
+		{
+			name:          "NotFound",
+			headers:       map[string]string{},
+			expectedError: opentracing.ErrSpanContextNotFound,
+		},
+		{
+			name:          "InvalidVersion",
+			headers:       map[string]string{"traceparent": "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+			expectedError: opentracing.ErrSpanContextCorrupted,
+		},
+		{
+			name:          "InvalidTraceID",
+			headers:       map[string]string{"traceparent": "00-00000000000000000000000000000000-00f067aa0ba902b7-01"},
+			expectedError: opentracing.ErrSpanContextCorrupted,
+		},
+		{
+			name:          "InvalidSpanID",
+			headers:       map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-zzf067aa0ba902b7-01"},
+			expectedError: opentracing.ErrSpanContextCorrupted,
+		},
+		{
+			name:          "InvalidFlags",
+			headers:       map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-zz"},
+			expectedError: opentracing.ErrSpanContextCorrupted,
+		},
+		{
+			name:          "ExtraFields",
+			headers:       map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"},
+			expectedError: opentracing.ErrSpanContextCorrupted,
+		},
+		{
+			name:            "Sampled",
+			headers:         map[string]string{"Traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "Tracestate": "congo=t61rcWkgMzE"},
+			expectedTraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+			expectedSpanID:  "f067aa0ba902b7",
+			expectedSampled: true,
+			expectedState:   "congo=t61rcWkgMzE",
+		},
+		{
+			name:            "NotSampled",
+			headers:         map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00"},
+			expectedTraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+			expectedSpanID:  "f067aa0ba902b7",
+			expectedSampled: false,
+		},
+		{
+			name:            "FutureVersion",
+			headers:         map[string]string{"traceparent": "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"},
+			expectedTraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+			expectedSpanID:  "f067aa0ba902b7",
+			expectedSampled: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			states := newTraceStates(10)
+			ctx, err := (&w3cPropagator{states: states}).Extract(opentracing.TextMapCarrier(tc.headers))
+
+			if tc.expectedError != nil {
+				assert.Equal(t, tc.expectedError, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedTraceID, ctx.TraceID().String())
+				assert.Equal(t, tc.expectedSpanID, ctx.SpanID().String())
+				assert.Equal(t, tc.expectedSampled, ctx.IsSampled())
+
+				assert.Equal(t, tc.expectedState, states.get(ctx.TraceID()))
+
+				ctx.ForeachBaggageItem(func(k, v string) bool {
+					assert.Fail(t, "unexpected baggage item", k)
+					return true
+				})
+			}
+		})
+	}
+}
+
+func TestB3Propagator(t *testing.T) {
+	traceID := jaeger.TraceID{High: 0x80f198ee56343ba8, Low: 0x64fe8b2a57d3eff7}
+	ctx := jaeger.NewSpanContext(traceID, jaeger.SpanID(0xe457b5a2e4d86bd1), jaeger.SpanID(0x05e3ac9a4f6e3b90), true, nil)
+
+	t.Run("InjectSingle", func(t *testing.T) {
+		carrier := opentracing.TextMapCarrier{}
+		err := (&b3Propagator{single: true}).Inject(ctx, carrier)
+
+		assert.NoError(t, err)
+		assert.Equal(t, opentracing.TextMapCarrier{
+			"b3": "80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-1-05e3ac9a4f6e3b90",
+		}, carrier)
+	})
+
+	t.Run("InjectMulti", func(t *testing.T) {
+		carrier := opentracing.TextMapCarrier{}
+		err := (&b3Propagator{single: false}).Inject(ctx, carrier)
+
+		assert.NoError(t, err)
+		assert.Equal(t, opentracing.TextMapCarrier{
+			"x-b3-traceid":      "80f198ee56343ba864fe8b2a57d3eff7",
+			"x-b3-spanid":       "e457b5a2e4d86bd1",
+			"x-b3-parentspanid": "05e3ac9a4f6e3b90",
+			"x-b3-sampled":      "1",
+		}, carrier)
+	})
+
+	t.Run("InjectInvalidCarrier", func(t *testing.T) {
+		err := (&b3Propagator{}).Inject(ctx, "invalid")
+		assert.Equal(t, opentracing.ErrInvalidCarrier, err)
+	})
+
+	tests := []struct {
+		name             string
+		single           bool
+		sampler          jaeger.Sampler
+		headers          map[string]string
+		expectedError    error
+		expectedTraceID  string
+		expectedSpanID   string
+		expectedParentID string
+		expectedSampled  bool
+		expectedDebug    bool
+	}{
+		{
+			name:          "SingleNotFound",
+			single:        true,
+			headers:       map[string]string{"x-b3-traceid": "64fe8b2a57d3eff7"},
+			expectedError: opentracing.ErrSpanContextNotFound,
+		},
+		{
+			name:          "SingleSamplingOnly",
+			single:        true,
+			headers:       map[string]string{"b3": "0"},
+			expectedError: opentracing.ErrSpanContextNotFound,
+		},
+		{
+			name:          "SingleTooManyFields",
+			single:        true,
+			headers:       map[string]string{"b3": "64fe8b2a57d3eff7-e457b5a2e4d86bd1-1-05e3ac9a4f6e3b90-extra"},
+			expectedError: opentracing.ErrSpanContextCorrupted,
+		},
+		{
+			name:          "SingleInvalidSampled",
+			single:        true,
+			headers:       map[string]string{"b3": "64fe8b2a57d3eff7-e457b5a2e4d86bd1-x"},
+			expectedError: opentracing.ErrSpanContextCorrupted,
+		},
+		{
+			name:            "SingleTraceIDAndSpanID",
+			single:          true,
+			headers:         map[string]string{"b3": "64fe8b2a57d3eff7-e457b5a2e4d86bd1"},
+			expectedTraceID: "64fe8b2a57d3eff7",
+			expectedSpanID:  "e457b5a2e4d86bd1",
+			expectedSampled: false,
+		},
+		{
+			name:            "SingleDeferred",
+			single:          true,
+			sampler:         jaeger.NewConstSampler(true),
+			headers:         map[string]string{"b3": "64fe8b2a57d3eff7-e457b5a2e4d86bd1"},
+			expectedTraceID: "64fe8b2a57d3eff7",
+			expectedSpanID:  "e457b5a2e4d86bd1",
+			expectedSampled: true,
+		},
+		{
+			name:            "SingleNotSampled",
+			single:          true,
+			sampler:         jaeger.NewConstSampler(true),
+			headers:         map[string]string{"b3": "64fe8b2a57d3eff7-e457b5a2e4d86bd1-0"},
+			expectedTraceID: "64fe8b2a57d3eff7",
+			expectedSpanID:  "e457b5a2e4d86bd1",
+			expectedSampled: false,
+		},
+		{
+			name:             "Single",
+			single:           true,
+			headers:          map[string]string{"B3": "80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-d-05e3ac9a4f6e3b90"},
+			expectedTraceID:  "80f198ee56343ba864fe8b2a57d3eff7",
+			expectedSpanID:   "e457b5a2e4d86bd1",
+			expectedParentID: "5e3ac9a4f6e3b90",
+			expectedSampled:  true,
+			expectedDebug:    true,
+		},
+		{
+			name:          "MultiNotFound",
+			single:        false,
+			headers:       map[string]string{"b3": "64fe8b2a57d3eff7-e457b5a2e4d86bd1"},
+			expectedError: opentracing.ErrSpanContextNotFound,
+		},
+		{
+			name:          "MultiInvalidTraceID",
+			single:        false,
+			headers:       map[string]string{"x-b3-traceid": "64fe8b", "x-b3-spanid": "e457b5a2e4d86bd1"},
+			expectedError: opentracing.ErrSpanContextCorrupted,
+		},
+		{
+			name:          "MultiMissingSpanID",
+			single:        false,
+			headers:       map[string]string{"x-b3-traceid": "64fe8b2a57d3eff7"},
+			expectedError: opentracing.ErrSpanContextCorrupted,
+		},
+		{
+			name:             "Multi",
+			single:           false,
+			headers:          map[string]string{"X-B3-TraceId": "64fe8b2a57d3eff7", "X-B3-SpanId": "e457b5a2e4d86bd1", "X-B3-ParentSpanId": "05e3ac9a4f6e3b90", "X-B3-Sampled": "1"},
+			expectedTraceID:  "64fe8b2a57d3eff7",
+			expectedSpanID:   "e457b5a2e4d86bd1",
+			expectedParentID: "5e3ac9a4f6e3b90",
+			expectedSampled:  true,
+		},
+		{
+			name:            "MultiDeferred",
+			single:          false,
+			sampler:         jaeger.NewConstSampler(true),
+			headers:         map[string]string{"x-b3-traceid": "64fe8b2a57d3eff7", "x-b3-spanid": "e457b5a2e4d86bd1"},
+			expectedTraceID: "64fe8b2a57d3eff7",
+			expectedSpanID:  "e457b5a2e4d86bd1",
+			expectedSampled: true,
+		},
+		{
+			name:            "MultiDeferredNotSampled",
+			single:          false,
+			sampler:         jaeger.NewConstSampler(false),
+			headers:         map[string]string{"x-b3-traceid": "64fe8b2a57d3eff7", "x-b3-spanid": "e457b5a2e4d86bd1"},
+			expectedTraceID: "64fe8b2a57d3eff7",
+			expectedSpanID:  "e457b5a2e4d86bd1",
+			expectedSampled: false,
+		},
+		{
+			name:            "MultiDebug",
+			single:          false,
+			headers:         map[string]string{"x-b3-traceid": "64fe8b2a57d3eff7", "x-b3-spanid": "e457b5a2e4d86bd1", "x-b3-flags": "1"},
+			expectedTraceID: "64fe8b2a57d3eff7",
+			expectedSpanID:  "e457b5a2e4d86bd1",
+			expectedSampled: true,
+			expectedDebug:   true,
+		},
+		{
+			name:             "SingleDebug",
+			single:           true,
+			headers:          map[string]string{"b3": "64fe8b2a57d3eff7-e457b5a2e4d86bd1-d-05e3ac9a4f6e3b90"},
+			expectedTraceID:  "64fe8b2a57d3eff7",
+			expectedSpanID:   "e457b5a2e4d86bd1",
+			expectedParentID: "5e3ac9a4f6e3b90",
+			expectedSampled:  true,
+			expectedDebug:    true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, err := (&b3Propagator{single: tc.single, sampler: tc.sampler}).Extract(opentracing.TextMapCarrier(tc.headers))
+
+			if tc.expectedError != nil {
+				assert.Equal(t, tc.expectedError, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedTraceID, ctx.TraceID().String())
+				assert.Equal(t, tc.expectedSpanID, ctx.SpanID().String())
+				if tc.expectedParentID != "" {
+					assert.Equal(t, tc.expectedParentID, ctx.ParentID().String())
+				} else {
+					assert.Equal(t, jaeger.SpanID(0), ctx.ParentID())
+				}
+				assert.Equal(t, tc.expectedSampled, ctx.IsSampled())
+				assert.Equal(t, tc.expectedDebug, ctx.IsDebug())
+			}
+		})
+	}
+}
+
+func TestCompositePropagator(t *testing.T) {
+	p, err := newPropagator(opentracing.HTTPHeaders, []Propagation{PropagationW3C, PropagationB3Multi, PropagationJaeger}, nil, newTraceStates(10))
+	assert.NoError(t, err)
+
+	traceID := jaeger.TraceID{High: 0x4bf92f3577b34da6, Low: 0xa3ce929d0e0e4736}
+	ctx := jaeger.NewSpanContext(traceID, jaeger.SpanID(0x00f067aa0ba902b7), 0, true, nil)
+
+	t.Run("Inject", func(t *testing.T) {
+		header := http.Header{}
+		err := p.Inject(ctx, opentracing.HTTPHeadersCarrier(header))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", header.Get("traceparent"))
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", header.Get("x-b3-traceid"))
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736:f067aa0ba902b7:0:1", header.Get("uber-trace-id"))
+	})
+
+	t.Run("InjectInvalidCarrier", func(t *testing.T) {
+		err := p.Inject(ctx, "invalid")
+		assert.Equal(t, opentracing.ErrInvalidCarrier, err)
+	})
+
+	tests := []struct {
+		name            string
+		header          http.Header
+		expectedError   error
+		expectedTraceID string
+	}{
+		{
+			name:          "NotFound",
+			header:        http.Header{},
+			expectedError: opentracing.ErrSpanContextNotFound,
+		},
+		{
+			name:          "Corrupted",
+			header:        http.Header{"Traceparent": []string{"invalid"}},
+			expectedError: opentracing.ErrSpanContextCorrupted,
+		},
+		{
+			name:            "FallbackOnCorrupted",
+			header:          http.Header{"Traceparent": []string{"invalid"}, "X-B3-Traceid": []string{"64fe8b2a57d3eff7"}, "X-B3-Spanid": []string{"e457b5a2e4d86bd1"}},
+			expectedTraceID: "64fe8b2a57d3eff7",
+		},
+		{
+			name:            "FirstFormat",
+			header:          http.Header{"Traceparent": []string{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}, "Uber-Trace-Id": []string{"64fe8b2a57d3eff7:e457b5a2e4d86bd1:0:1"}},
+			expectedTraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+		},
+		{
+			name:            "LastFormat",
+			header:          http.Header{"Uber-Trace-Id": []string{"64fe8b2a57d3eff7:e457b5a2e4d86bd1:0:1"}},
+			expectedTraceID: "64fe8b2a57d3eff7",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, err := p.Extract(opentracing.HTTPHeadersCarrier(tc.header))
+
+			if tc.expectedError != nil {
+				assert.Equal(t, tc.expectedError, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedTraceID, ctx.TraceID().String())
+			}
+		})
+	}
+}
+
+func TestTraceStates(t *testing.T) {
+	states := newTraceStates(2)
+	id1 := jaeger.TraceID{Low: 1}
+	id2 := jaeger.TraceID{Low: 2}
+	id3 := jaeger.TraceID{Low: 3}
+
+	states.set(id1, "a=1")
+	states.set(id2, "b=2")
+	states.set(id1, "a=2")
+	assert.Equal(t, "a=2", states.get(id1))
+	assert.Equal(t, "b=2", states.get(id2))
+
+	states.set(id3, "c=3")
+	assert.Empty(t, states.get(id1))
+	assert.Equal(t, "b=2", states.get(id2))
+	assert.Equal(t, "c=3", states.get(id3))
+	assert.Len(t, states.states, 2)
+
+	var nilStates *traceStates
+	nilStates.set(id1, "a=1")
+	assert.Empty(t, nilStates.get(id1))
+}
@@ -0,0 +1,265 @@
+package trace
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	opentracing "github.com/opentracing/opentracing-go"
+	jaeger "github.com/uber/jaeger-client-go"
+)
+
+const defaultProviderQueueSize = 2048
+
+// ProviderOptions contains optional options for Provider
+//   Options are the options for creating the tracer
+//   Global true installs the tracer as the opentracing global tracer
+//   QueueSize is the maximum number of finished spans waiting to be reported (default 2048)
+type ProviderOptions struct {
+	Options
+	Global    bool
+	QueueSize int
+}
+
+// Provider owns a tracer and manages its lifecycle
+// Finished spans are queued and reported in the background until the provider is shut down.
+type Provider struct {
+	tracer   opentracing.Tracer
+	closer   io.Closer
+	reporter *queueReporter
+	logger   log.Logger
+	stop     chan struct{}
+	once     sync.Once
+	err      error
+}
+
+// NewProvider creates a new tracer provider
+func NewProvider(opts ProviderOptions) (*Provider, error) {
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = defaultProviderQueueSize
+	}
+
+	var reporter *queueReporter
+	tracer, closer, err := newTracer(opts.Options, func(next jaeger.Reporter) jaeger.Reporter {
+		reporter = newQueueReporter(next, opts.QueueSize)
+		return reporter
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Global {
+		opentracing.SetGlobalTracer(tracer)
+	}
+
+	logger := opts.Logger
+	if logger == nil {
+		logger = log.NewNopLogger()
+	}
+
+	return &Provider{
+		tracer:   tracer,
+		closer:   closer,
+		reporter: reporter,
+		logger:   logger,
+		stop:     make(chan struct{}),
+	}, nil
+}
+
+// Tracer returns the tracer owned by the provider
+func (p *Provider) Tracer() opentracing.Tracer {
+	return p.tracer
+}
+
+// Shutdown flushes all pending spans and closes the tracer
+// Spans that are not reported before the context is done and spans finished after shutdown are dropped.
+// It returns the total number of dropped spans and an error if the context is done before the tracer is closed.
+// Calling Shutdown more than once only returns the number of dropped spans and the error of the first call.
+func (p *Provider) Shutdown(ctx context.Context) (int, error) {
+	p.once.Do(func() {
+		close(p.stop)
+
+		p.reporter.flush(ctx)
+
+		// Closing the tracer also closes the underlying reporter which may flush its own buffer
+		done := make(chan struct{})
+		go func() {
+			p.closer.Close()
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-ctx.Done():
+			p.err = ctx.Err()
+		}
+	})
+
+	return p.reporter.Dropped(), p.err
+}
+
+// ShutdownOnSignal shuts down the provider when one of the signals is received (default SIGINT and SIGTERM)
+// The provider is given the timeout for flushing pending spans.
+// After the provider is shut down, the signal is raised again, so the default behavior for the signal (i.e. exiting) is not lost.
+// It returns a channel that is closed after the provider is shut down by a signal or by calling Shutdown.
+func (p *Provider) ShutdownOnSignal(timeout time.Duration, signals ...os.Signal) <-chan struct{} {
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGINT, syscall.SIGTERM}
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, signals...)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer signal.Stop(sigCh)
+
+		select {
+		case sig := <-sigCh:
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			dropped, err := p.Shutdown(ctx)
+			if err != nil {
+				level.Error(p.logger).Log("message", fmt.Sprintf("error on shutting down tracer: %s", err), "signal", sig.String(), "dropped", dropped)
+			} else {
+				level.Info(p.logger).Log("message", "tracer shut down", "signal", sig.String(), "dropped", dropped)
+			}
+
+			// Stop relaying the signal and raise it again for the next handler or the default behavior
+			signal.Stop(sigCh)
+			if proc, err := os.FindProcess(os.Getpid()); err == nil {
+				proc.Signal(sig)
+			}
+
+		case <-p.stop:
+		}
+	}()
+
+	return done
+}
+
+// queueReporter queues finished spans and reports them to the next reporter in the background
+// Spans are reported after Report returns, so the tracer must not pool spans (see newTracer).
+type queueReporter struct {
+	sync.RWMutex
+	next    jaeger.Reporter
+	queue   chan *jaeger.Span
+	closed  bool
+	dropped int64
+	stop    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+}
+
+func newQueueReporter(next jaeger.Reporter, size int) *queueReporter {
+	r := &queueReporter{
+		next:  next,
+		queue: make(chan *jaeger.Span, size),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+
+	go r.run()
+
+	return r
+}
+
+// Report implements jaeger.Reporter interface
+func (r *queueReporter) Report(span *jaeger.Span) {
+	r.RLock()
+	defer r.RUnlock()
+
+	if r.closed {
+		atomic.AddInt64(&r.dropped, 1)
+		return
+	}
+
+	select {
+	case r.queue <- span:
+	default:
+		atomic.AddInt64(&r.dropped, 1)
+	}
+}
+
+// Close implements jaeger.Reporter interface
+// It flushes all queued spans and closes the next reporter.
+func (r *queueReporter) Close() {
+	r.flush(context.Background())
+	r.next.Close()
+}
+
+// Dropped returns the number of spans dropped so far
+func (r *queueReporter) Dropped() int {
+	return int(atomic.LoadInt64(&r.dropped))
+}
+
+func (r *queueReporter) run() {
+	defer close(r.done)
+
+	for {
+		select {
+		case s := <-r.queue:
+			r.next.Report(s)
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// drop removes all queued spans and counts them as dropped
+func (r *queueReporter) drop() {
+	for {
+		select {
+		case <-r.queue:
+			atomic.AddInt64(&r.dropped, 1)
+		default:
+			return
+		}
+	}
+}
+
+// flush stops accepting new spans and reports queued spans until the context is done
+// Spans remaining in the queue after the context is done are dropped.
+func (r *queueReporter) flush(ctx context.Context) {
+	r.once.Do(func() {
+		r.Lock()
+		r.closed = true
+		r.Unlock()
+
+		close(r.stop)
+
+		select {
+		case <-r.done:
+		case <-ctx.Done():
+			r.drop()
+			return
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				r.drop()
+				return
+			default:
+			}
+
+			select {
+			case s := <-r.queue:
+				r.next.Report(s)
+			default:
+				return
+			}
+		}
+	})
+}
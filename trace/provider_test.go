@@ -0,0 +1,233 @@
+package trace
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	jaeger "github.com/uber/jaeger-client-go"
+)
+
+// blockingReporter is a span reporter blocking on reporting spans until it is released
+type blockingReporter struct {
+	*Recorder
+	release chan struct{}
+	closed  chan struct{}
+	once    sync.Once
+}
+
+func newBlockingReporter() *blockingReporter {
+	return &blockingReporter{
+		Recorder: NewRecorder(),
+		release:  make(chan struct{}),
+		closed:   make(chan struct{}),
+	}
+}
+
+func (r *blockingReporter) Report(span *jaeger.Span) {
+	<-r.release
+	r.Recorder.Report(span)
+}
+
+func (r *blockingReporter) Close() {
+	r.once.Do(func() {
+		close(r.closed)
+	})
+}
+
+func TestNewProvider(t *testing.T) {
+	tests := []struct {
+		name          string
+		opts          ProviderOptions
+		expectedError string
+	}{
+		{
+			name: "Defaults",
+			opts: ProviderOptions{},
+		},
+		{
+			name: "WithSpanReporter",
+			opts: ProviderOptions{
+				Options: Options{
+					Name:         "service_name",
+					SpanReporter: NewRecorder(),
+				},
+				QueueSize: 10,
+			},
+		},
+		{
+			name: "InvalidPropagation",
+			opts: ProviderOptions{
+				Options: Options{
+					Propagation: []Propagation{Propagation(100)},
+				},
+			},
+			expectedError: "unknown propagation format: 100",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			provider, err := NewProvider(tc.opts)
+
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				assert.Nil(t, provider)
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, provider)
+				assert.NotNil(t, provider.Tracer())
+
+				dropped, err := provider.Shutdown(context.Background())
+				assert.NoError(t, err)
+				assert.Equal(t, 0, dropped)
+			}
+		})
+	}
+}
+
+func TestProviderGlobal(t *testing.T) {
+	defer opentracing.SetGlobalTracer(opentracing.NoopTracer{})
+
+	provider, err := NewProvider(ProviderOptions{
+		Options: Options{
+			SpanReporter: NewRecorder(),
+		},
+		Global: true,
+	})
+	assert.NoError(t, err)
+	defer provider.Shutdown(context.Background())
+
+	assert.Equal(t, provider.Tracer(), opentracing.GlobalTracer())
+}
+
+func TestProviderShutdown(t *testing.T) {
+	recorder := NewRecorder()
+	provider, err := NewProvider(ProviderOptions{
+		Options: Options{
+			SpanReporter: recorder,
+		},
+	})
+	assert.NoError(t, err)
+
+	for _, op := range []string{"first", "second", "third"} {
+		provider.Tracer().StartSpan(op).Finish()
+	}
+
+	dropped, err := provider.Shutdown(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, dropped)
+	assert.Len(t, recorder.Spans(), 3)
+
+	// Spans finished after shutdown are dropped
+	provider.Tracer().StartSpan("late").Finish()
+
+	dropped, err = provider.Shutdown(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, dropped)
+	assert.Len(t, recorder.Spans(), 3)
+}
+
+func TestProviderShutdownDeadline(t *testing.T) {
+	reporter := newBlockingReporter()
+	defer close(reporter.release)
+
+	provider, err := NewProvider(ProviderOptions{
+		Options: Options{
+			SpanReporter: reporter,
+		},
+		QueueSize: 2,
+	})
+	assert.NoError(t, err)
+
+	// The first span is being reported, the next two spans are queued, and the rest are dropped
+	provider.Tracer().StartSpan("first").Finish()
+	assert.Eventually(t, func() bool {
+		return len(provider.reporter.queue) == 0
+	}, time.Second, time.Millisecond)
+	for i := 0; i < 4; i++ {
+		provider.Tracer().StartSpan("next").Finish()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	dropped, err := provider.Shutdown(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, 4, dropped)
+}
+
+func TestProviderShutdownOnSignal(t *testing.T) {
+	t.Run("Signal", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		recorder := NewRecorder()
+		provider, err := NewProvider(ProviderOptions{
+			Options: Options{
+				SpanReporter: recorder,
+				Logger:       log.NewJSONLogger(buf),
+			},
+		})
+		assert.NoError(t, err)
+
+		// The signal is also relayed here, so the test process is not terminated when the signal is raised again
+		sigCh := make(chan os.Signal, 2)
+		signal.Notify(sigCh, syscall.SIGUSR1)
+		defer signal.Stop(sigCh)
+
+		done := provider.ShutdownOnSignal(time.Second, syscall.SIGUSR1)
+		provider.Tracer().StartSpan("test").Finish()
+
+		assert.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR1))
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("provider is not shut down")
+		}
+
+		// The original signal and the raised signal
+		for i := 0; i < 2; i++ {
+			select {
+			case <-sigCh:
+			case <-time.After(time.Second):
+				t.Fatal("signal is not raised again")
+			}
+		}
+
+		assert.Len(t, recorder.Spans(), 1)
+
+		lines := decodeLogLines(t, buf)
+		assert.Len(t, lines, 1)
+		assert.Equal(t, "info", lines[0]["level"])
+		assert.Equal(t, "tracer shut down", lines[0]["message"])
+		assert.Equal(t, float64(0), lines[0]["dropped"])
+	})
+
+	t.Run("Shutdown", func(t *testing.T) {
+		provider, err := NewProvider(ProviderOptions{
+			Options: Options{
+				SpanReporter: NewRecorder(),
+			},
+		})
+		assert.NoError(t, err)
+
+		done := provider.ShutdownOnSignal(time.Second)
+
+		_, err = provider.Shutdown(context.Background())
+		assert.NoError(t, err)
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("signal handler is not stopped")
+		}
+	})
+}
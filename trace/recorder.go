@@ -0,0 +1,126 @@
+package trace
+
+import (
+	"sync"
+
+	jaeger "github.com/uber/jaeger-client-go"
+)
+
+// Recorder is a span reporter keeping finished spans in memory
+// It can be used for verifying spans in tests.
+type Recorder struct {
+	sync.Mutex
+	spans []SpanData
+}
+
+// NewRecorder creates a new in-memory span reporter
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Report implements jaeger.Reporter interface
+func (r *Recorder) Report(span *jaeger.Span) {
+	data := newSpanData(span)
+
+	r.Lock()
+	defer r.Unlock()
+
+	r.spans = append(r.spans, data)
+}
+
+// Close implements jaeger.Reporter interface
+func (r *Recorder) Close() {}
+
+// Reset removes all recorded spans
+func (r *Recorder) Reset() {
+	r.Lock()
+	defer r.Unlock()
+
+	r.spans = nil
+}
+
+// Spans returns all recorded spans in the order they are finished
+func (r *Recorder) Spans() []SpanData {
+	return r.filter(func(s SpanData) bool {
+		return true
+	})
+}
+
+// SpansByOperation returns all recorded spans with an operation name
+func (r *Recorder) SpansByOperation(operation string) []SpanData {
+	return r.filter(func(s SpanData) bool {
+		return s.Operation == operation
+	})
+}
+
+// SpansByTag returns all recorded spans having a tag with a value
+func (r *Recorder) SpansByTag(key string, value interface{}) []SpanData {
+	return r.filter(func(s SpanData) bool {
+		v, ok := s.Tags[key]
+		return ok && tagEqual(v, value)
+	})
+}
+
+// Trace returns all recorded spans of a trace
+func (r *Recorder) Trace(traceID string) []SpanData {
+	return r.filter(func(s SpanData) bool {
+		return s.TraceID == traceID
+	})
+}
+
+// Children returns all recorded spans with a parent span
+func (r *Recorder) Children(spanID string) []SpanData {
+	return r.filter(func(s SpanData) bool {
+		return s.ParentID == spanID
+	})
+}
+
+func (r *Recorder) filter(f func(SpanData) bool) []SpanData {
+	r.Lock()
+	defer r.Unlock()
+
+	spans := []SpanData{}
+	for _, s := range r.spans {
+		if f(s) {
+			spans = append(spans, s)
+		}
+	}
+
+	return spans
+}
+
+// tagEqual compares a recorded tag value with a given value
+// Jaeger records all integers as int64 and all floats as float64.
+func tagEqual(recorded, value interface{}) bool {
+	switch v := value.(type) {
+	case int:
+		value = int64(v)
+	case int8:
+		value = int64(v)
+	case int16:
+		value = int64(v)
+	case int32:
+		value = int64(v)
+	case uint:
+		value = int64(v)
+	case uint8:
+		value = int64(v)
+	case uint16:
+		value = int64(v)
+	case uint32:
+		value = int64(v)
+	case uint64:
+		value = int64(v)
+	case float32:
+		value = float64(v)
+	}
+
+	if b, ok := recorded.([]byte); ok {
+		if v, ok := value.([]byte); ok {
+			return string(b) == string(v)
+		}
+		return false
+	}
+
+	return recorded == value
+}
@@ -0,0 +1,152 @@
+package trace
+
+import (
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	jaeger "github.com/uber/jaeger-client-go"
+)
+
+func newTestTracer(reporter jaeger.Reporter) (opentracing.Tracer, func()) {
+	tracer, closer := jaeger.NewTracer("test_service", jaeger.NewConstSampler(true), reporter)
+	return tracer, func() { closer.Close() }
+}
+
+func TestRecorder(t *testing.T) {
+	tests := []struct {
+		name          string
+		operations    []string
+		tags          map[string]interface{}
+		operation     string
+		tagKey        string
+		tagValue      interface{}
+		expectedSpans int
+		expectedByOp  int
+		expectedByTag int
+	}{
+		{
+			name:          "NoSpan",
+			operations:    []string{},
+			operation:     "query",
+			tagKey:        "db.type",
+			tagValue:      "sql",
+			expectedSpans: 0,
+			expectedByOp:  0,
+			expectedByTag: 0,
+		},
+		{
+			name:          "StringTag",
+			operations:    []string{"query", "query", "publish"},
+			tags:          map[string]interface{}{"db.type": "sql"},
+			operation:     "query",
+			tagKey:        "db.type",
+			tagValue:      "sql",
+			expectedSpans: 3,
+			expectedByOp:  2,
+			expectedByTag: 3,
+		},
+		{
+			name:          "IntTag",
+			operations:    []string{"request", "request"},
+			tags:          map[string]interface{}{"http.status_code": 200},
+			operation:     "request",
+			tagKey:        "http.status_code",
+			tagValue:      200,
+			expectedSpans: 2,
+			expectedByOp:  2,
+			expectedByTag: 2,
+		},
+		{
+			name:          "TagMismatch",
+			operations:    []string{"request"},
+			tags:          map[string]interface{}{"http.status_code": 200},
+			operation:     "query",
+			tagKey:        "http.status_code",
+			tagValue:      "200",
+			expectedSpans: 1,
+			expectedByOp:  0,
+			expectedByTag: 0,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := NewRecorder()
+			tracer, close := newTestTracer(recorder)
+			defer close()
+
+			for _, op := range tc.operations {
+				span := tracer.StartSpan(op)
+				for k, v := range tc.tags {
+					span.SetTag(k, v)
+				}
+				span.Finish()
+			}
+
+			assert.Len(t, recorder.Spans(), tc.expectedSpans)
+			assert.Len(t, recorder.SpansByOperation(tc.operation), tc.expectedByOp)
+			assert.Len(t, recorder.SpansByTag(tc.tagKey, tc.tagValue), tc.expectedByTag)
+
+			recorder.Reset()
+			assert.Len(t, recorder.Spans(), 0)
+		})
+	}
+}
+
+func TestRecorderTrace(t *testing.T) {
+	recorder := NewRecorder()
+	tracer, close := newTestTracer(recorder)
+	defer close()
+
+	parent := tracer.StartSpan("parent")
+	child1 := tracer.StartSpan("child1", opentracing.ChildOf(parent.Context()))
+	child2 := tracer.StartSpan("child2", opentracing.ChildOf(parent.Context()))
+	other := tracer.StartSpan("other")
+
+	child1.Finish()
+	child2.Finish()
+	parent.Finish()
+	other.Finish()
+
+	parentCtx := parent.Context().(jaeger.SpanContext)
+	spans := recorder.Trace(parentCtx.TraceID().String())
+	assert.Len(t, spans, 3)
+	assert.Equal(t, "child1", spans[0].Operation)
+	assert.Equal(t, "child2", spans[1].Operation)
+	assert.Equal(t, "parent", spans[2].Operation)
+
+	children := recorder.Children(parentCtx.SpanID().String())
+	assert.Len(t, children, 2)
+	for _, c := range children {
+		assert.Equal(t, parentCtx.TraceID().String(), c.TraceID)
+		assert.Equal(t, parentCtx.SpanID().String(), c.ParentID)
+	}
+
+	assert.Len(t, recorder.Spans(), 4)
+}
+
+func TestTagEqual(t *testing.T) {
+	tests := []struct {
+		recorded interface{}
+		value    interface{}
+		expected bool
+	}{
+		{"value", "value", true},
+		{"value", "other", false},
+		{int64(10), 10, true},
+		{int64(10), int32(10), true},
+		{int64(10), uint8(10), true},
+		{int64(10), 11, false},
+		{float64(0.5), float32(0.5), true},
+		{float64(0.5), 0.5, true},
+		{true, true, true},
+		{true, "true", false},
+		{[]byte("data"), []byte("data"), true},
+		{[]byte("data"), "data", false},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, tc.expected, tagEqual(tc.recorded, tc.value))
+	}
+}
@@ -0,0 +1,100 @@
+package trace
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	jaeger "github.com/uber/jaeger-client-go"
+)
+
+// perOperationSampler implements jaeger.Sampler with a probabilistic sampler per operation
+type perOperationSampler struct {
+	defaultSampler *jaeger.ProbabilisticSampler
+	samplers       map[string]*jaeger.ProbabilisticSampler
+}
+
+// NewPerOperationSampler creates a Jaeger sampler with a sampling probability per operation
+//   defaultProbability is the probability between 0 and 1 for operations not in probabilities
+//   probabilities maps operation names to probabilities between 0 and 1
+// The sampling decision is made when the root span of a trace is started and child spans follow the decision of their parents.
+func NewPerOperationSampler(defaultProbability float64, probabilities map[string]float64) (jaeger.Sampler, error) {
+	defaultSampler, err := jaeger.NewProbabilisticSampler(defaultProbability)
+	if err != nil {
+		return nil, err
+	}
+
+	samplers := make(map[string]*jaeger.ProbabilisticSampler, len(probabilities))
+	for op, p := range probabilities {
+		sampler, err := jaeger.NewProbabilisticSampler(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid probability for operation %s: %s", op, err)
+		}
+		samplers[op] = sampler
+	}
+
+	return &perOperationSampler{
+		defaultSampler: defaultSampler,
+		samplers:       samplers,
+	}, nil
+}
+
+// ParseOperationProbabilities parses a table of operation probabilities (i.e. http-server-request=0.1, /healthz=0)
+// Entries are separated by commas and operation names and probabilities are separated by the last equal sign.
+func ParseOperationProbabilities(table string) (map[string]float64, error) {
+	probabilities := map[string]float64{}
+
+	for _, entry := range strings.Split(table, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		i := strings.LastIndex(entry, "=")
+		if i == -1 {
+			return nil, fmt.Errorf("invalid entry %q: expected operation=probability", entry)
+		}
+
+		op := strings.TrimSpace(entry[:i])
+		if op == "" {
+			return nil, fmt.Errorf("invalid entry %q: operation is empty", entry)
+		}
+
+		p, err := strconv.ParseFloat(strings.TrimSpace(entry[i+1:]), 64)
+		if err != nil || p < 0 || p > 1 {
+			return nil, fmt.Errorf("invalid entry %q: probability must be a number between 0 and 1", entry)
+		}
+
+		probabilities[op] = p
+	}
+
+	return probabilities, nil
+}
+
+// IsSampled implements jaeger.Sampler interface
+func (s *perOperationSampler) IsSampled(id jaeger.TraceID, operation string) (bool, []jaeger.Tag) {
+	if sampler, ok := s.samplers[operation]; ok {
+		return sampler.IsSampled(id, operation)
+	}
+
+	return s.defaultSampler.IsSampled(id, operation)
+}
+
+// Close implements jaeger.Sampler interface
+func (s *perOperationSampler) Close() {}
+
+// Equal implements jaeger.Sampler interface
+func (s *perOperationSampler) Equal(other jaeger.Sampler) bool {
+	o, ok := other.(*perOperationSampler)
+	if !ok || !s.defaultSampler.Equal(o.defaultSampler) || len(s.samplers) != len(o.samplers) {
+		return false
+	}
+
+	for op, sampler := range s.samplers {
+		if os, ok := o.samplers[op]; !ok || !sampler.Equal(os) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,150 @@
+package trace
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	jaeger "github.com/uber/jaeger-client-go"
+)
+
+func TestNewPerOperationSampler(t *testing.T) {
+	tests := []struct {
+		name               string
+		defaultProbability float64
+		probabilities      map[string]float64
+		expectedError      string
+	}{
+		{
+			name:               "InvalidDefault",
+			defaultProbability: 1.5,
+			expectedError:      "Sampling Rate must be between 0.0 and 1.0, received 1.500000",
+		},
+		{
+			name:               "InvalidOperation",
+			defaultProbability: 1,
+			probabilities:      map[string]float64{"/healthz": -1},
+			expectedError:      "invalid probability for operation /healthz: Sampling Rate must be between 0.0 and 1.0, received -1.000000",
+		},
+		{
+			name:               "Success",
+			defaultProbability: 1,
+			probabilities:      map[string]float64{"http-server-request": 0.1, "/healthz": 0},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sampler, err := NewPerOperationSampler(tc.defaultProbability, tc.probabilities)
+
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				assert.Nil(t, sampler)
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, sampler)
+			}
+		})
+	}
+}
+
+func TestParseOperationProbabilities(t *testing.T) {
+	tests := []struct {
+		name                  string
+		table                 string
+		expectedProbabilities map[string]float64
+		expectedError         string
+	}{
+		{
+			name:                  "Empty",
+			table:                 "",
+			expectedProbabilities: map[string]float64{},
+		},
+		{
+			name:                  "Success",
+			table:                 "http-server-request=0.1, /healthz=0 ,grpc-server-request=1,",
+			expectedProbabilities: map[string]float64{"http-server-request": 0.1, "/healthz": 0, "grpc-server-request": 1},
+		},
+		{
+			name:                  "EqualSignInOperation",
+			table:                 "GET /items?id=1=0.5",
+			expectedProbabilities: map[string]float64{"GET /items?id=1": 0.5},
+		},
+		{
+			name:          "MissingProbability",
+			table:         "http-server-request",
+			expectedError: `invalid entry "http-server-request": expected operation=probability`,
+		},
+		{
+			name:          "MissingOperation",
+			table:         "=0.1",
+			expectedError: `invalid entry "=0.1": operation is empty`,
+		},
+		{
+			name:          "InvalidProbability",
+			table:         "/healthz=none",
+			expectedError: `invalid entry "/healthz=none": probability must be a number between 0 and 1`,
+		},
+		{
+			name:          "OutOfRangeProbability",
+			table:         "/healthz=2",
+			expectedError: `invalid entry "/healthz=2": probability must be a number between 0 and 1`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			probabilities, err := ParseOperationProbabilities(tc.table)
+
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				assert.Nil(t, probabilities)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedProbabilities, probabilities)
+			}
+		})
+	}
+}
+
+func TestPerOperationSampler(t *testing.T) {
+	sampler, err := NewPerOperationSampler(1, map[string]float64{"/healthz": 0, "query": 0.5})
+	assert.NoError(t, err)
+	defer sampler.Close()
+
+	low := jaeger.TraceID{Low: 1}
+	high := jaeger.TraceID{Low: 0x7fffffffffffffff}
+
+	tests := []struct {
+		operation       string
+		traceID         jaeger.TraceID
+		expectedSampled bool
+	}{
+		{"http-server-request", low, true},
+		{"http-server-request", high, true},
+		{"/healthz", low, false},
+		{"/healthz", high, false},
+		{"query", low, true},
+		{"query", high, false},
+	}
+
+	for _, tc := range tests {
+		sampled, tags := sampler.IsSampled(tc.traceID, tc.operation)
+		assert.Equal(t, tc.expectedSampled, sampled, tc.operation)
+		assert.Len(t, tags, 2)
+	}
+}
+
+func TestPerOperationSamplerEqual(t *testing.T) {
+	sampler, _ := NewPerOperationSampler(1, map[string]float64{"/healthz": 0})
+	same, _ := NewPerOperationSampler(1, map[string]float64{"/healthz": 0})
+	differentDefault, _ := NewPerOperationSampler(0.5, map[string]float64{"/healthz": 0})
+	differentOperation, _ := NewPerOperationSampler(1, map[string]float64{"/ready": 0})
+	differentProbability, _ := NewPerOperationSampler(1, map[string]float64{"/healthz": 0.1})
+	probabilistic, _ := jaeger.NewProbabilisticSampler(1)
+
+	assert.True(t, sampler.Equal(same))
+	assert.False(t, sampler.Equal(differentDefault))
+	assert.False(t, sampler.Equal(differentOperation))
+	assert.False(t, sampler.Equal(differentProbability))
+	assert.False(t, sampler.Equal(probabilistic))
+}
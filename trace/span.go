@@ -0,0 +1,84 @@
+package trace
+
+import (
+	"time"
+
+	jaeger "github.com/uber/jaeger-client-go"
+	jthrift "github.com/uber/jaeger-client-go/thrift-gen/jaeger"
+)
+
+type (
+	// SpanData is a snapshot of a finished span
+	SpanData struct {
+		TraceID   string                 `json:"traceId"`
+		SpanID    string                 `json:"spanId"`
+		ParentID  string                 `json:"parentId,omitempty"`
+		Service   string                 `json:"service"`
+		Operation string                 `json:"operation"`
+		StartTime time.Time              `json:"startTime"`
+		Duration  time.Duration          `json:"duration"`
+		Tags      map[string]interface{} `json:"tags,omitempty"`
+		Logs      []LogData              `json:"logs,omitempty"`
+	}
+
+	// LogData is a snapshot of a log record of a finished span
+	LogData struct {
+		Timestamp time.Time              `json:"timestamp"`
+		Fields    map[string]interface{} `json:"fields"`
+	}
+)
+
+// newSpanData creates a snapshot of a Jaeger span
+// A snapshot has to be created before Report returns since Jaeger spans can be pooled and reused.
+func newSpanData(span *jaeger.Span) SpanData {
+	ctx, _ := span.Context().(jaeger.SpanContext)
+	js := jaeger.BuildJaegerThrift(span)
+	process := jaeger.BuildJaegerProcessThrift(span)
+
+	data := SpanData{
+		TraceID:   ctx.TraceID().String(),
+		SpanID:    ctx.SpanID().String(),
+		Service:   process.ServiceName,
+		Operation: js.OperationName,
+		StartTime: time.Unix(0, js.StartTime*int64(time.Microsecond)),
+		Duration:  time.Duration(js.Duration) * time.Microsecond,
+		Tags:      tagsToMap(js.Tags),
+	}
+
+	if ctx.ParentID() != 0 {
+		data.ParentID = ctx.ParentID().String()
+	}
+
+	for _, l := range js.Logs {
+		data.Logs = append(data.Logs, LogData{
+			Timestamp: time.Unix(0, l.Timestamp*int64(time.Microsecond)),
+			Fields:    tagsToMap(l.Fields),
+		})
+	}
+
+	return data
+}
+
+func tagsToMap(tags []*jthrift.Tag) map[string]interface{} {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	m := make(map[string]interface{}, len(tags))
+	for _, t := range tags {
+		switch t.VType {
+		case jthrift.TagType_STRING:
+			m[t.Key] = t.GetVStr()
+		case jthrift.TagType_DOUBLE:
+			m[t.Key] = t.GetVDouble()
+		case jthrift.TagType_BOOL:
+			m[t.Key] = t.GetVBool()
+		case jthrift.TagType_LONG:
+			m[t.Key] = t.GetVLong()
+		case jthrift.TagType_BINARY:
+			m[t.Key] = t.GetVBinary()
+		}
+	}
+
+	return m
+}
@@ -0,0 +1,52 @@
+package trace
+
+import (
+	"testing"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	jaeger "github.com/uber/jaeger-client-go"
+)
+
+func TestNewSpanData(t *testing.T) {
+	recorder := NewRecorder()
+	tracer, close := newTestTracer(recorder)
+	defer close()
+
+	start := time.Now()
+	parent := tracer.StartSpan("parent", opentracing.StartTime(start))
+	child := tracer.StartSpan("child", opentracing.ChildOf(parent.Context()), opentracing.StartTime(start))
+	child.SetTag("string", "value")
+	child.SetTag("int", 10)
+	child.SetTag("float", 0.5)
+	child.SetTag("bool", true)
+	child.LogKV("event", "done")
+	child.FinishWithOptions(opentracing.FinishOptions{FinishTime: start.Add(2 * time.Second)})
+	parent.Finish()
+
+	spans := recorder.SpansByOperation("child")
+	assert.Len(t, spans, 1)
+
+	parentCtx := parent.Context().(jaeger.SpanContext)
+	childCtx := child.Context().(jaeger.SpanContext)
+
+	data := spans[0]
+	assert.Equal(t, childCtx.TraceID().String(), data.TraceID)
+	assert.Equal(t, childCtx.SpanID().String(), data.SpanID)
+	assert.Equal(t, parentCtx.SpanID().String(), data.ParentID)
+	assert.Equal(t, "test_service", data.Service)
+	assert.Equal(t, "child", data.Operation)
+	assert.Equal(t, start.Truncate(time.Microsecond).UnixNano(), data.StartTime.UnixNano())
+	assert.Equal(t, 2*time.Second, data.Duration)
+	assert.Equal(t, "value", data.Tags["string"])
+	assert.Equal(t, int64(10), data.Tags["int"])
+	assert.Equal(t, 0.5, data.Tags["float"])
+	assert.Equal(t, true, data.Tags["bool"])
+	assert.Len(t, data.Logs, 1)
+	assert.Equal(t, "done", data.Logs[0].Fields["event"])
+
+	spans = recorder.SpansByOperation("parent")
+	assert.Len(t, spans, 1)
+	assert.Empty(t, spans[0].ParentID)
+}
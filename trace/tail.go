@@ -0,0 +1,351 @@
+package trace
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	jaeger "github.com/uber/jaeger-client-go"
+)
+
+const (
+	defaultTailDecisionWait = 10 * time.Second
+	defaultTailMaxTraces    = 10000
+)
+
+type (
+	// TailSamplingOptions contains optional options for creating a TailSamplingReporter
+	TailSamplingOptions struct {
+		// DecisionWait is the maximum duration to buffer the spans of a trace before deciding (default 10s)
+		// A trace is decided earlier when all of its spans started in this process are finished.
+		DecisionWait time.Duration
+		// LatencyThreshold keeps traces having a span with a duration equal to or greater than it (default 0 disabled)
+		LatencyThreshold time.Duration
+		// SampleRate is the probability between 0 and 1 for keeping traces without errors or high latency
+		SampleRate float64
+		// MaxTraces is the maximum number of buffered traces (default 10000)
+		// When the buffer is full, spans of new traces are decided on their own without buffering.
+		MaxTraces int
+	}
+
+	// TailSamplingReporter is a span reporter buffering the spans of a trace and deciding whether or not to keep the trace
+	// Traces with errors (error tag) or high latency are always kept and other traces are sampled.
+	// Kept spans are reported to the next reporter.
+	// Since spans are held after being reported, the tracer must not pool spans.
+	// The tracer should also sample all traces (i.e. NewConstSampler(true)), so the decision is made by this reporter.
+	//
+	// It is also a span observer (jaeger.ContribObserver) and NewTracer registers it with the tracer.
+	// Observed spans are checked without building a snapshot of them and a trace is decided once all of its spans started in this process are finished.
+	// So, spans finishing after their parent spans (i.e. spans started by Go or FollowsFrom references) still affect the decision
+	// and traces joined from other services are decided as soon as they are done in this process.
+	// If the tracer does not have it as an observer, traces are decided on their spans without a parent
+	// and traces joined from other services are decided after the decision wait.
+	TailSamplingReporter struct {
+		sync.Mutex
+		next     jaeger.Reporter
+		opts     TailSamplingOptions
+		sampler  *jaeger.ProbabilisticSampler
+		spans    map[tailSpanKey]*tailSpan
+		local    map[jaeger.TraceID]int
+		traces   map[jaeger.TraceID]*tailTrace
+		decided  map[jaeger.TraceID]tailDecision
+		stop     chan struct{}
+		done     chan struct{}
+		stopOnce sync.Once
+	}
+
+	tailSpanKey struct {
+		traceID jaeger.TraceID
+		spanID  jaeger.SpanID
+	}
+
+	// tailSpan keeps what is needed for deciding a trace from a started span
+	// root is only used for spans not observed and is true for spans without a parent.
+	tailSpan struct {
+		start    time.Time
+		duration time.Duration
+		root     bool
+		hasError int32
+	}
+
+	tailTrace struct {
+		spans    []*jaeger.Span
+		keep     bool
+		deadline time.Time
+	}
+
+	tailDecision struct {
+		keep    bool
+		expires time.Time
+	}
+)
+
+// NewTailSamplingReporter creates a new span reporter for tail-based sampling
+func NewTailSamplingReporter(next jaeger.Reporter, opts TailSamplingOptions) (*TailSamplingReporter, error) {
+	sampler, err := jaeger.NewProbabilisticSampler(opts.SampleRate)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.DecisionWait <= 0 {
+		opts.DecisionWait = defaultTailDecisionWait
+	}
+
+	if opts.MaxTraces <= 0 {
+		opts.MaxTraces = defaultTailMaxTraces
+	}
+
+	r := &TailSamplingReporter{
+		next:    next,
+		opts:    opts,
+		sampler: sampler,
+		spans:   map[tailSpanKey]*tailSpan{},
+		local:   map[jaeger.TraceID]int{},
+		traces:  map[jaeger.TraceID]*tailTrace{},
+		decided: map[jaeger.TraceID]tailDecision{},
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	go r.run()
+
+	return r, nil
+}
+
+// OnStartSpan implements jaeger.ContribObserver interface
+// The spans of each trace started in this process and not finished yet are counted, so the trace is decided when the count drops to zero.
+func (r *TailSamplingReporter) OnStartSpan(sp opentracing.Span, operationName string, options opentracing.StartSpanOptions) (jaeger.ContribSpanObserver, bool) {
+	ctx, ok := sp.Context().(jaeger.SpanContext)
+	if !ok || !ctx.IsSampled() {
+		return nil, false
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	s := &tailSpan{
+		start: options.StartTime,
+	}
+	r.spans[tailSpanKey{ctx.TraceID(), ctx.SpanID()}] = s
+	r.local[ctx.TraceID()]++
+
+	return s, true
+}
+
+// Report implements jaeger.Reporter interface
+func (r *TailSamplingReporter) Report(span *jaeger.Span) {
+	ctx, _ := span.Context().(jaeger.SpanContext)
+	id := ctx.TraceID()
+	key := tailSpanKey{id, ctx.SpanID()}
+
+	r.Lock()
+	s, observed := r.spans[key]
+	last := false
+	if observed {
+		delete(r.spans, key)
+		last = r.forgetLocal(id)
+	}
+	r.Unlock()
+
+	if !observed {
+		s = newTailSpan(span)
+	}
+
+	important := r.isImportant(s)
+
+	r.Lock()
+
+	// Spans arriving after a trace is decided follow the decision
+	if d, ok := r.decided[id]; ok {
+		r.Unlock()
+		if d.keep {
+			r.next.Report(span)
+		}
+		return
+	}
+
+	t, ok := r.traces[id]
+	if !ok {
+		if len(r.traces) >= r.opts.MaxTraces {
+			r.Unlock()
+			if important || r.isSampled(id) {
+				r.next.Report(span)
+			}
+			return
+		}
+
+		t = &tailTrace{
+			deadline: time.Now().Add(r.opts.DecisionWait),
+		}
+		r.traces[id] = t
+	}
+
+	t.spans = append(t.spans, span)
+	t.keep = t.keep || important
+
+	// An observed trace is decided when its last span in this process is finished
+	var spans []*jaeger.Span
+	if (observed && last) || (!observed && s.root) {
+		spans = r.decide(id, t)
+	}
+
+	r.Unlock()
+
+	for _, s := range spans {
+		r.next.Report(s)
+	}
+}
+
+// Close implements jaeger.Reporter interface
+// It decides all buffered traces and closes the next reporter.
+func (r *TailSamplingReporter) Close() {
+	r.stopOnce.Do(func() {
+		close(r.stop)
+	})
+
+	<-r.done
+
+	r.Lock()
+	var spans []*jaeger.Span
+	for id, t := range r.traces {
+		spans = append(spans, r.decide(id, t)...)
+	}
+	r.Unlock()
+
+	for _, s := range spans {
+		r.next.Report(s)
+	}
+
+	r.next.Close()
+}
+
+func (r *TailSamplingReporter) run() {
+	defer close(r.done)
+
+	interval := r.opts.DecisionWait / 10
+	if interval < time.Millisecond {
+		interval = time.Millisecond
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.decideExpired(time.Now())
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// decideExpired decides all traces past their deadline and forgets expired decisions
+// It also forgets observed spans started before the decision wait, so spans never reported (i.e. not finished) are not kept forever.
+func (r *TailSamplingReporter) decideExpired(now time.Time) {
+	r.Lock()
+
+	for key, s := range r.spans {
+		if now.Sub(s.start) > r.opts.DecisionWait {
+			delete(r.spans, key)
+			r.forgetLocal(key.traceID)
+		}
+	}
+
+	var spans []*jaeger.Span
+	for id, t := range r.traces {
+		if now.After(t.deadline) {
+			spans = append(spans, r.decide(id, t)...)
+		}
+	}
+
+	for id, d := range r.decided {
+		if now.After(d.expires) {
+			delete(r.decided, id)
+		}
+	}
+
+	r.Unlock()
+
+	for _, s := range spans {
+		r.next.Report(s)
+	}
+}
+
+// forgetLocal decreases the number of unfinished spans of a trace in this process
+// It returns true if no unfinished span of the trace is left.
+func (r *TailSamplingReporter) forgetLocal(id jaeger.TraceID) bool {
+	if r.local[id]--; r.local[id] > 0 {
+		return false
+	}
+
+	delete(r.local, id)
+	return true
+}
+
+// decide removes a trace from the buffer and returns its spans if the trace is kept
+// The decision is remembered for late spans of the same trace.
+func (r *TailSamplingReporter) decide(id jaeger.TraceID, t *tailTrace) []*jaeger.Span {
+	delete(r.traces, id)
+
+	keep := t.keep || r.isSampled(id)
+	r.decided[id] = tailDecision{
+		keep:    keep,
+		expires: time.Now().Add(r.opts.DecisionWait),
+	}
+
+	if !keep {
+		return nil
+	}
+
+	return t.spans
+}
+
+// isImportant determines whether or not a span has an error or high latency
+func (r *TailSamplingReporter) isImportant(s *tailSpan) bool {
+	if atomic.LoadInt32(&s.hasError) == 1 {
+		return true
+	}
+
+	return r.opts.LatencyThreshold > 0 && s.duration >= r.opts.LatencyThreshold
+}
+
+func (r *TailSamplingReporter) isSampled(id jaeger.TraceID) bool {
+	sampled, _ := r.sampler.IsSampled(id, "")
+	return sampled
+}
+
+// newTailSpan creates a tailSpan from a snapshot of a span not observed
+func newTailSpan(span *jaeger.Span) *tailSpan {
+	ctx, _ := span.Context().(jaeger.SpanContext)
+	data := newSpanData(span)
+
+	s := &tailSpan{
+		start:    data.StartTime,
+		duration: data.Duration,
+		root:     ctx.ParentID() == 0,
+	}
+
+	if data.Tags[string(ext.Error)] == true {
+		s.hasError = 1
+	}
+
+	return s
+}
+
+// OnSetOperationName implements jaeger.ContribSpanObserver interface
+func (s *tailSpan) OnSetOperationName(operationName string) {}
+
+// OnSetTag implements jaeger.ContribSpanObserver interface
+func (s *tailSpan) OnSetTag(key string, value interface{}) {
+	if key == string(ext.Error) && value == true {
+		atomic.StoreInt32(&s.hasError, 1)
+	}
+}
+
+// OnFinish implements jaeger.ContribSpanObserver interface
+func (s *tailSpan) OnFinish(options opentracing.FinishOptions) {
+	s.duration = options.FinishTime.Sub(s.start)
+}
@@ -0,0 +1,339 @@
+package trace
+
+import (
+	"testing"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	jaeger "github.com/uber/jaeger-client-go"
+)
+
+func TestNewTailSamplingReporter(t *testing.T) {
+	tests := []struct {
+		name          string
+		opts          TailSamplingOptions
+		expectedOpts  TailSamplingOptions
+		expectedError string
+	}{
+		{
+			name:          "InvalidSampleRate",
+			opts:          TailSamplingOptions{SampleRate: 2},
+			expectedError: "Sampling Rate must be between 0.0 and 1.0, received 2.000000",
+		},
+		{
+			name: "Defaults",
+			opts: TailSamplingOptions{},
+			expectedOpts: TailSamplingOptions{
+				DecisionWait: 10 * time.Second,
+				MaxTraces:    10000,
+			},
+		},
+		{
+			name: "WithOptions",
+			opts: TailSamplingOptions{
+				DecisionWait:     time.Second,
+				LatencyThreshold: 500 * time.Millisecond,
+				SampleRate:       0.1,
+				MaxTraces:        100,
+			},
+			expectedOpts: TailSamplingOptions{
+				DecisionWait:     time.Second,
+				LatencyThreshold: 500 * time.Millisecond,
+				SampleRate:       0.1,
+				MaxTraces:        100,
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := NewTailSamplingReporter(NewRecorder(), tc.opts)
+
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				assert.Nil(t, r)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedOpts, r.opts)
+				r.Close()
+			}
+		})
+	}
+}
+
+func TestTailSamplingReporter(t *testing.T) {
+	tests := []struct {
+		name          string
+		opts          TailSamplingOptions
+		childError    bool
+		childDuration time.Duration
+		expectedSpans int
+	}{
+		{
+			name:          "Dropped",
+			opts:          TailSamplingOptions{SampleRate: 0},
+			expectedSpans: 0,
+		},
+		{
+			name:          "Sampled",
+			opts:          TailSamplingOptions{SampleRate: 1},
+			expectedSpans: 2,
+		},
+		{
+			name:          "Error",
+			opts:          TailSamplingOptions{SampleRate: 0},
+			childError:    true,
+			expectedSpans: 2,
+		},
+		{
+			name:          "HighLatency",
+			opts:          TailSamplingOptions{SampleRate: 0, LatencyThreshold: time.Second},
+			childDuration: 2 * time.Second,
+			expectedSpans: 2,
+		},
+		{
+			name:          "LowLatency",
+			opts:          TailSamplingOptions{SampleRate: 0, LatencyThreshold: time.Second},
+			childDuration: 500 * time.Millisecond,
+			expectedSpans: 0,
+		},
+	}
+
+	for _, tc := range tests {
+		for _, observed := range []bool{false, true} {
+			name := tc.name
+			if observed {
+				name += "Observed"
+			}
+
+			t.Run(name, func(t *testing.T) {
+				recorder := NewRecorder()
+				reporter, err := NewTailSamplingReporter(recorder, tc.opts)
+				assert.NoError(t, err)
+
+				tracer, close := newTestTracer(reporter)
+				if observed {
+					tracer, close = newObservedTestTracer(reporter)
+				}
+				defer close()
+
+				start := time.Now()
+				root := tracer.StartSpan("root", opentracing.StartTime(start))
+				child := tracer.StartSpan("child", opentracing.ChildOf(root.Context()), opentracing.StartTime(start))
+				if tc.childError {
+					child.SetTag("error", true)
+				}
+				child.FinishWithOptions(opentracing.FinishOptions{FinishTime: start.Add(tc.childDuration)})
+
+				// The trace is buffered until the root span is finished
+				assert.Len(t, recorder.Spans(), 0)
+
+				root.FinishWithOptions(opentracing.FinishOptions{FinishTime: start.Add(tc.childDuration)})
+				assert.Len(t, recorder.Spans(), tc.expectedSpans)
+
+				// Late spans follow the decision of their trace
+				late := tracer.StartSpan("late", opentracing.ChildOf(root.Context()))
+				late.Finish()
+				if tc.expectedSpans > 0 {
+					assert.Len(t, recorder.SpansByOperation("late"), 1)
+				} else {
+					assert.Len(t, recorder.SpansByOperation("late"), 0)
+				}
+			})
+		}
+	}
+}
+
+func TestTailSamplingReporterDecisionWait(t *testing.T) {
+	recorder := NewRecorder()
+	reporter, err := NewTailSamplingReporter(recorder, TailSamplingOptions{
+		DecisionWait: 20 * time.Millisecond,
+		SampleRate:   0,
+	})
+	assert.NoError(t, err)
+
+	tracer, close := newTestTracer(reporter)
+	defer close()
+
+	// Without observing spans, a span with a remote parent is not a root span
+	remote := jaeger.NewSpanContext(jaeger.TraceID{Low: 0x7fffffffffffffff}, jaeger.SpanID(1), 0, true, nil)
+	span := tracer.StartSpan("server", opentracing.ChildOf(remote))
+	span.SetTag("error", true)
+	span.Finish()
+
+	assert.Len(t, recorder.Spans(), 0)
+	assert.Eventually(t, func() bool {
+		return len(recorder.Spans()) == 1
+	}, time.Second, 5*time.Millisecond)
+
+	// Decisions are forgotten after the decision wait
+	assert.Eventually(t, func() bool {
+		reporter.Lock()
+		defer reporter.Unlock()
+		return len(reporter.decided) == 0
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestTailSamplingReporterMaxTraces(t *testing.T) {
+	recorder := NewRecorder()
+	reporter, err := NewTailSamplingReporter(recorder, TailSamplingOptions{
+		SampleRate: 0,
+		MaxTraces:  1,
+	})
+	assert.NoError(t, err)
+
+	tracer, close := newTestTracer(reporter)
+	defer close()
+
+	root1 := tracer.StartSpan("root1")
+	tracer.StartSpan("child1", opentracing.ChildOf(root1.Context())).Finish()
+
+	// The buffer is full and spans of new traces are decided on their own
+	root2 := tracer.StartSpan("root2")
+	child2 := tracer.StartSpan("child2", opentracing.ChildOf(root2.Context()))
+	child2.SetTag("error", true)
+	child2.Finish()
+	tracer.StartSpan("child3", opentracing.ChildOf(root2.Context())).Finish()
+
+	assert.Len(t, recorder.SpansByOperation("child1"), 0)
+	assert.Len(t, recorder.SpansByOperation("child2"), 1)
+	assert.Len(t, recorder.SpansByOperation("child3"), 0)
+}
+
+func TestTailSamplingReporterClose(t *testing.T) {
+	recorder := NewRecorder()
+	reporter, err := NewTailSamplingReporter(recorder, TailSamplingOptions{
+		SampleRate: 0,
+	})
+	assert.NoError(t, err)
+
+	tracer, close := newTestTracer(reporter)
+
+	root := tracer.StartSpan("root")
+	child := tracer.StartSpan("child", opentracing.ChildOf(root.Context()))
+	child.SetTag("error", true)
+	child.Finish()
+
+	assert.Len(t, recorder.Spans(), 0)
+	close()
+	assert.Len(t, recorder.Spans(), 1)
+}
+
+func newObservedTestTracer(reporter *TailSamplingReporter) (opentracing.Tracer, func()) {
+	tracer, closer := jaeger.NewTracer("test_service", jaeger.NewConstSampler(true), reporter, jaeger.TracerOptions.ContribObserver(reporter))
+	return tracer, func() { closer.Close() }
+}
+
+func TestTailSamplingReporterLocalRoot(t *testing.T) {
+	recorder := NewRecorder()
+	reporter, err := NewTailSamplingReporter(recorder, TailSamplingOptions{
+		SampleRate: 0,
+	})
+	assert.NoError(t, err)
+
+	tracer, close := newObservedTestTracer(reporter)
+	defer close()
+
+	// A span with a remote parent is the local root span of its trace
+	remote := jaeger.NewSpanContext(jaeger.TraceID{Low: 0x7fffffffffffffff}, jaeger.SpanID(1), 0, true, nil)
+	server := tracer.StartSpan("server", opentracing.ChildOf(remote))
+	child := tracer.StartSpan("child", opentracing.ChildOf(server.Context()))
+	child.SetTag("error", true)
+	child.Finish()
+
+	assert.Len(t, recorder.Spans(), 0)
+
+	server.Finish()
+	assert.Len(t, recorder.Spans(), 2)
+
+	reporter.Lock()
+	assert.Len(t, reporter.spans, 0)
+	assert.Len(t, reporter.local, 0)
+	reporter.Unlock()
+}
+
+func TestTailSamplingReporterFinishedParent(t *testing.T) {
+	tests := []struct {
+		name      string
+		reference func(opentracing.SpanContext) opentracing.SpanReference
+	}{
+		{"ChildOf", opentracing.ChildOf},
+		{"FollowsFrom", opentracing.FollowsFrom},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := NewRecorder()
+			reporter, err := NewTailSamplingReporter(recorder, TailSamplingOptions{
+				SampleRate: 0,
+			})
+			assert.NoError(t, err)
+
+			tracer, close := newObservedTestTracer(reporter)
+			defer close()
+
+			// The child span outlives its parent span (i.e. started by Go)
+			root := tracer.StartSpan("root")
+			child := tracer.StartSpan("child", tc.reference(root.Context()))
+			root.Finish()
+
+			// The trace is not decided until the child span is finished
+			grandchild := tracer.StartSpan("grandchild", opentracing.ChildOf(child.Context()))
+			grandchild.SetTag("error", true)
+			grandchild.Finish()
+			assert.Len(t, recorder.Spans(), 0)
+
+			child.Finish()
+			assert.Len(t, recorder.Spans(), 3)
+
+			reporter.Lock()
+			assert.Len(t, reporter.local, 0)
+			reporter.Unlock()
+		})
+	}
+}
+
+func TestTailSamplingReporterForgetSpans(t *testing.T) {
+	reporter, err := NewTailSamplingReporter(NewRecorder(), TailSamplingOptions{
+		DecisionWait: 20 * time.Millisecond,
+	})
+	assert.NoError(t, err)
+
+	tracer, close := newObservedTestTracer(reporter)
+	defer close()
+
+	// Spans never finished are forgotten after the decision wait
+	tracer.StartSpan("unfinished")
+
+	reporter.Lock()
+	assert.Len(t, reporter.spans, 1)
+	reporter.Unlock()
+
+	assert.Eventually(t, func() bool {
+		reporter.Lock()
+		defer reporter.Unlock()
+		return len(reporter.spans) == 0 && len(reporter.local) == 0
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestNewTracerTailSampling(t *testing.T) {
+	recorder := NewRecorder()
+	reporter, err := NewTailSamplingReporter(recorder, TailSamplingOptions{
+		SampleRate: 1,
+	})
+	assert.NoError(t, err)
+
+	tracer, closer, err := NewTracer(Options{
+		Name:         "service_name",
+		SpanReporter: reporter,
+	})
+	assert.NoError(t, err)
+	defer closer.Close()
+
+	// The reporter is registered as an observer, so the trace is decided once its spans in this process are finished
+	remote := jaeger.NewSpanContext(jaeger.TraceID{Low: 0x7fffffffffffffff}, jaeger.SpanID(1), 0, true, nil)
+	tracer.StartSpan("server", opentracing.ChildOf(remote)).Finish()
+
+	assert.Len(t, recorder.Spans(), 1)
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 
 	opentracing "github.com/opentracing/opentracing-go"
+	jaeger "github.com/uber/jaeger-client-go"
 	jconfig "github.com/uber/jaeger-client-go/config"
 	jmetrics "github.com/uber/jaeger-lib/metrics"
 	jprometheus "github.com/uber/jaeger-lib/metrics/prometheus"
@@ -98,16 +99,30 @@ func NewCollectorReporter(collectorAddr string, logSpans bool) *jconfig.Reporter
 }
 
 // Options contains optional options for Tracer
+//   SpanSampler if set is used for sampling spans instead of Sampler (i.e. NewPerOperationSampler)
+//   SpanReporter if set is used for reporting spans instead of Reporter (i.e. Recorder or FileReporter)
+//   Propagation is the list of formats for injecting and extracting span contexts (defaults to Jaeger format)
+//   LogBridge true mirrors span log events into Logger and enables attaching log events to spans (see LoggerWithSpan)
 type Options struct {
-	Name     string
-	Sampler  *jconfig.SamplerConfig
-	Reporter *jconfig.ReporterConfig
-	Logger   log.Logger
-	PromReg  prometheus.Registerer
+	Name         string
+	Sampler      *jconfig.SamplerConfig
+	Reporter     *jconfig.ReporterConfig
+	SpanSampler  jaeger.Sampler
+	SpanReporter jaeger.Reporter
+	Propagation  []Propagation
+	LogBridge    bool
+	Logger       log.Logger
+	PromReg      prometheus.Registerer
 }
 
 // NewTracer creates a new tracer
 func NewTracer(opts Options) (opentracing.Tracer, io.Closer, error) {
+	return newTracer(opts, nil)
+}
+
+// newTracer creates a new tracer
+// If wrap is not nil, the span reporter of tracer is wrapped by it.
+func newTracer(opts Options, wrap func(jaeger.Reporter) jaeger.Reporter) (opentracing.Tracer, io.Closer, error) {
 	if opts.Name == "" {
 		opts.Name = "tracer"
 	}
@@ -127,18 +142,83 @@ func NewTracer(opts Options) (opentracing.Tracer, io.Closer, error) {
 		Reporter:    opts.Reporter,
 	}
 
+	var jlogger jaeger.Logger = jaeger.NullLogger
 	if opts.Logger != nil {
-		jlogger := &jaegerLogger{opts.Logger}
+		jlogger = &jaegerLogger{opts.Logger}
 		loggerOpt := jconfig.Logger(jlogger)
 		jgOpts = append(jgOpts, loggerOpt)
 	}
 
+	if opts.SpanSampler != nil {
+		jgOpts = append(jgOpts, jconfig.Sampler(opts.SpanSampler))
+	}
+
+	var factory jmetrics.Factory = jmetrics.NullFactory
 	if opts.PromReg != nil {
 		regOpt := jprometheus.WithRegisterer(opts.PromReg)
-		factory := jprometheus.New(regOpt).Namespace(jmetrics.NSOptions{Name: opts.Name})
+		factory = jprometheus.New(regOpt).Namespace(jmetrics.NSOptions{Name: opts.Name})
 		metricsOpt := jconfig.Metrics(factory)
 		jgOpts = append(jgOpts, metricsOpt)
 	}
 
-	return jgConfig.NewTracer(jgOpts...)
+	if len(opts.Propagation) > 0 {
+		// The sampler is created here the same way the tracer creates it, so propagators can share it with the tracer
+		sampler := opts.SpanSampler
+		if sampler == nil {
+			var err error
+			sampler, err = opts.Sampler.NewSampler(opts.Name, jaeger.NewMetrics(factory, nil))
+			if err != nil {
+				return nil, nil, err
+			}
+			jgOpts = append(jgOpts, jconfig.Sampler(sampler))
+		}
+
+		states := newTraceStates(maxTraceStates)
+		for _, format := range []opentracing.BuiltinFormat{opentracing.HTTPHeaders, opentracing.TextMap} {
+			p, err := newPropagator(format, opts.Propagation, sampler, states)
+			if err != nil {
+				if opts.SpanSampler == nil {
+					sampler.Close()
+				}
+				return nil, nil, err
+			}
+			jgOpts = append(jgOpts, jconfig.Injector(format, p), jconfig.Extractor(format, p))
+		}
+	}
+
+	// Span reporters observing spans (i.e. TailSamplingReporter) are registered as observers too
+	if observer, ok := opts.SpanReporter.(jaeger.ContribObserver); ok {
+		jgOpts = append(jgOpts, jconfig.ContribObserver(observer))
+	}
+
+	reporter := opts.SpanReporter
+	if wrap != nil {
+		// The reporter is created here the same way the tracer creates it, so it can be wrapped
+		if reporter == nil {
+			var err error
+			reporter, err = opts.Reporter.NewReporter(opts.Name, jaeger.NewMetrics(factory, nil), jlogger)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		reporter = wrap(reporter)
+
+		// Wrapping reporters may keep spans after Report returns, so spans cannot be pooled and reused
+		jgOpts = append(jgOpts, jconfig.PoolSpans(false))
+	}
+
+	if reporter != nil {
+		jgOpts = append(jgOpts, jconfig.Reporter(reporter))
+	}
+
+	tracer, closer, err := jgConfig.NewTracer(jgOpts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if opts.LogBridge {
+		tracer = newBridgeTracer(tracer, opts.Logger)
+	}
+
+	return tracer, closer, nil
 }
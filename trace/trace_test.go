@@ -1,14 +1,20 @@
 package trace
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"net/http"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/go-kit/kit/log"
+	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
+	jaeger "github.com/uber/jaeger-client-go"
 	"github.com/uber/jaeger-client-go/config"
 )
 
@@ -179,6 +185,13 @@ func TestNewTracer(t *testing.T) {
 				PromReg:  prometheus.NewRegistry(),
 			},
 		},
+		{
+			"WithSpanReporter",
+			Options{
+				Name:         "service_name",
+				SpanReporter: NewRecorder(),
+			},
+		},
 	}
 
 	for _, tc := range tests {
@@ -192,3 +205,270 @@ func TestNewTracer(t *testing.T) {
 		})
 	}
 }
+
+func TestNewTracerSpanReporter(t *testing.T) {
+	recorder := NewRecorder()
+	tracer, closer, err := NewTracer(Options{
+		Name:         "service_name",
+		SpanReporter: recorder,
+	})
+	assert.NoError(t, err)
+	defer closer.Close()
+
+	span := tracer.StartSpan("operation")
+	span.SetTag("key", "value")
+	span.Finish()
+
+	spans := recorder.SpansByTag("key", "value")
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "service_name", spans[0].Service)
+	assert.Equal(t, "operation", spans[0].Operation)
+}
+
+func TestNewTracerPropagation(t *testing.T) {
+	tests := []struct {
+		name           string
+		propagation    []Propagation
+		expectedHeader string
+		expectedError  string
+	}{
+		{"Jaeger", []Propagation{PropagationJaeger}, "Uber-Trace-Id", ""},
+		{"W3C", []Propagation{PropagationW3C}, "Traceparent", ""},
+		{"B3Single", []Propagation{PropagationB3Single}, "B3", ""},
+		{"B3Multi", []Propagation{PropagationB3Multi}, "X-B3-Traceid", ""},
+		{"Unknown", []Propagation{Propagation(99)}, "", "unknown propagation format: 99"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tracer, closer, err := NewTracer(Options{
+				Name:         "service_name",
+				SpanReporter: NewRecorder(),
+				Propagation:  tc.propagation,
+			})
+
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				return
+			}
+
+			assert.NoError(t, err)
+			defer closer.Close()
+
+			span := tracer.StartSpan("parent")
+			defer span.Finish()
+
+			header := http.Header{}
+			err = tracer.Inject(span.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(header))
+			assert.NoError(t, err)
+			assert.NotEmpty(t, header[tc.expectedHeader])
+			if tc.expectedHeader != "Uber-Trace-Id" {
+				assert.Empty(t, header["Uber-Trace-Id"])
+			}
+
+			ctx, err := tracer.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(header))
+			assert.NoError(t, err)
+			assert.Equal(t, span.Context().(jaeger.SpanContext).TraceID(), ctx.(jaeger.SpanContext).TraceID())
+			assert.Equal(t, span.Context().(jaeger.SpanContext).SpanID(), ctx.(jaeger.SpanContext).SpanID())
+
+			carrier := opentracing.TextMapCarrier{}
+			err = tracer.Inject(span.Context(), opentracing.TextMap, carrier)
+			assert.NoError(t, err)
+
+			ctx, err = tracer.Extract(opentracing.TextMap, carrier)
+			assert.NoError(t, err)
+			assert.Equal(t, span.Context().(jaeger.SpanContext).TraceID(), ctx.(jaeger.SpanContext).TraceID())
+		})
+	}
+}
+
+func TestNewTracerPropagationB3Debug(t *testing.T) {
+	tests := []struct {
+		name           string
+		propagation    Propagation
+		header         http.Header
+		expectedHeader string
+		expectedValue  string
+	}{
+		{
+			name:           "Single",
+			propagation:    PropagationB3Single,
+			header:         http.Header{"B3": {"64fe8b2a57d3eff7-e457b5a2e4d86bd1-d"}},
+			expectedHeader: "B3",
+			expectedValue:  "-d-e457b5a2e4d86bd1",
+		},
+		{
+			name:           "Multi",
+			propagation:    PropagationB3Multi,
+			header:         http.Header{"X-B3-Traceid": {"64fe8b2a57d3eff7"}, "X-B3-Spanid": {"e457b5a2e4d86bd1"}, "X-B3-Flags": {"1"}},
+			expectedHeader: "X-B3-Flags",
+			expectedValue:  "1",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tracer, closer, err := NewTracer(Options{
+				Name:         "service_name",
+				Sampler:      NewConstSampler(false),
+				SpanReporter: NewRecorder(),
+				Propagation:  []Propagation{tc.propagation},
+			})
+			assert.NoError(t, err)
+			defer closer.Close()
+
+			parent, err := tracer.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(tc.header))
+			assert.NoError(t, err)
+			assert.True(t, parent.(jaeger.SpanContext).IsDebug())
+
+			// The debug flag is propagated to the next hop
+			span := tracer.StartSpan("child", opentracing.ChildOf(parent))
+			defer span.Finish()
+
+			header := http.Header{}
+			err = tracer.Inject(span.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(header))
+			assert.NoError(t, err)
+			assert.Contains(t, header.Get(tc.expectedHeader), tc.expectedValue)
+		})
+	}
+}
+
+func TestNewTracerPropagationDeferredSampling(t *testing.T) {
+	tests := []struct {
+		name          string
+		sampler       *config.SamplerConfig
+		header        http.Header
+		expectedSpans int
+	}{
+		{
+			name:          "Deferred",
+			sampler:       NewConstSampler(true),
+			header:        http.Header{"X-B3-Traceid": {"64fe8b2a57d3eff7"}, "X-B3-Spanid": {"e457b5a2e4d86bd1"}},
+			expectedSpans: 1,
+		},
+		{
+			name:          "DeferredNotSampled",
+			sampler:       NewConstSampler(false),
+			header:        http.Header{"X-B3-Traceid": {"64fe8b2a57d3eff7"}, "X-B3-Spanid": {"e457b5a2e4d86bd1"}},
+			expectedSpans: 0,
+		},
+		{
+			name:          "NotSampled",
+			sampler:       NewConstSampler(true),
+			header:        http.Header{"X-B3-Traceid": {"64fe8b2a57d3eff7"}, "X-B3-Spanid": {"e457b5a2e4d86bd1"}, "X-B3-Sampled": {"0"}},
+			expectedSpans: 0,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := NewRecorder()
+			tracer, closer, err := NewTracer(Options{
+				Name:         "service_name",
+				Sampler:      tc.sampler,
+				SpanReporter: recorder,
+				Propagation:  []Propagation{PropagationB3Multi},
+			})
+			assert.NoError(t, err)
+			defer closer.Close()
+
+			ctx, err := tracer.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(tc.header))
+			assert.NoError(t, err)
+
+			span := tracer.StartSpan("child", opentracing.ChildOf(ctx))
+			span.Finish()
+
+			assert.Len(t, recorder.Spans(), tc.expectedSpans)
+		})
+	}
+}
+
+func TestNewTracerPropagationTraceState(t *testing.T) {
+	tracer, closer, err := NewTracer(Options{
+		Name:         "service_name",
+		SpanReporter: NewRecorder(),
+		Propagation:  []Propagation{PropagationW3C, PropagationJaeger},
+	})
+	assert.NoError(t, err)
+	defer closer.Close()
+
+	in := http.Header{}
+	in.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	in.Set("tracestate", "congo=t61rcWkgMzE")
+
+	ctx, err := tracer.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(in))
+	assert.NoError(t, err)
+
+	span := tracer.StartSpan("child", opentracing.ChildOf(ctx))
+	defer span.Finish()
+
+	out := http.Header{}
+	err = tracer.Inject(span.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(out))
+	assert.NoError(t, err)
+	assert.Equal(t, "congo=t61rcWkgMzE", out.Get("tracestate"))
+
+	for k := range out {
+		assert.False(t, strings.HasPrefix(strings.ToLower(k), "uberctx-"), "unexpected baggage header %s", k)
+	}
+}
+
+func TestNewTracerOTLPReporter(t *testing.T) {
+	collector := newMockCollector()
+	defer collector.Close()
+
+	tracer, closer, err := NewTracer(Options{
+		Name: "service_name",
+		SpanReporter: NewOTLPReporter(OTLPOptions{
+			Endpoint: collector.server.URL,
+			Encoding: OTLPJSON,
+		}),
+	})
+	assert.NoError(t, err)
+
+	span := tracer.StartSpan("operation")
+	span.Finish()
+	closer.Close()
+
+	assert.Equal(t, []int{1}, collector.spans(t))
+}
+
+func TestNewTracerSpanSampler(t *testing.T) {
+	sampler, err := NewPerOperationSampler(1, map[string]float64{"/healthz": 0})
+	assert.NoError(t, err)
+
+	recorder := NewRecorder()
+	tracer, closer, err := NewTracer(Options{
+		Name:         "service_name",
+		SpanSampler:  sampler,
+		SpanReporter: recorder,
+	})
+	assert.NoError(t, err)
+	defer closer.Close()
+
+	tracer.StartSpan("/healthz").Finish()
+	tracer.StartSpan("/items").Finish()
+
+	assert.Len(t, recorder.SpansByOperation("/healthz"), 0)
+	assert.Len(t, recorder.SpansByOperation("/items"), 1)
+}
+
+func TestNewTracerLogBridge(t *testing.T) {
+	buf := new(bytes.Buffer)
+	tracer, closer, err := NewTracer(Options{
+		Name:         "service_name",
+		SpanReporter: NewRecorder(),
+		LogBridge:    true,
+		Logger:       log.NewJSONLogger(buf),
+	})
+	assert.NoError(t, err)
+	defer closer.Close()
+
+	ctx, finish := StartSpan(opentracing.ContextWithSpan(context.Background(), tracer.StartSpan("parent")), "child")
+	opentracing.SpanFromContext(ctx).LogKV("event", "hello")
+	finish(nil)
+
+	lines := decodeLogLines(t, buf)
+	assert.Len(t, lines, 1)
+	assert.Equal(t, "child", lines[0]["span"])
+	assert.Equal(t, "hello", lines[0]["event"])
+}